@@ -0,0 +1,5 @@
+// Package auth verifies bearer tokens and carries the authenticated caller in the context.
+//
+// The gRPC auth interceptors resolve the caller into a `Principal`. Services and repositories
+// then read the user id with `UserID(ctx)` instead of trusting the `user_id` of a request.
+package auth
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKSOptions configures a JWKS key source
+type JWKSOptions struct {
+	JWTOptions
+
+	// Client fetches the key set (defaults to a client with a 10s timeout)
+	Client *http.Client
+
+	// RefreshInterval is how long fetched keys are used before the key set is fetched again (defaults to 1h)
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval limits how often an unknown key id triggers a fetch, failed fetches included (defaults to 1m)
+	MinRefreshInterval time.Duration
+
+	// Logger receives failed refreshes and skipped keys (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// jwks caches the keys of a JSON Web Key Set by key id
+type jwks struct {
+	url  string
+	opts JWKSOptions
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+
+	// attemptedAt is when the last fetch started, successful or not.
+	// Concurrent refreshes share a single fetch through group.
+	attemptedAt time.Time
+	group       singleflight.Group
+}
+
+// NewJWKSVerifier creates a verifier for JWTs signed with one of the keys published at the JWKS url.
+// The key set is fetched once up front so that a wrong url fails at startup.
+func NewJWKSVerifier(ctx context.Context, url string, opts JWKSOptions) (Verifier, error) {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = time.Hour
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	set := &jwks{url: url, opts: opts}
+	if err := set.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return newJWTVerifier(set.keyFunc, opts.JWTOptions,
+		"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+	), nil
+}
+
+// keyFunc returns the key matching the token's `kid`, refetching the key set when the key is unknown or stale.
+// Fetches are at least `MinRefreshInterval` apart, so tokens with made-up key ids cannot flood the provider.
+func (s *jwks) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key id")
+	}
+
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	age := time.Since(s.fetchedAt)
+	sinceAttempt := time.Since(s.attemptedAt)
+	s.mu.Unlock()
+
+	if (!ok || age > s.opts.RefreshInterval) && sinceAttempt > s.opts.MinRefreshInterval {
+		_, err, _ := s.group.Do(s.url, func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), s.opts.Client.Timeout)
+			defer cancel()
+			return nil, s.refresh(ctx)
+		})
+		if err != nil {
+			// keep using the keys we have, the provider may be briefly unavailable
+			s.opts.Logger.Warn("auth: failed to refresh JWKS",
+				slog.String("url", s.url),
+				slog.Any("error", err),
+			)
+		}
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+		s.mu.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the key set and replaces the cached keys
+func (s *jwks) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.attemptedAt = time.Now()
+	s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			s.opts.Logger.WarnContext(ctx, "auth: skipping JWKS key",
+				slog.String("kid", k.Kid),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// jsonWebKey is a public key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key into its crypto type
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// decodeInt decodes a base64url encoded big-endian integer
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter: %w", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
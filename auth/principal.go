@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// User is the caller's user record
+	User *gen.Userpayload
+
+	// Claims are the verified claims of the caller's token
+	Claims *Claims
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of the context, if there is one
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil && p.User != nil
+}
+
+// UserID returns the id of the authenticated user, or `ErrUnauthenticated` if the context has no principal
+func UserID(ctx context.Context) (string, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: no authenticated user in context", qerrors.ErrUnauthenticated)
+	}
+	return p.User.ID, nil
+}
+
+// Authorize checks that the user id supplied by a request belongs to the authenticated user.
+// An empty id is accepted and replaced by the authenticated user's id.
+func Authorize(ctx context.Context, userID string) (string, error) {
+	id, err := UserID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if userID != "" && userID != id {
+		return "", fmt.Errorf("%w: user %s cannot act on behalf of %s", qerrors.ErrPermissionDenied, id, userID)
+	}
+	return id, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidToken is returned when a token cannot be verified
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the verified claims of a token that are needed to resolve the caller
+type Claims struct {
+	// Subject is the `sub` claim, either the user id or the id given by the identity provider
+	Subject string
+
+	// Email is the `email` claim, used to resolve users whose subject is not their user id.
+	// It is only set when the identity provider verified the address (see `EmailVerified`).
+	Email string
+
+	// EmailVerified is the `email_verified` claim
+	EmailVerified bool
+
+	// ExpiresAt is the `exp` claim
+	ExpiresAt time.Time
+
+	// Raw holds every claim of the token
+	Raw map[string]any
+}
+
+// Verifier validates a bearer token and returns its claims
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// VerifierFunc adapts an ordinary function to the `Verifier` interface
+type VerifierFunc func(ctx context.Context, token string) (*Claims, error)
+
+// Verify calls f(ctx, token)
+func (f VerifierFunc) Verify(ctx context.Context, token string) (*Claims, error) {
+	return f(ctx, token)
+}
@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"log/slog"
+	"strconv"
+)
+
+// AdminConfig configures `CreateTopics`
+type AdminConfig struct {
+	// Servers is the Kafka bootstrap servers list
+	Servers string
+
+	// ReplicationFactor overrides the replication factor of every topic, e.g. 1 for a single local broker
+	ReplicationFactor int
+
+	// DeadLetters also creates the `<topic>.dlq` topic of every topic
+	DeadLetters bool
+
+	// Config holds extra admin client configuration
+	Config kafka.ConfigMap
+
+	// Logger receives a record per created topic (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// CreateTopics creates the given topics (every catalog topic when none are given).
+// Topics that already exist are left untouched.
+func CreateTopics(ctx context.Context, cfg AdminConfig, specs ...TopicSpec) error {
+	if len(specs) == 0 {
+		specs = Catalog()
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	config := kafka.ConfigMap{"bootstrap.servers": cfg.Servers}
+	for k, v := range cfg.Config {
+		config[k] = v
+	}
+	admin, err := kafka.NewAdminClient(&config)
+	if err != nil {
+		return fmt.Errorf("failed to create admin client: %w", err)
+	}
+	defer admin.Close()
+
+	topics := make([]kafka.TopicSpecification, 0, len(specs))
+	for _, spec := range specs {
+		topics = append(topics, cfg.specification(spec.Name, spec))
+		if cfg.DeadLetters {
+			// dead letters are inspected and replayed by hand, they are never compacted away
+			dlq := spec
+			dlq.Compacted = false
+			topics = append(topics, cfg.specification(DeadLetterTopic(spec.Name), dlq))
+		}
+	}
+
+	results, err := admin.CreateTopics(ctx, topics)
+	if err != nil {
+		return fmt.Errorf("failed to create topics: %w", err)
+	}
+
+	var errs []error
+	for _, res := range results {
+		switch res.Error.Code() {
+		case kafka.ErrNoError:
+			cfg.Logger.InfoContext(ctx, "topic created", slog.String("topic", res.Topic))
+		case kafka.ErrTopicAlreadyExists:
+		default:
+			errs = append(errs, fmt.Errorf("failed to create topic %s: %w", res.Topic, res.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// specification returns the topic specification of a spec
+func (cfg AdminConfig) specification(name string, spec TopicSpec) kafka.TopicSpecification {
+	replication := spec.ReplicationFactor
+	if cfg.ReplicationFactor > 0 {
+		replication = cfg.ReplicationFactor
+	}
+
+	retention := "-1"
+	if spec.Retention > 0 {
+		retention = strconv.FormatInt(spec.Retention.Milliseconds(), 10)
+	}
+	policy := "delete"
+	if spec.Compacted {
+		policy = "compact"
+	}
+
+	return kafka.TopicSpecification{
+		Topic:             name,
+		NumPartitions:     spec.Partitions,
+		ReplicationFactor: replication,
+		Config: map[string]string{
+			"retention.ms":   retention,
+			"cleanup.policy": policy,
+		},
+	}
+}
@@ -0,0 +1,339 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"github.com/qwallet-expense-tracker/shared/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrProducerClosed is returned by `Publish` after `Close` was called
+var ErrProducerClosed = errors.New("broker: producer is closed")
+
+// ProducerConfig configures a `Producer`
+type ProducerConfig struct {
+	// Servers is the Kafka bootstrap servers list
+	Servers string
+
+	// Linger is how long messages are buffered to build larger batches (defaults to 5ms)
+	Linger time.Duration
+
+	// BatchSize is the maximum number of messages sent in one batch (defaults to 10000)
+	BatchSize int
+
+	// Compression is the codec of the batches: "none", "gzip", "snappy", "lz4" or "zstd" (defaults to "snappy")
+	Compression string
+
+	// DisableIdempotence turns off the idempotent producer, which otherwise guarantees that
+	// retries neither duplicate nor reorder messages within a partition
+	DisableIdempotence bool
+
+	// Config holds extra producer configuration, applied on top of the producer's defaults
+	Config kafka.ConfigMap
+
+	// Name identifies the publishing service in the envelope of published events (defaults to the program name)
+	Name string
+
+	// Codec encodes the events published with `Publish` (defaults to `ProtoCodec`)
+	Codec Codec
+
+	// OnError is called from the delivery-report loop for every failed delivery and producer error.
+	// The message is nil for errors that are not about one message.
+	OnError func(msg *kafka.Message, err error)
+
+	// Logger receives the errors when there is no `OnError` (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// Producer publishes messages asynchronously. A single background loop handles the
+// delivery reports of every message, so producers never wait on each other.
+type Producer struct {
+	producer *kafka.Producer
+	cfg      ProducerConfig
+
+	// mu guards closed, messages are only produced while the producer is open.
+	// closing is closed by `Close` to wake up publishers waiting for room in the queue.
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+
+	// unregisterMetrics removes the queue length of the producer once it is closed
+	unregisterMetrics func()
+
+	pendingMu sync.Mutex
+	pending   map[*Delivery]struct{}
+	done      chan struct{}
+}
+
+// Delivery is the future result of a published message
+type Delivery struct {
+	msg   *kafka.Message
+	span  trace.Span
+	start time.Time
+	err   error
+	done  chan struct{}
+}
+
+// Done is closed once the delivery report of the message was received
+func (d *Delivery) Done() <-chan struct{} {
+	return d.done
+}
+
+// Wait blocks until the message is delivered or the context is done
+func (d *Delivery) Wait(ctx context.Context) (*kafka.Message, error) {
+	select {
+	case <-d.done:
+		return d.msg, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// complete records the delivery result and wakes up the waiters
+func (d *Delivery) complete(msg *kafka.Message, err error) {
+	d.msg, d.err = msg, err
+	if d.span != nil {
+		if err != nil {
+			d.span.RecordError(err)
+			d.span.SetStatus(codes.Error, err.Error())
+		}
+		d.span.End()
+	}
+	close(d.done)
+}
+
+// NewAsyncProducer creates a new asynchronous producer and starts its delivery-report loop
+func NewAsyncProducer(cfg ProducerConfig) (*Producer, error) {
+	if cfg.Linger <= 0 {
+		cfg.Linger = 5 * time.Millisecond
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10000
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = "snappy"
+	}
+	if cfg.Name == "" {
+		cfg.Name = filepath.Base(os.Args[0])
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = ProtoCodec{}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	config := kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Servers,
+		"linger.ms":          int(cfg.Linger.Milliseconds()),
+		"batch.num.messages": cfg.BatchSize,
+		"compression.type":   cfg.Compression,
+		"enable.idempotence": !cfg.DisableIdempotence,
+	}
+	if !cfg.DisableIdempotence {
+		config["acks"] = "all"
+	}
+	for k, v := range cfg.Config {
+		config[k] = v
+	}
+
+	p, err := kafka.NewProducer(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+	producer := &Producer{
+		producer:          p,
+		cfg:               cfg,
+		unregisterMetrics: metrics.RegisterProducer(p),
+		closing:           make(chan struct{}),
+		pending:           make(map[*Delivery]struct{}),
+		done:              make(chan struct{}),
+	}
+	go producer.deliveryReports()
+	return producer, nil
+}
+
+// Publish produces a message to the given topic without waiting for it to be delivered
+func (p *Producer) Publish(ctx context.Context, topic string, key, value []byte, headers ...kafka.Header) *Delivery {
+	return p.PublishMessage(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            key,
+		Value:          value,
+		Headers:        headers,
+	})
+}
+
+// PublishMessage produces a message without waiting for it to be delivered.
+// When the local queue is full it waits for room until the context is done or the producer is closed.
+func (p *Producer) PublishMessage(ctx context.Context, msg *kafka.Message) *Delivery {
+	d := &Delivery{start: time.Now(), done: make(chan struct{})}
+	_, d.span = telemetry.StartProduce(ctx, msg)
+	msg.Opaque = d
+	p.track(d)
+	for {
+		err := p.tryProduce(msg)
+		if err == nil {
+			return d
+		}
+
+		var kErr kafka.Error
+		if errors.Is(err, ErrProducerClosed) {
+			p.fail(d, err)
+			return d
+		}
+		if !errors.As(err, &kErr) || kErr.Code() != kafka.ErrQueueFull {
+			p.fail(d, fmt.Errorf("failed to produce message: %w", err))
+			return d
+		}
+		select {
+		case <-ctx.Done():
+			p.fail(d, fmt.Errorf("failed to produce message: %w", ctx.Err()))
+			return d
+		case <-p.closing:
+			p.fail(d, ErrProducerClosed)
+			return d
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// tryProduce produces the message unless the producer is closed. The read lock is only held
+// for the attempt itself, so `Close` never waits for a publisher that waits for room in the queue.
+func (p *Producer) tryProduce(msg *kafka.Message) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+	return p.producer.Produce(msg, nil)
+}
+
+// fail completes a delivery that was never produced, unless `Close` already completed it
+func (p *Producer) fail(d *Delivery, err error) {
+	if p.untrack(d) {
+		d.complete(nil, err)
+	}
+}
+
+// track adds a delivery to the in-flight set
+func (p *Producer) track(d *Delivery) {
+	p.pendingMu.Lock()
+	p.pending[d] = struct{}{}
+	p.pendingMu.Unlock()
+}
+
+// untrack removes a delivery from the in-flight set and reports whether it was in it
+func (p *Producer) untrack(d *Delivery) bool {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+	_, ok := p.pending[d]
+	delete(p.pending, d)
+	return ok
+}
+
+// inflight returns the number of deliveries that have not completed
+func (p *Producer) inflight() int {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+	return len(p.pending)
+}
+
+// deliveryReports completes the deliveries of published messages and surfaces producer errors
+func (p *Producer) deliveryReports() {
+	defer close(p.done)
+	for ev := range p.producer.Events() {
+		switch e := ev.(type) {
+		case *kafka.Message:
+			d, ok := e.Opaque.(*Delivery)
+			if !ok || !p.untrack(d) {
+				// produced on the underlying producer by someone else
+				continue
+			}
+			var err error
+			if e.TopicPartition.Error != nil {
+				err = fmt.Errorf("delivery failed: %w", e.TopicPartition.Error)
+				p.report(e, err)
+			}
+			metrics.ObserveProduce(*e.TopicPartition.Topic, time.Since(d.start), err)
+			d.complete(e, err)
+		case kafka.Error:
+			p.report(nil, e)
+		}
+	}
+}
+
+// report surfaces a delivery or producer error
+func (p *Producer) report(msg *kafka.Message, err error) {
+	if p.cfg.OnError != nil {
+		p.cfg.OnError(msg, err)
+		return
+	}
+	if msg != nil {
+		p.cfg.Logger.With(messageAttrs(msg.TopicPartition)...).Error("producer: failed to deliver message", slog.Any("error", err))
+		return
+	}
+	p.cfg.Logger.Error("producer error", slog.Any("error", err))
+}
+
+// Flush waits until every published message was delivered or the context is done
+func (p *Producer) Flush(ctx context.Context) error {
+	for p.producer.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to flush %d message(s): %w", p.producer.Len(), err)
+		}
+		p.producer.Flush(100)
+	}
+
+	// the reports may still be on their way to the delivery-report loop
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for p.inflight() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to flush %d message(s): %w", p.inflight(), ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Close stops accepting messages, drains the in-flight ones and closes the producer.
+// Messages still undelivered when the context is done are lost and their deliveries fail with `ErrProducerClosed`.
+func (p *Producer) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.closing)
+	p.mu.Unlock()
+
+	err := p.Flush(ctx)
+	p.producer.Close()
+	p.unregisterMetrics()
+	<-p.done
+
+	p.pendingMu.Lock()
+	lost := p.pending
+	p.pending = make(map[*Delivery]struct{})
+	p.pendingMu.Unlock()
+	for d := range lost {
+		d.complete(nil, ErrProducerClosed)
+	}
+	return err
+}
+
+// Kafka returns the underlying producer
+func (p *Producer) Kafka() *kafka.Producer {
+	return p.producer
+}
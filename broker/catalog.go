@@ -0,0 +1,116 @@
+package broker
+
+import (
+	eventsv1 "github.com/qwallet-expense-tracker/shared/events/v1"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+// TopicSpec describes a topic of the catalog
+type TopicSpec struct {
+	// Name is the topic name
+	Name string
+
+	// Event is a zero value of the proto message published on the topic
+	Event proto.Message
+
+	// Partitions is the number of partitions, events are keyed by user id so they stay ordered per user
+	Partitions int
+
+	// ReplicationFactor is the number of replicas of each partition
+	ReplicationFactor int
+
+	// Retention is how long messages are kept (0 keeps them forever)
+	Retention time.Duration
+
+	// Compacted keeps only the latest message of each key instead of deleting by age
+	Compacted bool
+
+	// Description says when the event is published
+	Description string
+}
+
+// EventType returns the full name of the topic's proto message, which is the envelope's `Type`
+func (s TopicSpec) EventType() string {
+	return string(proto.MessageName(s.Event))
+}
+
+const (
+	defaultPartitions        = 6
+	defaultReplicationFactor = 3
+	defaultRetention         = 7 * 24 * time.Hour
+)
+
+// topic returns a spec with the default partitions, replication and retention
+func topic[T ~string](name T, event proto.Message, description string) TopicSpec {
+	return TopicSpec{
+		Name:              string(name),
+		Event:             event,
+		Partitions:        defaultPartitions,
+		ReplicationFactor: defaultReplicationFactor,
+		Retention:         defaultRetention,
+		Description:       description,
+	}
+}
+
+// compacted keeps the latest event of each user forever instead of deleting events by age,
+// until a tombstone removes the user (see `IsTombstone`)
+func compacted(spec TopicSpec) TopicSpec {
+	spec.Compacted = true
+	spec.Retention = 0
+	return spec
+}
+
+// catalog lists every topic of the platform
+var catalog = []TopicSpec{
+	topic(AccountCreated, &eventsv1.AccountCreated{}, "An account was opened"),
+	topic(AccountUpdated, &eventsv1.AccountUpdated{}, "An account was renamed or its balance changed"),
+	topic(AccountDeleted, &eventsv1.AccountDeleted{}, "An account was closed"),
+
+	topic(UserCreated, &eventsv1.UserCreated{}, "A user signed up"),
+	compacted(topic(UserUpdated, &eventsv1.UserUpdated{}, "A user changed their profile, the latest profile of each user is kept")),
+	topic(UserDeleted, &eventsv1.UserDeleted{}, "A user deleted their account"),
+
+	topic(NotificationUserCreated, &eventsv1.Notification{}, "Welcome notification of a new user"),
+	topic(NotificationAccountCreated, &eventsv1.Notification{}, "Notification of a new account"),
+
+	topic(TransactionCreated, &eventsv1.TransactionCreated{}, "A transaction was recorded"),
+	topic(TransactionUpdated, &eventsv1.TransactionUpdated{}, "A transaction was edited"),
+	topic(TransactionDeleted, &eventsv1.TransactionDeleted{}, "A transaction was deleted"),
+
+	topic(TransferCompleted, &eventsv1.TransferCompleted{}, "Money was moved between two accounts of a user"),
+
+	topic(CategoryCreated, &eventsv1.CategoryCreated{}, "A category was created"),
+	topic(CategoryUpdated, &eventsv1.CategoryUpdated{}, "A category was edited"),
+	topic(CategoryDeleted, &eventsv1.CategoryDeleted{}, "A category was deleted"),
+
+	topic(GoalCreated, &eventsv1.GoalCreated{}, "A savings goal was created"),
+	topic(GoalUpdated, &eventsv1.GoalUpdated{}, "A savings goal was edited"),
+	topic(GoalDeleted, &eventsv1.GoalDeleted{}, "A savings goal was deleted"),
+	topic(GoalContributed, &eventsv1.GoalContributed{}, "Money was put towards a goal"),
+	topic(GoalCompleted, &eventsv1.GoalCompleted{}, "A goal reached its target"),
+	topic(GoalCancelled, &eventsv1.GoalCancelled{}, "A goal was abandoned before reaching its target"),
+
+	topic(BudgetThresholdReached, &eventsv1.BudgetThresholdReached{}, "The spending of a budget period crossed 80% or 100% of its limit"),
+
+	topic(BeneficiaryCreated, &eventsv1.BeneficiaryCreated{}, "A beneficiary was added"),
+	topic(BeneficiaryUpdated, &eventsv1.BeneficiaryUpdated{}, "A beneficiary was edited"),
+	topic(BeneficiaryDeleted, &eventsv1.BeneficiaryDeleted{}, "A beneficiary was removed"),
+}
+
+// Catalog returns the specs of every topic
+func Catalog() []TopicSpec {
+	specs := make([]TopicSpec, len(catalog))
+	copy(specs, catalog)
+	return specs
+}
+
+// LookupTopic returns the spec of the topic with the given name
+func LookupTopic(name string) (TopicSpec, bool) {
+	for _, spec := range catalog {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return TopicSpec{}, false
+}
@@ -2,10 +2,16 @@ package broker
 
 import (
 	"context"
+	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/metrics"
 	"log"
+	"time"
 )
 
+// pollTimeout is how long a single read blocks before the context is checked again
+const pollTimeout = 100 * time.Millisecond
+
 // NewConsumer creates a new Kafka consumer and subscribes to the given topics.
 func NewConsumer(servers, groupId string, topics []string) (*kafka.Consumer, error) {
 	// create a new consumer
@@ -15,20 +21,20 @@ func NewConsumer(servers, groupId string, topics []string) (*kafka.Consumer, err
 		"auto.offset.reset": "earliest",
 	})
 	if err != nil {
-		log.Fatalf("Failed to create consumer: %s\n", err)
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
-	
+
 	// subscribe to topics
-	go func(c *kafka.Consumer, topics []string) {
-		if err = c.SubscribeTopics(topics, nil); err != nil {
-			log.Fatalf("Failed to subscribe to topics %+v: %s\n", topics, err)
-		}
-	}(consumer, topics)
-	
+	if err = consumer.SubscribeTopics(topics, nil); err != nil {
+		_ = consumer.Close()
+		return nil, fmt.Errorf("failed to subscribe to topics %+v: %w", topics, err)
+	}
+	metrics.RegisterConsumer(groupId, consumer)
+
 	return consumer, nil
 }
 
-// ConsumeMessages consumes messages from Kafka and calls the handler function for each message.
+// ConsumeMessages consumes messages from Kafka and sends each message to the channel until the context is cancelled.
 func ConsumeMessages(ctx context.Context, c *kafka.Consumer, msgChan chan<- *kafka.Message) {
 	log.Println("Consuming messages...")
 	for {
@@ -36,11 +42,20 @@ func ConsumeMessages(ctx context.Context, c *kafka.Consumer, msgChan chan<- *kaf
 		case <-ctx.Done():
 			return
 		default:
-			msg, err := c.ReadMessage(-1)
+			msg, err := c.ReadMessage(pollTimeout)
 			if err != nil {
+				if kErr, ok := err.(kafka.Error); ok && kErr.IsTimeout() {
+					continue
+				}
 				log.Printf("Consumer error: %v (%v)\n", err, msg)
+				continue
+			}
+
+			select {
+			case msgChan <- msg:
+			case <-ctx.Done():
+				return
 			}
-			msgChan <- msg
 		}
 	}
 }
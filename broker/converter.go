@@ -1,21 +1,21 @@
 package broker
 
 import (
+	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/golang/protobuf/proto"
-	"log"
+	"google.golang.org/protobuf/proto"
 )
 
-// ConvertMessageToProto converts a message to a protobuf message
-func ConvertMessageToProto(msg *kafka.Message, out proto.Message) {
-	log.Printf("Consumed message from Kafka: %s\n", string(msg.Value))
+// ConvertMessageToProto converts a message to a protobuf message.
+// Decoding failures are permanent, so a handler returning this error is dead-lettered without retries.
+func ConvertMessageToProto(msg *kafka.Message, out proto.Message) error {
 	if err := proto.Unmarshal(msg.Value, out); err != nil {
-		log.Printf("Failed to unmarshal message: %v", err)
+		return Permanent(fmt.Errorf("failed to unmarshal message: %w", err))
 	}
+	return nil
 }
 
 // ConvertProtoToMessage converts a protobuf message to a byte array
 func ConvertProtoToMessage(in proto.Message) ([]byte, error) {
-	log.Printf("Producing message to Kafka: %v\n", in)
 	return proto.Marshal(in)
 }
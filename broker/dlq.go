@@ -0,0 +1,271 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deadLetterSuffix is appended to a topic name to get its dead-letter topic
+const deadLetterSuffix = ".dlq"
+
+// offsetsTimeout bounds the metadata and offset lookups done before a replay
+const offsetsTimeout = 5 * time.Second
+
+// Headers added to dead-lettered messages
+const (
+	HeaderDLQOriginalTopic     = "dlq-original-topic"
+	HeaderDLQOriginalPartition = "dlq-original-partition"
+	HeaderDLQOriginalOffset    = "dlq-original-offset"
+	HeaderDLQError             = "dlq-error"
+	HeaderDLQAttempts          = "dlq-attempts"
+	HeaderDLQFailedAt          = "dlq-failed-at"
+)
+
+// DeadLetterTopic returns the dead-letter topic for the given topic (e.g. "qwallet.account.created.dlq")
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// deadLetter publishes a failed message to its dead-letter topic and waits for the delivery report
+func deadLetter(ctx context.Context, p *kafka.Producer, msg *kafka.Message, cause error, attempts int) error {
+	topic := DeadLetterTopic(*msg.TopicPartition.Topic)
+	headers := withoutDLQHeaders(msg.Headers)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQOriginalTopic, Value: []byte(*msg.TopicPartition.Topic)},
+		kafka.Header{Key: HeaderDLQOriginalPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderDLQOriginalOffset, Value: []byte(strconv.FormatInt(int64(msg.TopicPartition.Offset), 10))},
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+
+	return produceAndWait(ctx, p, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	})
+}
+
+// produceAndWait produces a message and blocks until it is delivered or ctx is done.
+// A message abandoned because of ctx may still be delivered later.
+func produceAndWait(ctx context.Context, p *kafka.Producer, msg *kafka.Message) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveProduce(*msg.TopicPartition.Topic, time.Since(start), err)
+	}()
+
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := p.Produce(msg, deliveryChan); err != nil {
+		return fmt.Errorf("failed to produce message: %w", err)
+	}
+
+	var ev kafka.Event
+	select {
+	case ev = <-deliveryChan:
+	case <-ctx.Done():
+		return fmt.Errorf("delivery not confirmed: %w", ctx.Err())
+	}
+
+	switch e := ev.(type) {
+	case *kafka.Message:
+		if e.TopicPartition.Error != nil {
+			return fmt.Errorf("delivery failed: %w", e.TopicPartition.Error)
+		}
+		return nil
+	case kafka.Error:
+		return fmt.Errorf("delivery failed: %w", e)
+	default:
+		return fmt.Errorf("unexpected delivery event: %v", e)
+	}
+}
+
+// withoutDLQHeaders returns a copy of the headers without the dead-letter headers
+func withoutDLQHeaders(headers []kafka.Header) []kafka.Header {
+	out := make([]kafka.Header, 0, len(headers))
+	for _, h := range headers {
+		if !strings.HasPrefix(h.Key, "dlq-") {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// headerValue returns the value of the first header with the given key
+func headerValue(headers []kafka.Header, key string) (string, bool) {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+// ReplayConfig configures `ReplayDeadLetters`
+type ReplayConfig struct {
+	// Servers is the Kafka bootstrap servers list
+	Servers string
+
+	// Topic is the source topic whose dead letters are replayed
+	Topic string
+
+	// GroupID is the consumer group used to track replay progress (defaults to "<topic>.dlq.replay")
+	GroupID string
+
+	// Producer publishes the replayed messages back to the source topic
+	Producer *kafka.Producer
+
+	// Limit is the maximum number of messages to replay (0 replays everything)
+	Limit int
+
+	// IdleTimeout ends the replay when no message arrives for this long (defaults to 10s)
+	IdleTimeout time.Duration
+
+	// Logger receives the replay records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// ReplayDeadLetters moves messages from the dead-letter topic back to the topic they failed on.
+// Each message is only committed on the dead-letter topic after it was delivered to the source topic.
+// The replay stops at the end of the dead-letter topic as of its start, so messages that fail again
+// and are dead-lettered during the replay are left for the next one.
+// It returns the number of replayed messages.
+func ReplayDeadLetters(ctx context.Context, cfg ReplayConfig) (int, error) {
+	if cfg.Producer == nil {
+		return 0, errors.New("broker: replay requires a producer")
+	}
+	if cfg.GroupID == "" {
+		cfg.GroupID = DeadLetterTopic(cfg.Topic) + ".replay"
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Servers,
+		"group.id":           cfg.GroupID,
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			cfg.Logger.ErrorContext(ctx, "failed to close replay consumer",
+				slog.String("group_id", cfg.GroupID),
+				slog.Any("error", err),
+			)
+		}
+	}()
+
+	dlq := DeadLetterTopic(cfg.Topic)
+	if err = consumer.Subscribe(dlq, nil); err != nil {
+		return 0, fmt.Errorf("failed to subscribe to %s: %w", dlq, err)
+	}
+
+	ends, err := replayEnds(consumer, dlq)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	idleSince := time.Now()
+	for len(ends) > 0 && (cfg.Limit == 0 || replayed < cfg.Limit) {
+		if ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+		if time.Since(idleSince) > cfg.IdleTimeout {
+			break
+		}
+
+		msg, err := consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			var kErr kafka.Error
+			if errors.As(err, &kErr) && kErr.IsTimeout() {
+				continue
+			}
+			return replayed, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		idleSince = time.Now()
+
+		partition, offset := msg.TopicPartition.Partition, msg.TopicPartition.Offset
+		if end, ok := ends[partition]; !ok || offset >= end {
+			// dead-lettered after the replay started, left uncommitted for the next replay
+			delete(ends, partition)
+			continue
+		}
+
+		topic, ok := headerValue(msg.Headers, HeaderDLQOriginalTopic)
+		if !ok {
+			topic = cfg.Topic
+		}
+		if err = produceAndWait(ctx, cfg.Producer, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            msg.Key,
+			Value:          msg.Value,
+			Headers:        withoutDLQHeaders(msg.Headers),
+		}); err != nil {
+			return replayed, fmt.Errorf("failed to replay message %v: %w", msg.TopicPartition, err)
+		}
+
+		if _, err = consumer.CommitMessage(msg); err != nil {
+			return replayed, fmt.Errorf("failed to commit replayed message %v: %w", msg.TopicPartition, err)
+		}
+		replayed++
+		if offset+1 >= ends[partition] {
+			delete(ends, partition)
+		}
+	}
+
+	cfg.Logger.InfoContext(ctx, "replayed dead letters",
+		slog.String("topic", dlq),
+		slog.Int("replayed", replayed),
+	)
+	return replayed, nil
+}
+
+// replayEnds returns the end offset of every dead-letter partition that has messages left to replay
+func replayEnds(consumer *kafka.Consumer, topic string) (map[int32]kafka.Offset, error) {
+	timeout := int(offsetsTimeout.Milliseconds())
+	md, err := consumer.GetMetadata(&topic, false, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of %s: %w", topic, err)
+	}
+
+	partitions := make([]kafka.TopicPartition, 0, len(md.Topics[topic].Partitions))
+	for _, p := range md.Topics[topic].Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: p.ID})
+	}
+	committed, err := consumer.Committed(partitions, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get committed offsets of %s: %w", topic, err)
+	}
+
+	ends := make(map[int32]kafka.Offset, len(committed))
+	for _, tp := range committed {
+		low, high, err := consumer.QueryWatermarkOffsets(topic, tp.Partition, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offsets of %s [%d]: %w", topic, tp.Partition, err)
+		}
+		next := tp.Offset
+		if next < 0 {
+			// nothing committed yet, the replay starts at the earliest message
+			next = kafka.Offset(low)
+		}
+		if kafka.Offset(high) > next {
+			ends[tp.Partition] = kafka.Offset(high)
+		}
+	}
+	return ends, nil
+}
@@ -0,0 +1,113 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"strconv"
+	"time"
+)
+
+// Envelope headers, set on every message published with `Publish`
+const (
+	HeaderEventID       = "event-id"
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderOccurredAt    = "occurred-at"
+	HeaderUserID        = "user-id"
+	HeaderTraceID       = "trace-id"
+	HeaderProducer      = "producer"
+	HeaderCorrelationID = "correlation-id"
+	HeaderContentType   = "content-type"
+)
+
+// ErrMissingEnvelope is returned when a message lacks the required envelope headers
+var ErrMissingEnvelope = errors.New("broker: message has no event envelope")
+
+// Envelope describes an event independently of its payload.
+// It travels in the Kafka headers so consumers can route and filter without decoding the value.
+type Envelope struct {
+	// ID uniquely identifies the event, consumers use it to drop duplicates
+	ID string
+
+	// Type is the full name of the payload's proto message (e.g. "qwallet.events.v1.AccountCreated")
+	Type string
+
+	// SchemaVersion is the version of the payload schema, bumped on breaking changes
+	SchemaVersion int
+
+	// OccurredAt is when the event happened
+	OccurredAt time.Time
+
+	// UserID is the user the event belongs to, if any
+	UserID string
+
+	// TraceID is the trace the event was published in, if any
+	TraceID string
+
+	// Producer names the service that published the event
+	Producer string
+
+	// CorrelationID ties the event to the request or event that caused it, if any
+	CorrelationID string
+
+	// ContentType is the encoding of the payload (see `Codec`)
+	ContentType string
+}
+
+// Headers returns the envelope as Kafka headers, leaving out empty optional fields
+func (e Envelope) Headers() []kafka.Header {
+	headers := []kafka.Header{
+		{Key: HeaderEventID, Value: []byte(e.ID)},
+		{Key: HeaderEventType, Value: []byte(e.Type)},
+		{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(e.SchemaVersion))},
+		{Key: HeaderOccurredAt, Value: []byte(e.OccurredAt.UTC().Format(time.RFC3339Nano))},
+	}
+	optional := []struct{ key, value string }{
+		{HeaderUserID, e.UserID},
+		{HeaderTraceID, e.TraceID},
+		{HeaderProducer, e.Producer},
+		{HeaderCorrelationID, e.CorrelationID},
+		{HeaderContentType, e.ContentType},
+	}
+	for _, h := range optional {
+		if h.value != "" {
+			headers = append(headers, kafka.Header{Key: h.key, Value: []byte(h.value)})
+		}
+	}
+	return headers
+}
+
+// ParseEnvelope reads the envelope from the message headers.
+// The event id and type are required, the other fields are best effort.
+func ParseEnvelope(msg *kafka.Message) (Envelope, error) {
+	var e Envelope
+	var ok bool
+	if e.ID, ok = headerValue(msg.Headers, HeaderEventID); !ok || e.ID == "" {
+		return e, ErrMissingEnvelope
+	}
+	if e.Type, ok = headerValue(msg.Headers, HeaderEventType); !ok || e.Type == "" {
+		return e, ErrMissingEnvelope
+	}
+
+	if v, ok := headerValue(msg.Headers, HeaderSchemaVersion); ok {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return e, fmt.Errorf("invalid %s header %q: %w", HeaderSchemaVersion, v, err)
+		}
+		e.SchemaVersion = version
+	}
+	if v, ok := headerValue(msg.Headers, HeaderOccurredAt); ok {
+		at, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return e, fmt.Errorf("invalid %s header %q: %w", HeaderOccurredAt, v, err)
+		}
+		e.OccurredAt = at
+	}
+	e.UserID, _ = headerValue(msg.Headers, HeaderUserID)
+	e.TraceID, _ = headerValue(msg.Headers, HeaderTraceID)
+	e.Producer, _ = headerValue(msg.Headers, HeaderProducer)
+	e.CorrelationID, _ = headerValue(msg.Headers, HeaderCorrelationID)
+	e.ContentType, _ = headerValue(msg.Headers, HeaderContentType)
+	return e, nil
+}
@@ -0,0 +1,109 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+// PublishOption sets a field of the envelope of a published event
+type PublishOption func(*Envelope)
+
+// WithEventID sets the event id instead of generating one (e.g. to republish an event)
+func WithEventID(id string) PublishOption {
+	return func(e *Envelope) { e.ID = id }
+}
+
+// WithUserID sets the user the event belongs to
+func WithUserID(userID string) PublishOption {
+	return func(e *Envelope) { e.UserID = userID }
+}
+
+// WithCorrelationID ties the event to the request or event that caused it
+func WithCorrelationID(id string) PublishOption {
+	return func(e *Envelope) { e.CorrelationID = id }
+}
+
+// WithSchemaVersion sets the payload schema version (defaults to 1)
+func WithSchemaVersion(version int) PublishOption {
+	return func(e *Envelope) { e.SchemaVersion = version }
+}
+
+// WithOccurredAt sets when the event happened (defaults to now)
+func WithOccurredAt(t time.Time) PublishOption {
+	return func(e *Envelope) { e.OccurredAt = t }
+}
+
+// Publish encodes the event with the producer's codec, wraps it in an envelope and publishes it
+func Publish[T proto.Message](ctx context.Context, p *Producer, topic string, key []byte, event T, opts ...PublishOption) *Delivery {
+	env := Envelope{
+		ID:            uuid.NewString(),
+		Type:          string(proto.MessageName(event)),
+		SchemaVersion: 1,
+		OccurredAt:    time.Now(),
+		Producer:      p.cfg.Name,
+		ContentType:   p.cfg.Codec.ContentType(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		env.TraceID = sc.TraceID().String()
+	}
+	for _, opt := range opts {
+		opt(&env)
+	}
+
+	value, err := p.cfg.Codec.Encode(topic, event)
+	if err != nil {
+		d := &Delivery{done: make(chan struct{})}
+		d.complete(nil, fmt.Errorf("failed to encode %s: %w", env.Type, err))
+		return d
+	}
+	return p.Publish(ctx, topic, key, value, env.Headers()...)
+}
+
+// Decode reads the envelope of the message and decodes its payload into a T.
+// A payload of another type than T, or one that cannot be decoded, is a permanent failure.
+func Decode[T any, PT interface {
+	*T
+	proto.Message
+}](msg *kafka.Message, codec Codec) (PT, Envelope, error) {
+	env, err := ParseEnvelope(msg)
+	if err != nil {
+		return nil, env, Permanent(err)
+	}
+
+	out := PT(new(T))
+	if want := string(proto.MessageName(out)); env.Type != want {
+		return nil, env, Permanent(fmt.Errorf("unexpected event type %s, expected %s", env.Type, want))
+	}
+	if err = codec.Decode(*msg.TopicPartition.Topic, msg.Value, out); err != nil {
+		return nil, env, Permanent(fmt.Errorf("failed to decode %s: %w", env.Type, err))
+	}
+	return out, env, nil
+}
+
+// IsTombstone reports whether the message is a tombstone, which removes its key from a compacted topic
+func IsTombstone(msg *kafka.Message) bool {
+	return msg.Value == nil && len(msg.Headers) == 0
+}
+
+// EventHandler creates a `Handler` that decodes the enveloped event into T before calling fn.
+// Tombstones carry no event and are skipped.
+func EventHandler[T any, PT interface {
+	*T
+	proto.Message
+}](codec Codec, fn func(ctx context.Context, event PT, env Envelope) error) Handler {
+	return HandlerFunc(func(ctx context.Context, msg *kafka.Message) error {
+		if IsTombstone(msg) {
+			return nil
+		}
+		event, env, err := Decode[T, PT](msg, codec)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, event, env)
+	})
+}
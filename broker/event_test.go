@@ -0,0 +1,157 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	eventsv1 "github.com/qwallet-expense-tracker/shared/events/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+	"time"
+)
+
+// publishAndConsume publishes the event through a mock cluster and returns the message read back from it
+func publishAndConsume(t *testing.T, codec Codec, event proto.Message, opts ...PublishOption) *kafka.Message {
+	t.Helper()
+
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to create mock cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+
+	topic := string(AccountCreated)
+	if err = cluster.CreateTopic(topic, 1, 1); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	producer, err := NewAsyncProducer(ProducerConfig{
+		Servers: cluster.BootstrapServers(),
+		Name:    "event-test",
+		Codec:   codec,
+	})
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	t.Cleanup(func() { _ = producer.Close(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = Publish(ctx, producer, topic, []byte("user-1"), event, opts...).Wait(ctx); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cluster.BootstrapServers(),
+		"group.id":           "event-test",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	t.Cleanup(func() { _ = consumer.Close() })
+
+	if err = consumer.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: kafka.OffsetBeginning}}); err != nil {
+		t.Fatalf("failed to assign partition: %v", err)
+	}
+	msg, err := consumer.ReadMessage(10 * time.Second)
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestPublishDecodeSchemaRegistryRoundTrip(t *testing.T) {
+	codec, err := NewSchemaRegistryCodec("mock://event-test")
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+
+	event := &eventsv1.AccountCreated{Account: &eventsv1.Account{
+		AccountNumber: "0123456789",
+		Name:          "Savings",
+		Balance:       &eventsv1.Money{Minor: 12_345, Currency: "GHS"},
+		UserId:        "user-1",
+		UpdatedAt:     timestamppb.New(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+	}}
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 123_000_000, time.UTC)
+	msg := publishAndConsume(t, codec, event,
+		WithEventID("event-1"),
+		WithUserID("user-1"),
+		WithCorrelationID("request-1"),
+		WithSchemaVersion(2),
+		WithOccurredAt(occurredAt),
+	)
+
+	// the Confluent wire format starts with the magic byte and the schema id
+	if len(msg.Value) < 5 || msg.Value[0] != 0 {
+		t.Fatalf("value is not in the schema registry wire format: %x", msg.Value)
+	}
+
+	got, env, err := Decode[eventsv1.AccountCreated](msg, codec)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !proto.Equal(got, event) {
+		t.Errorf("decoded %v, want %v", got, event)
+	}
+
+	want := Envelope{
+		ID:            "event-1",
+		Type:          "qwallet.events.v1.AccountCreated",
+		SchemaVersion: 2,
+		OccurredAt:    occurredAt,
+		UserID:        "user-1",
+		Producer:      "event-test",
+		CorrelationID: "request-1",
+		ContentType:   ContentTypeSchemaRegistry,
+	}
+	if !env.OccurredAt.Equal(want.OccurredAt) {
+		t.Errorf("occurred at %v, want %v", env.OccurredAt, want.OccurredAt)
+	}
+	env.OccurredAt = want.OccurredAt
+	if env != want {
+		t.Errorf("envelope %+v, want %+v", env, want)
+	}
+}
+
+func TestDecodePermanentFailures(t *testing.T) {
+	codec, err := NewSchemaRegistryCodec("mock://event-test-failures")
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+	msg := publishAndConsume(t, codec, &eventsv1.AccountCreated{Account: &eventsv1.Account{AccountNumber: "0123456789"}})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		got, env, err := Decode[eventsv1.UserDeleted](msg, codec)
+		if !IsPermanent(err) {
+			t.Fatalf("expected a permanent error, got %v", err)
+		}
+		if got != nil {
+			t.Errorf("expected no event, got %v", got)
+		}
+		if env.Type != "qwallet.events.v1.AccountCreated" {
+			t.Errorf("envelope type %s, want qwallet.events.v1.AccountCreated", env.Type)
+		}
+	})
+
+	t.Run("missing envelope", func(t *testing.T) {
+		bare := *msg
+		bare.Headers = nil
+		_, _, err := Decode[eventsv1.AccountCreated](&bare, codec)
+		if !IsPermanent(err) || !errors.Is(err, ErrMissingEnvelope) {
+			t.Fatalf("expected a permanent ErrMissingEnvelope, got %v", err)
+		}
+	})
+
+	t.Run("undecodable payload", func(t *testing.T) {
+		corrupt := *msg
+		corrupt.Value = []byte("not protobuf")
+		_, _, err := Decode[eventsv1.AccountCreated](&corrupt, codec)
+		if !IsPermanent(err) {
+			t.Fatalf("expected a permanent error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler processes a single Kafka message.
+// Returning an error leaves the message's offset uncommitted.
+type Handler interface {
+	Handle(ctx context.Context, msg *kafka.Message) error
+}
+
+// HandlerFunc adapts an ordinary function to the `Handler` interface
+type HandlerFunc func(ctx context.Context, msg *kafka.Message) error
+
+// Handle calls f(ctx, msg)
+func (f HandlerFunc) Handle(ctx context.Context, msg *kafka.Message) error {
+	return f(ctx, msg)
+}
+
+// ProtoHandler creates a `Handler` that decodes the message value into T before calling fn
+func ProtoHandler[T any, PT interface {
+	*T
+	proto.Message
+}](fn func(ctx context.Context, msg PT) error) Handler {
+	return HandlerFunc(func(ctx context.Context, msg *kafka.Message) error {
+		out := PT(new(T))
+		if err := proto.Unmarshal(msg.Value, out); err != nil {
+			return Permanent(fmt.Errorf("failed to unmarshal message: %w", err))
+		}
+		return fn(ctx, out)
+	})
+}
+
+// HandlerError is returned by the runner when a handler fails to process a message
+type HandlerError struct {
+	Topic     string
+	Partition int32
+	Offset    kafka.Offset
+	Err       error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler failed for %s [%d] @ %v: %v", e.Topic, e.Partition, e.Offset, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
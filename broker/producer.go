@@ -1,8 +1,12 @@
 package broker
 
 import (
+	"context"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"github.com/qwallet-expense-tracker/shared/telemetry"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // NewProducer creates a new Kafka producer.
@@ -11,24 +15,29 @@ func NewProducer(brokers string) (*kafka.Producer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create producer: %w", err)
 	}
+	metrics.RegisterProducer(p)
 	return p, nil
 }
 
 // ProduceMessage produces a message to the given topic.
 func ProduceMessage(p *kafka.Producer, topic string, key, value []byte) error {
-	deliveryChan := make(chan kafka.Event)
-	if err := p.Produce(&kafka.Message{
+	return ProduceMessageContext(context.Background(), p, topic, key, value)
+}
+
+// ProduceMessageContext produces a message to the given topic, propagating the trace of ctx in the message headers.
+func ProduceMessageContext(ctx context.Context, p *kafka.Producer, topic string, key, value []byte) error {
+	msg := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Key:            key,
 		Value:          value,
-	}, deliveryChan); err != nil {
-		return fmt.Errorf("failed to produce message: %w", err)
 	}
-	
-	e := <-deliveryChan
-	m := e.(*kafka.Message)
-	if m.TopicPartition.Error != nil {
-		return fmt.Errorf("delivery failed: %w", m.TopicPartition.Error)
+	_, span := telemetry.StartProduce(ctx, msg)
+	defer span.End()
+
+	if err := produceAndWait(ctx, p, msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,116 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how often a failed message is retried before it is dead-lettered
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one (defaults to 3)
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry (defaults to 200ms)
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries (defaults to 10s)
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff grows by after each retry (defaults to 2)
+	Multiplier float64
+
+	// Jitter randomizes each backoff by up to this fraction (e.g. 0.2 => ±20%)
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the retry policy used for topics without their own policy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// withDefaults fills the unset fields from the default policy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = def.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = def.Multiplier
+	}
+	return p
+}
+
+// Backoff returns the wait before the given retry (1 is the first retry)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	p = p.withDefaults()
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	return time.Duration(backoff)
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error so that the message is dead-lettered without being retried
+// (e.g. a payload that can never be decoded)
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error was marked with `Permanent`
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// retry calls fn until it succeeds, returns a permanent error or the policy is exhausted.
+// It returns the last error and the number of attempts made.
+func retry(ctx context.Context, p RetryPolicy, fn func() error) (int, error) {
+	p = p.withDefaults()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || IsPermanent(err) || attempt >= p.MaxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, errors.Join(err, ctx.Err())
+		case <-time.After(p.Backoff(attempt)):
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"github.com/qwallet-expense-tracker/shared/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RunnerConfig configures a `Runner`
+type RunnerConfig struct {
+	// Servers is the Kafka bootstrap servers list
+	Servers string
+
+	// GroupID is the consumer group id
+	GroupID string
+
+	// Workers is the number of concurrent handlers (defaults to the number of CPUs).
+	// Messages of the same partition are always handled by the same worker, in order.
+	Workers int
+
+	// CommitInterval is how often the offsets of handled messages are committed (defaults to 5s)
+	CommitInterval time.Duration
+
+	// ShutdownTimeout bounds how long in-flight handlers may run after shutdown starts (defaults to 30s)
+	ShutdownTimeout time.Duration
+
+	// Config holds extra consumer configuration, applied on top of the runner's defaults
+	Config kafka.ConfigMap
+
+	// Retry is the retry policy for topics without an entry in `TopicRetry`
+	Retry RetryPolicy
+
+	// TopicRetry overrides the retry policy per topic
+	TopicRetry map[string]RetryPolicy
+
+	// DeadLetterProducer publishes messages that still fail after the last attempt to `<topic>.dlq`.
+	// Without it, such a failure stops the runner and the message is redelivered on the next start.
+	DeadLetterProducer *kafka.Producer
+
+	// Logger receives the consumer records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// Runner consumes topics and dispatches each message to the handler registered for its topic.
+// Offsets are only committed once the handler has succeeded, so delivery is at-least-once.
+type Runner struct {
+	cfg      RunnerConfig
+	handlers map[string]Handler
+
+	consumer *kafka.Consumer
+	inflight sync.WaitGroup
+
+	// unregisterMetrics removes the offsets of the consumer once it is closed
+	unregisterMetrics func()
+}
+
+// NewRunner creates a new consumer runner
+func NewRunner(cfg RunnerConfig) *Runner {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = 5 * time.Second
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Runner{cfg: cfg, handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler for the given topic.
+// It panics if the topic already has a handler, like `http.ServeMux` does.
+func (r *Runner) Handle(topic string, h Handler) {
+	if h == nil {
+		panic("broker: nil handler for topic " + topic)
+	}
+	if _, exists := r.handlers[topic]; exists {
+		panic("broker: multiple handlers registered for topic " + topic)
+	}
+	r.handlers[topic] = h
+}
+
+// HandleFunc registers the handler function for the given topic
+func (r *Runner) HandleFunc(topic string, fn func(ctx context.Context, msg *kafka.Message) error) {
+	r.Handle(topic, HandlerFunc(fn))
+}
+
+// Topics returns the topics that have a registered handler
+func (r *Runner) Topics() []string {
+	topics := make([]string, 0, len(r.handlers))
+	for topic := range r.handlers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Run consumes messages until the context is cancelled, SIGTERM/SIGINT is received or a handler fails.
+// In-flight messages are drained and their offsets committed before it returns.
+func (r *Runner) Run(ctx context.Context) error {
+	if len(r.handlers) == 0 {
+		return errors.New("broker: runner has no handlers")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := r.connect(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.consumer.Close(); err != nil {
+			r.cfg.Logger.ErrorContext(ctx, "failed to close consumer",
+				slog.String("group_id", r.cfg.GroupID),
+				slog.Any("error", err),
+			)
+		}
+		r.unregisterMetrics()
+	}()
+
+	// handlers keep running after shutdown starts until they finish or the shutdown timeout expires
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	errChan := make(chan error, r.cfg.Workers)
+	workers := make([]chan *kafka.Message, r.cfg.Workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = make(chan *kafka.Message, 1)
+		wg.Add(1)
+		go func(msgs <-chan *kafka.Message) {
+			defer wg.Done()
+			r.work(handlerCtx, msgs, errChan)
+		}(workers[i])
+	}
+
+	runErr := r.poll(ctx, workers, errChan)
+
+	// drain: no new messages are dispatched, wait for the in-flight ones
+	for _, w := range workers {
+		close(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(r.cfg.ShutdownTimeout):
+		r.cfg.Logger.WarnContext(ctx, "consumer shutdown timed out, cancelling handlers",
+			slog.String("group_id", r.cfg.GroupID),
+			slog.Duration("timeout", r.cfg.ShutdownTimeout),
+		)
+		cancelHandlers()
+		<-done
+	}
+
+	if runErr == nil {
+		select {
+		case runErr = <-errChan:
+		default:
+		}
+	}
+
+	r.commit()
+	return runErr
+}
+
+// connect creates the consumer and subscribes to the registered topics
+func (r *Runner) connect() error {
+	config := kafka.ConfigMap{
+		"bootstrap.servers":        r.cfg.Servers,
+		"group.id":                 r.cfg.GroupID,
+		"auto.offset.reset":        "earliest",
+		"enable.auto.commit":       false,
+		"enable.auto.offset.store": false,
+	}
+	for k, v := range r.cfg.Config {
+		config[k] = v
+	}
+
+	consumer, err := kafka.NewConsumer(&config)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+	if err = consumer.SubscribeTopics(r.Topics(), r.rebalance); err != nil {
+		_ = consumer.Close()
+		return fmt.Errorf("failed to subscribe to topics %+v: %w", r.Topics(), err)
+	}
+	r.unregisterMetrics = metrics.RegisterConsumer(r.cfg.GroupID, consumer)
+	r.consumer = consumer
+	return nil
+}
+
+// poll reads messages and dispatches them to the workers until the context is done or a handler fails
+func (r *Runner) poll(ctx context.Context, workers []chan *kafka.Message, errChan <-chan error) error {
+	ticker := time.NewTicker(r.cfg.CommitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errChan:
+			return err
+		case <-ticker.C:
+			r.commit()
+		default:
+		}
+
+		switch e := r.consumer.Poll(int(pollTimeout.Milliseconds())).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				r.cfg.Logger.With(messageAttrs(e.TopicPartition)...).ErrorContext(ctx, "consumer error",
+					slog.Any("error", e.TopicPartition.Error),
+				)
+				continue
+			}
+			r.inflight.Add(1)
+			worker := workers[partitionIndex(e.TopicPartition, len(workers))]
+			select {
+			case worker <- e:
+			case err := <-errChan:
+				r.inflight.Done()
+				return err
+			}
+		case kafka.Error:
+			if e.IsFatal() {
+				return fmt.Errorf("fatal consumer error: %w", e)
+			}
+			r.cfg.Logger.ErrorContext(ctx, "consumer error",
+				slog.String("code", e.Code().String()),
+				slog.Any("error", e),
+			)
+		}
+	}
+}
+
+// work handles the messages dispatched to one worker.
+// After a failure, later messages of the same partition are skipped so their offsets are never stored past it.
+func (r *Runner) work(ctx context.Context, msgs <-chan *kafka.Message, errChan chan<- error) {
+	type partition struct {
+		topic string
+		id    int32
+	}
+	failed := make(map[partition]bool)
+	for msg := range msgs {
+		tp := partition{topic: *msg.TopicPartition.Topic, id: msg.TopicPartition.Partition}
+		if failed[tp] {
+			r.inflight.Done()
+			continue
+		}
+
+		if err := r.process(ctx, msg); err != nil {
+			failed[tp] = true
+			select {
+			case errChan <- err:
+			default:
+			}
+		} else if _, err = r.consumer.StoreMessage(msg); err != nil {
+			r.cfg.Logger.With(messageAttrs(msg.TopicPartition)...).ErrorContext(ctx, "failed to store offset",
+				slog.Any("error", err),
+			)
+		}
+		r.inflight.Done()
+	}
+}
+
+// process handles a message with retries and dead-letters it once the retry policy is exhausted
+func (r *Runner) process(ctx context.Context, msg *kafka.Message) error {
+	topic := *msg.TopicPartition.Topic
+	h, ok := r.handlers[topic]
+	if !ok {
+		r.cfg.Logger.With(messageAttrs(msg.TopicPartition)...).WarnContext(ctx, "no handler registered for topic, skipping message")
+		return nil
+	}
+
+	ctx, span := telemetry.StartConsume(ctx, msg)
+	defer span.End()
+
+	attempts, err := retry(ctx, r.retryPolicy(topic), func() error {
+		return handle(ctx, h, msg)
+	})
+	span.SetAttributes(attribute.Int("messaging.attempts", attempts))
+	if err == nil {
+		metrics.RecordConsumed(topic, "ok")
+		return nil
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	// never dead-letter a message only because we are shutting down, it is redelivered instead
+	if r.cfg.DeadLetterProducer != nil && ctx.Err() == nil {
+		r.cfg.Logger.With(messageAttrs(msg.TopicPartition)...).WarnContext(ctx, "dead-lettering message",
+			slog.Int("attempts", attempts),
+			slog.Any("error", err),
+		)
+		dlqErr := deadLetter(ctx, r.cfg.DeadLetterProducer, msg, err, attempts)
+		if dlqErr == nil {
+			metrics.RecordConsumed(topic, "dead_lettered")
+			return nil
+		}
+		err = errors.Join(err, fmt.Errorf("failed to dead-letter message: %w", dlqErr))
+	}
+
+	metrics.RecordConsumed(topic, "error")
+	return &HandlerError{
+		Topic:     topic,
+		Partition: msg.TopicPartition.Partition,
+		Offset:    msg.TopicPartition.Offset,
+		Err:       err,
+	}
+}
+
+// retryPolicy returns the retry policy for the topic
+func (r *Runner) retryPolicy(topic string) RetryPolicy {
+	if p, ok := r.cfg.TopicRetry[topic]; ok {
+		return p
+	}
+	return r.cfg.Retry
+}
+
+// handle calls the handler, turning a panic into an error
+func handle(ctx context.Context, h Handler, msg *kafka.Message) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return h.Handle(ctx, msg)
+}
+
+// rebalance commits the handled offsets before partitions are taken away from this consumer
+func (r *Runner) rebalance(c *kafka.Consumer, ev kafka.Event) error {
+	if _, ok := ev.(kafka.RevokedPartitions); ok {
+		r.inflight.Wait()
+		r.commit()
+	}
+	return nil
+}
+
+// commit commits the stored offsets
+func (r *Runner) commit() {
+	if _, err := r.consumer.Commit(); err != nil {
+		var kErr kafka.Error
+		if errors.As(err, &kErr) && kErr.Code() == kafka.ErrNoOffset {
+			return
+		}
+		r.cfg.Logger.Error("failed to commit offsets",
+			slog.String("group_id", r.cfg.GroupID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// messageAttrs returns the log attributes that locate a message
+func messageAttrs(tp kafka.TopicPartition) []any {
+	topic := ""
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+	return []any{
+		slog.String("topic", topic),
+		slog.Int("partition", int(tp.Partition)),
+		slog.Int64("offset", int64(tp.Offset)),
+	}
+}
+
+// partitionIndex maps a partition to a worker so that a partition is always handled by the same worker
+func partitionIndex(tp kafka.TopicPartition, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(*tp.Topic))
+	return int((h.Sum32() + uint32(tp.Partition)) % uint32(n))
+}
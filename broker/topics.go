@@ -25,3 +25,56 @@ const (
 	NotificationUserCreated    NotificationTopic = "qwallet.notification.user.created"
 	NotificationAccountCreated NotificationTopic = "qwallet.notification.account.created"
 )
+
+// TransactionTopic represents the topic for transaction events.
+type TransactionTopic string
+
+const (
+	TransactionCreated TransactionTopic = "qwallet.transaction.created"
+	TransactionUpdated TransactionTopic = "qwallet.transaction.updated"
+	TransactionDeleted TransactionTopic = "qwallet.transaction.deleted"
+)
+
+// CategoryTopic represents the topic for category events.
+type CategoryTopic string
+
+const (
+	CategoryCreated CategoryTopic = "qwallet.category.created"
+	CategoryUpdated CategoryTopic = "qwallet.category.updated"
+	CategoryDeleted CategoryTopic = "qwallet.category.deleted"
+)
+
+// GoalTopic represents the topic for goal events.
+type GoalTopic string
+
+const (
+	GoalCreated     GoalTopic = "qwallet.goal.created"
+	GoalUpdated     GoalTopic = "qwallet.goal.updated"
+	GoalDeleted     GoalTopic = "qwallet.goal.deleted"
+	GoalContributed GoalTopic = "qwallet.goal.contributed"
+	GoalCompleted   GoalTopic = "qwallet.goal.completed"
+	GoalCancelled   GoalTopic = "qwallet.goal.cancelled"
+)
+
+// TransferTopic represents the topic for transfer events.
+type TransferTopic string
+
+const (
+	TransferCompleted TransferTopic = "qwallet.transfer.completed"
+)
+
+// BudgetTopic represents the topic for budget events.
+type BudgetTopic string
+
+const (
+	BudgetThresholdReached BudgetTopic = "qwallet.budget.threshold_reached"
+)
+
+// BeneficiaryTopic represents the topic for beneficiary events.
+type BeneficiaryTopic string
+
+const (
+	BeneficiaryCreated BeneficiaryTopic = "qwallet.beneficiary.created"
+	BeneficiaryUpdated BeneficiaryTopic = "qwallet.beneficiary.updated"
+	BeneficiaryDeleted BeneficiaryTopic = "qwallet.beneficiary.deleted"
+)
@@ -2,6 +2,10 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"log"
 )
@@ -9,6 +13,9 @@ import (
 // client is the cache client
 var client *redis.Client
 
+// ErrNotConnected is returned when the cache is used before `Connect` was called
+var ErrNotConnected = errors.New("cache: client is not connected")
+
 // Connect initializes the cache client
 func Connect(address, password string, db int) error {
 	log.Println("❄️connecting to cache...")
@@ -18,6 +25,12 @@ func Connect(address, password string, db int) error {
 		DB:       db,
 	})
 
+	// trace every command with the global tracer provider (see the telemetry package)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return fmt.Errorf("failed to instrument cache client: %w", err)
+	}
+	client.AddHook(metrics.RedisHook{})
+
 	// check if the connection is successful
 	if _, err := client.Ping(context.Background()).Result(); err != nil {
 		return err
@@ -27,9 +40,19 @@ func Connect(address, password string, db int) error {
 }
 
 // GetCacheClient returns the cache client
+//
+// Deprecated: exits the process before `Connect` was called, use `CacheClient` instead.
 func GetCacheClient() *redis.Client {
 	if client == nil {
 		log.Fatalln("cache client is not initialized")
 	}
 	return client
 }
+
+// CacheClient returns the cache client, or `ErrNotConnected` before `Connect` was called
+func CacheClient() (*redis.Client, error) {
+	if client == nil {
+		return nil, ErrNotConnected
+	}
+	return client, nil
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// GetJSON gets a JSON value stored with `StoreJSON` and decodes it into T
+func GetJSON[T any](ctx context.Context, c ICache, key string) (*T, error) {
+	value, err := c.GetString(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var out T
+	if err = json.Unmarshal([]byte(value), &out); err != nil {
+		return nil, fmt.Errorf("failed to decode cached value for %s: %w", key, err)
+	}
+	return &out, nil
+}
+
+// StoreJSON encodes the value as JSON and stores it
+func StoreJSON(ctx context.Context, c ICache, key string, value any, expiration time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for %s: %w", key, err)
+	}
+	return c.StoreString(ctx, key, string(b), expiration)
+}
+
+// StoreHashField stores one field of a hash when the cache supports it (see `IHashFieldStore`)
+func StoreHashField(ctx context.Context, c ICache, key, field string, value interface{}, expiration time.Duration) error {
+	store, ok := c.(IHashFieldStore)
+	if !ok {
+		return fmt.Errorf("cache %T cannot store a single hash field", c)
+	}
+	return store.StoreHashField(ctx, key, field, value, expiration)
+}
+
+// GetListOf gets a list stored with `StoreList` and decodes each element into T.
+// String elements are returned as-is for T = string, everything else is decoded as JSON.
+func GetListOf[T any](ctx context.Context, c ICache, key string) ([]T, error) {
+	values, err := c.GetList(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]T, len(values))
+	for i, v := range values {
+		if err = decodeElement(v, &out[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode element %d of %s: %w", i, key, err)
+		}
+	}
+	return out, nil
+}
+
+// GetHashOf gets a hash stored with `StoreHash` and decodes each field into T
+func GetHashOf[T any](ctx context.Context, c ICache, key string) (map[string]T, error) {
+	fields, err := c.GetHash(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]T, len(fields))
+	for k, v := range fields {
+		var decoded T
+		if err = decodeElement(v, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode field %s of %s: %w", k, key, err)
+		}
+		out[k] = decoded
+	}
+	return out, nil
+}
+
+// ScanHash scans the hash stored at the key into a struct with `redis:"field"` tags
+func ScanHash(ctx context.Context, c ICache, key string, dst any) error {
+	fields, err := c.GetHash(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(fields))
+	for k, v := range fields {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("unexpected value type %T of field %s of %s", v, k, key)
+		}
+		values[k] = s
+	}
+	cmd := redis.NewMapStringStringCmd(ctx)
+	cmd.SetVal(values)
+	return cmd.Scan(dst)
+}
+
+// decodeElement decodes a raw cached value into dst
+func decodeElement[T any](v any, dst *T) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("unexpected value type %T", v)
+	}
+	if str, ok := any(dst).(*string); ok {
+		*str = s
+		return nil
+	}
+	return json.Unmarshal([]byte(s), dst)
+}
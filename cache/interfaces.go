@@ -29,3 +29,9 @@ type ICache interface {
 
 	Delete(context.Context, string) error
 }
+
+// IHashFieldStore is implemented by caches that can write a single field of a hash
+type IHashFieldStore interface {
+	// StoreHashField - store one field of a hash, leaving the other fields as they are
+	StoreHashField(context.Context, string, string, interface{}, time.Duration) error
+}
@@ -2,12 +2,24 @@ package cache
 
 import (
 	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
 	"time"
 )
 
-type cache struct {
-	ICache
-}
+// ErrCacheMiss is returned when the key does not exist (it is `redis.Nil`, so existing checks keep working)
+var ErrCacheMiss = redis.Nil
+
+// emptyMarker is stored as the only element of an empty list or field of an empty hash,
+// redis deletes empty lists and hashes so they could not be told apart from a miss otherwise
+const emptyMarker = "\x00empty"
+
+type cache struct{}
+
+// ensure every method of the `ICache` interface is implemented
+var _ ICache = (*cache)(nil)
 
 // New returns a new cache instance
 func New() ICache {
@@ -16,15 +28,198 @@ func New() ICache {
 
 // Get returns the value of the key
 func (c *cache) Get(ctx context.Context, key string) (string, error) {
-	return GetCacheClient().Get(ctx, key).Result()
+	client, err := CacheClient()
+	if err != nil {
+		return "", err
+	}
+	return client.Get(ctx, key).Result()
 }
 
 // Set sets the value of the key
 func (c *cache) Set(ctx context.Context, key, value string, expiration time.Duration) error {
-	return GetCacheClient().Set(ctx, key, value, expiration).Err()
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, key, value, expiration).Err()
+}
+
+// GetString returns the string/JSON value of the key
+func (c *cache) GetString(ctx context.Context, key string) (string, error) {
+	client, err := CacheClient()
+	if err != nil {
+		return "", err
+	}
+	return client.Get(ctx, key).Result()
+}
+
+// GetHash returns all the fields of the hash stored at the key
+func (c *cache) GetHash(ctx context.Context, key string) (map[string]interface{}, error) {
+	client, err := CacheClient()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	// redis does not keep empty hashes, so an empty result means the key does not exist
+	if len(fields) == 0 {
+		return nil, ErrCacheMiss
+	}
+
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if k != emptyMarker {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// GetList returns all the elements of the list stored at the key
+func (c *cache) GetList(ctx context.Context, key string) ([]any, error) {
+	client, err := CacheClient()
+	if err != nil {
+		return nil, err
+	}
+	values, err := client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	// redis does not keep empty lists, so an empty result means the key does not exist
+	if len(values) == 0 {
+		return nil, ErrCacheMiss
+	}
+	if len(values) == 1 && values[0] == emptyMarker {
+		return []any{}, nil
+	}
+
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out, nil
+}
+
+// StoreString stores a string/JSON value
+func (c *cache) StoreString(ctx context.Context, key, value string, expiration time.Duration) error {
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, key, value, expiration).Err()
+}
+
+// StoreHash replaces the hash stored at the key. Values that are not strings or numbers are stored as JSON.
+func (c *cache) StoreHash(ctx context.Context, key string, values map[string]interface{}, expiration time.Duration) error {
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		encoded, err := encodeValue(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode field %s: %w", k, err)
+		}
+		fields[k] = encoded
+	}
+	if len(fields) == 0 {
+		fields[emptyMarker] = ""
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, fields)
+		if expiration > 0 {
+			pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+	return err
+}
+
+// StoreHashField sets a single field of the hash stored at the key and renews the expiration of the hash.
+// Unlike `StoreHash`, it never writes back fields that were read earlier and may have been invalidated since.
+func (c *cache) StoreHashField(ctx context.Context, key, field string, value interface{}, expiration time.Duration) error {
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode field %s: %w", field, err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, encoded)
+		pipe.HDel(ctx, key, emptyMarker)
+		if expiration > 0 {
+			pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+	return err
+}
+
+// StoreList replaces the list stored at the key. Values that are not strings or numbers are stored as JSON.
+func (c *cache) StoreList(ctx context.Context, key string, values []any, expiration time.Duration) error {
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+
+	elements := make([]interface{}, len(values))
+	for i, v := range values {
+		encoded, err := encodeValue(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode element %d: %w", i, err)
+		}
+		elements[i] = encoded
+	}
+	if len(elements) == 0 {
+		elements = append(elements, emptyMarker)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.RPush(ctx, key, elements...)
+		if expiration > 0 {
+			pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+	return err
 }
 
 // Delete deletes the key
 func (c *cache) Delete(ctx context.Context, key string) error {
-	return GetCacheClient().Del(ctx, key).Err()
+	client, err := CacheClient()
+	if err != nil {
+		return err
+	}
+	return client.Del(ctx, key).Err()
+}
+
+// encodeValue converts a value to something redis can store, falling back to JSON
+func encodeValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string, []byte, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64,
+		encoding.BinaryMarshaler:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
 }
@@ -0,0 +1,233 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/qwallet-expense-tracker/shared/cache"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Entities cached by the read-through layer
+const (
+	EntityAccounts   = "accounts"
+	EntityCategories = "categories"
+	EntityGoals      = "goals"
+)
+
+// Config configures the read-through cache
+type Config struct {
+	// KeyPrefix is prepended to every cache key (defaults to "qwallet:cache")
+	KeyPrefix string
+
+	// AccountsTTL is how long a user's accounts are cached (defaults to 1m, balances change often)
+	AccountsTTL time.Duration
+
+	// CategoriesTTL is how long a user's categories are cached (defaults to 1h)
+	CategoriesTTL time.Duration
+
+	// GoalsTTL is how long a user's goal pages are cached (defaults to 5m)
+	GoalsTTL time.Duration
+
+	// Logger receives the cache failures, which only cost a miss (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// Stats holds the counters of one cached entity
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Errors        uint64
+	Invalidations uint64
+}
+
+// counters holds the live counters of one cached entity
+type counters struct {
+	hits, misses, errors, invalidations atomic.Uint64
+}
+
+// Cache serves the account, category and goal lookups of `gen.Querier` from the cache package
+// and invalidates them on writes and on the Postgres `pg_notify` channels.
+type Cache struct {
+	store    cache.ICache
+	cfg      Config
+	counters map[string]*counters
+}
+
+// New creates a new read-through cache
+func New(store cache.ICache, cfg Config) *Cache {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "qwallet:cache"
+	}
+	if cfg.AccountsTTL <= 0 {
+		cfg.AccountsTTL = time.Minute
+	}
+	if cfg.CategoriesTTL <= 0 {
+		cfg.CategoriesTTL = time.Hour
+	}
+	if cfg.GoalsTTL <= 0 {
+		cfg.GoalsTTL = 5 * time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Cache{
+		store: store,
+		cfg:   cfg,
+		counters: map[string]*counters{
+			EntityAccounts:   {},
+			EntityCategories: {},
+			EntityGoals:      {},
+		},
+	}
+}
+
+// Wrap returns a `gen.Querier` that reads through this cache.
+// A `database.TxQuerier` of `InTx` bypasses the cache and only invalidates it once the transaction committed,
+// so uncommitted rows are never cached and a rollback leaves the cache untouched.
+func (c *Cache) Wrap(q gen.Querier) gen.Querier {
+	tx, _ := q.(database.TxQuerier)
+	return &querier{Querier: q, cache: c, tx: tx}
+}
+
+// Stats returns a snapshot of the hit/miss counters per entity
+func (c *Cache) Stats() map[string]Stats {
+	out := make(map[string]Stats, len(c.counters))
+	for entity, cnt := range c.counters {
+		out[entity] = Stats{
+			Hits:          cnt.hits.Load(),
+			Misses:        cnt.misses.Load(),
+			Errors:        cnt.errors.Load(),
+			Invalidations: cnt.invalidations.Load(),
+		}
+	}
+	return out
+}
+
+// Invalidate removes the cached entries of the entity for the user
+func (c *Cache) Invalidate(ctx context.Context, entity, userID string) {
+	c.invalidated(entity)
+	if err := c.store.Delete(ctx, c.key(entity, userID)); err != nil {
+		c.counters[entity].errors.Add(1)
+		c.cfg.Logger.ErrorContext(ctx, "cache: failed to invalidate",
+			slog.String("entity", entity),
+			slog.String("user_id", userID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// Listen invalidates cached entries from the `pg_notify` channels of the triggers until the context is cancelled.
+// The connection is dedicated to listening and must not be used for anything else meanwhile.
+func (c *Cache) Listen(ctx context.Context, conn *pgx.Conn) error {
+	// transactions change account balances, goal contributions are transactions too
+	channels := map[string][]string{
+		"accounts":     {EntityAccounts},
+		"categories":   {EntityCategories},
+		"goals":        {EntityGoals},
+		"transactions": {EntityAccounts, EntityGoals},
+	}
+	for channel := range channels {
+		if _, err := conn.Exec(ctx, "listen "+channel); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		var payload struct {
+			UserID string `json:"user_id"`
+		}
+		if err = json.Unmarshal([]byte(n.Payload), &payload); err != nil || payload.UserID == "" {
+			continue
+		}
+		for _, entity := range channels[n.Channel] {
+			c.Invalidate(ctx, entity, payload.UserID)
+		}
+	}
+}
+
+// key returns the cache key of the entity for the user
+func (c *Cache) key(entity, userID string) string {
+	return fmt.Sprintf("%s:%s:%s", c.cfg.KeyPrefix, entity, userID)
+}
+
+// categoryOwnerKey returns the key that maps a category to its user (`UpdateCategory` has no user id)
+func (c *Cache) categoryOwnerKey(categoryID string) string {
+	return fmt.Sprintf("%s:category-owner:%s", c.cfg.KeyPrefix, categoryID)
+}
+
+// hit counts a cache hit
+func (c *Cache) hit(entity string) {
+	c.counters[entity].hits.Add(1)
+	metrics.RecordCacheHit(entity)
+}
+
+// miss counts a cache miss
+func (c *Cache) miss(entity string) {
+	c.counters[entity].misses.Add(1)
+	metrics.RecordCacheMiss(entity)
+}
+
+// invalidated counts an invalidation
+func (c *Cache) invalidated(entity string) {
+	c.counters[entity].invalidations.Add(1)
+	metrics.RecordCacheInvalidation(entity)
+}
+
+// get reads a JSON value, counting hits, misses and errors
+func get[T any](ctx context.Context, c *Cache, entity, key string) (T, bool) {
+	var zero T
+	v, err := cache.GetJSON[T](ctx, c.store, key)
+	switch {
+	case err == nil:
+		c.hit(entity)
+		return *v, true
+	case errors.Is(err, cache.ErrCacheMiss):
+		c.miss(entity)
+	default:
+		c.miss(entity)
+		c.counters[entity].errors.Add(1)
+		c.cfg.Logger.WarnContext(ctx, "cache: failed to read", slog.String("key", key), slog.Any("error", err))
+	}
+	return zero, false
+}
+
+// put writes a JSON value, a failure only costs a future miss
+func put(ctx context.Context, c *Cache, entity, key string, value any, ttl time.Duration) {
+	if err := cache.StoreJSON(ctx, c.store, key, value, ttl); err != nil {
+		c.counters[entity].errors.Add(1)
+		c.cfg.Logger.WarnContext(ctx, "cache: failed to write", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// executor wraps every querier of an `Executor` with the cache
+type executor struct {
+	database.Executor
+	cache *Cache
+}
+
+// Executor returns an `Executor` whose queriers read through this cache, so repositories can use it directly
+func (c *Cache) Executor(e database.Executor) database.Executor {
+	return &executor{Executor: e, cache: c}
+}
+
+func (e *executor) Execute(ctx context.Context, fn func(q gen.Querier) error) error {
+	return e.Executor.Execute(ctx, func(q gen.Querier) error {
+		return fn(e.cache.Wrap(q))
+	})
+}
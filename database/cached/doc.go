@@ -0,0 +1,8 @@
+// Package cached provides a cache-aside decorator for `gen.Querier`.
+//
+// `GetAccounts`, `GetCategoriesForUser` and `ListUserGoals` are served from the cache package
+// with a TTL per entity. The write methods invalidate the affected entries once they succeed, and
+// `Cache.Listen` invalidates entries changed elsewhere through the `pg_notify` channels of the triggers.
+// Inside `database.InTx` the cache is bypassed and invalidated only after the commit.
+// Account balances converted into the base currency are only refreshed with new exchange rates once the TTL expires.
+package cached
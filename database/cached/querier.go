@@ -0,0 +1,205 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/cache"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"log/slog"
+)
+
+// querier decorates a `gen.Querier` with the read-through cache.
+// Methods that are not overridden go straight to the database.
+type querier struct {
+	gen.Querier
+	cache *Cache
+
+	// tx is set when the querier runs in a transaction
+	tx database.TxQuerier
+}
+
+func (q *querier) GetAccounts(ctx context.Context, userID string) ([]*gen.Accountpayload, error) {
+	if q.tx != nil {
+		return q.Querier.GetAccounts(ctx, userID)
+	}
+	key := q.cache.key(EntityAccounts, userID)
+	if items, ok := get[[]*gen.Accountpayload](ctx, q.cache, EntityAccounts, key); ok {
+		return items, nil
+	}
+
+	items, err := q.Querier.GetAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	put(ctx, q.cache, EntityAccounts, key, items, q.cache.cfg.AccountsTTL)
+	return items, nil
+}
+
+func (q *querier) GetCategoriesForUser(ctx context.Context, userID string) ([]*gen.Categorypayload, error) {
+	if q.tx != nil {
+		return q.Querier.GetCategoriesForUser(ctx, userID)
+	}
+	key := q.cache.key(EntityCategories, userID)
+	if items, ok := get[[]*gen.Categorypayload](ctx, q.cache, EntityCategories, key); ok {
+		return items, nil
+	}
+
+	items, err := q.Querier.GetCategoriesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if err = q.cache.store.StoreString(ctx, q.cache.categoryOwnerKey(item.ID), userID, q.cache.cfg.CategoriesTTL); err != nil {
+			q.cache.counters[EntityCategories].errors.Add(1)
+			q.cache.cfg.Logger.WarnContext(ctx, "cache: failed to store owner of category",
+				slog.String("category_id", item.ID),
+				slog.Any("error", err),
+			)
+		}
+	}
+	put(ctx, q.cache, EntityCategories, key, items, q.cache.cfg.CategoriesTTL)
+	return items, nil
+}
+
+func (q *querier) ListUserGoals(ctx context.Context, userID string, pageNumber int32, pageSize int32) ([]*gen.Goalpayload, error) {
+	if q.tx != nil {
+		return q.Querier.ListUserGoals(ctx, userID, pageNumber, pageSize)
+	}
+	// every page of a user lives in one hash, so a single delete invalidates all of them
+	key := q.cache.key(EntityGoals, userID)
+	field := fmt.Sprintf("%d:%d", pageNumber, pageSize)
+
+	pages, err := cache.GetHashOf[[]*gen.Goalpayload](ctx, q.cache.store, key)
+	switch {
+	case err == nil:
+		if items, ok := pages[field]; ok {
+			q.cache.hit(EntityGoals)
+			return items, nil
+		}
+		q.cache.miss(EntityGoals)
+	case errors.Is(err, cache.ErrCacheMiss):
+		q.cache.miss(EntityGoals)
+	default:
+		q.cache.miss(EntityGoals)
+		q.cache.counters[EntityGoals].errors.Add(1)
+		q.cache.cfg.Logger.WarnContext(ctx, "cache: failed to read", slog.String("key", key), slog.Any("error", err))
+	}
+
+	items, err := q.Querier.ListUserGoals(ctx, userID, pageNumber, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the page fetched now is written, the pages read earlier may have been invalidated meanwhile
+	if err = cache.StoreHashField(ctx, q.cache.store, key, field, items, q.cache.cfg.GoalsTTL); err != nil {
+		q.cache.counters[EntityGoals].errors.Add(1)
+		q.cache.cfg.Logger.WarnContext(ctx, "cache: failed to write", slog.String("key", key), slog.Any("error", err))
+	}
+	return items, nil
+}
+
+// invalidate runs the write and invalidates the entities of the user once it has succeeded,
+// or once the transaction committed when the querier runs in one
+func (q *querier) invalidate(ctx context.Context, userID string, err error, entities ...string) error {
+	if err != nil {
+		return err
+	}
+
+	invalidate := func(ctx context.Context) {
+		for _, entity := range entities {
+			q.cache.Invalidate(ctx, entity, userID)
+		}
+	}
+	if q.tx != nil {
+		q.tx.AfterCommit(func() { invalidate(context.WithoutCancel(ctx)) })
+		return nil
+	}
+	invalidate(ctx)
+	return nil
+}
+
+func (q *querier) CreateAccount(ctx context.Context, userID string, accountName string, initialBalance money.Amount, currency string) error {
+	return q.invalidate(ctx, userID, q.Querier.CreateAccount(ctx, userID, accountName, initialBalance, currency), EntityAccounts)
+}
+
+func (q *querier) UpdateAccount(ctx context.Context, accountNumber string, userID string, accountName string) error {
+	return q.invalidate(ctx, userID, q.Querier.UpdateAccount(ctx, accountNumber, userID, accountName), EntityAccounts)
+}
+
+func (q *querier) DeleteAccount(ctx context.Context, accountNumber string, userID string) error {
+	return q.invalidate(ctx, userID, q.Querier.DeleteAccount(ctx, accountNumber, userID), EntityAccounts)
+}
+
+func (q *querier) UpdateBaseCurrency(ctx context.Context, userID string, currency string) error {
+	// the accounts are listed with their balance in the base currency
+	return q.invalidate(ctx, userID, q.Querier.UpdateBaseCurrency(ctx, userID, currency), EntityAccounts)
+}
+
+func (q *querier) Deposit(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error {
+	return q.invalidate(ctx, userID, q.Querier.Deposit(ctx, userID, accountNumber, categoryID, amount, description), EntityAccounts)
+}
+
+func (q *querier) Withdraw(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error {
+	return q.invalidate(ctx, userID, q.Querier.Withdraw(ctx, userID, accountNumber, categoryID, amount, description), EntityAccounts)
+}
+
+func (q *querier) Transfer(ctx context.Context, userID string, fromAccountNumber string, toAccountNumber string, amount money.Amount, description string) (*gen.Transferpayload, error) {
+	transfer, err := q.Querier.Transfer(ctx, userID, fromAccountNumber, toAccountNumber, amount, description)
+	return transfer, q.invalidate(ctx, userID, err, EntityAccounts)
+}
+
+func (q *querier) UpdateTransaction(ctx context.Context, transactionID string, userID string, accountNumber string, categoryID string, transactionType string, amount money.Amount, description string, splitCategoryIds []string, splitAmounts []money.Amount, splitMemos []string) error {
+	return q.invalidate(ctx, userID, q.Querier.UpdateTransaction(ctx, transactionID, userID, accountNumber, categoryID, transactionType, amount, description, splitCategoryIds, splitAmounts, splitMemos), EntityAccounts, EntityGoals)
+}
+
+func (q *querier) DeleteTransaction(ctx context.Context, transactionID string, userID string) error {
+	return q.invalidate(ctx, userID, q.Querier.DeleteTransaction(ctx, transactionID, userID), EntityAccounts, EntityGoals)
+}
+
+func (q *querier) ContributeToGoal(ctx context.Context, userID string, goalID string, amount money.Amount, description string, accountNumber string) error {
+	return q.invalidate(ctx, userID, q.Querier.ContributeToGoal(ctx, userID, goalID, amount, description, accountNumber), EntityAccounts, EntityGoals)
+}
+
+func (q *querier) CreateGoal(ctx context.Context, userID string, name string, targetAmount money.Amount, description string) (string, error) {
+	goalID, err := q.Querier.CreateGoal(ctx, userID, name, targetAmount, description)
+	return goalID, q.invalidate(ctx, userID, err, EntityGoals)
+}
+
+func (q *querier) UpdateGoal(ctx context.Context, goalID string, userID string, name string, targetAmount money.Amount, description string) error {
+	return q.invalidate(ctx, userID, q.Querier.UpdateGoal(ctx, goalID, userID, name, targetAmount, description), EntityGoals)
+}
+
+func (q *querier) DeleteGoal(ctx context.Context, goalID string, userID string) error {
+	// deleting a goal rolls its contributions back into the accounts
+	return q.invalidate(ctx, userID, q.Querier.DeleteGoal(ctx, goalID, userID), EntityAccounts, EntityGoals)
+}
+
+func (q *querier) CreateCategory(ctx context.Context, name string, description string, userID string) error {
+	return q.invalidate(ctx, userID, q.Querier.CreateCategory(ctx, name, description, userID), EntityCategories)
+}
+
+func (q *querier) DeleteCategory(ctx context.Context, categoryID string, userID string) error {
+	return q.invalidate(ctx, userID, q.Querier.DeleteCategory(ctx, categoryID, userID), EntityCategories)
+}
+
+func (q *querier) UpdateCategory(ctx context.Context, categoryID string, name string, description string) error {
+	if err := q.Querier.UpdateCategory(ctx, categoryID, name, description); err != nil {
+		return err
+	}
+
+	// the owner is only known if the category list was cached, otherwise there is nothing to invalidate
+	userID, err := q.cache.store.GetString(ctx, q.cache.categoryOwnerKey(categoryID))
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			q.cache.cfg.Logger.WarnContext(ctx, "cache: failed to read owner of category",
+				slog.String("category_id", categoryID),
+				slog.Any("error", err),
+			)
+		}
+		return nil
+	}
+	return q.invalidate(ctx, userID, nil, EntityCategories)
+}
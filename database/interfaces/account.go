@@ -3,10 +3,12 @@ package interfaces
 import (
 	"context"
 	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 type IAccountRepository interface {
-	CreateAccount(context.Context, string, string, float32) error
+	// CreateAccount creates an account in the given currency, or in the base currency of the user when it is empty
+	CreateAccount(ctx context.Context, userID, name string, initialBalance money.Amount, currency string) error
 	UpdateAccount(context.Context, string, string, string) error
 	GetUserAccounts(context.Context, string) ([]*gen.Accountpayload, error)
 	DeleteAccount(context.Context, string, string) error
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// CreateBeneficiaryParams holds the fields of a new beneficiary
+type CreateBeneficiaryParams struct {
+	UserID        string
+	Name          string
+	AccountNumber string
+	Description   string
+}
+
+// UpdateBeneficiaryParams holds the editable fields of a beneficiary
+type UpdateBeneficiaryParams struct {
+	BeneficiaryID string
+	UserID        string
+	Name          string
+	AccountNumber string
+	Description   string
+}
+
+type IBeneficiaryRepository interface {
+	CreateBeneficiary(context.Context, CreateBeneficiaryParams) error
+	UpdateBeneficiary(context.Context, UpdateBeneficiaryParams) error
+	DeleteBeneficiary(ctx context.Context, beneficiaryID, userID string) error
+	GetBeneficiary(ctx context.Context, beneficiaryID, userID string) (*gen.Beneficiarypayload, error)
+	GetUserBeneficiaries(ctx context.Context, userID string, page Page) ([]*gen.Beneficiarypayload, error)
+}
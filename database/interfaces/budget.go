@@ -0,0 +1,66 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"time"
+)
+
+// Budget periods
+const (
+	BudgetPeriodWeekly  = "WEEKLY"
+	BudgetPeriodMonthly = "MONTHLY"
+	BudgetPeriodCustom  = "CUSTOM"
+)
+
+// CreateBudgetParams holds the fields of a new budget
+type CreateBudgetParams struct {
+	UserID     string
+	CategoryID string
+
+	// Amount is the spending limit of one period
+	Amount money.Amount
+
+	// Period is one of the `BudgetPeriod*` constants
+	Period string
+
+	// PeriodDays is the length of `BudgetPeriodCustom` periods
+	PeriodDays int32
+
+	// StartDate is the first day of the first period (defaults to the first day of the current month)
+	StartDate time.Time
+
+	// Rollover carries the unused amount of a period over to the next one
+	Rollover bool
+
+	// Currency is the ISO 4217 code of the amount (defaults to the base currency of the user),
+	// spending in other currencies is converted into it
+	Currency string
+}
+
+// UpdateBudgetParams holds the editable fields of a budget
+type UpdateBudgetParams struct {
+	BudgetID   string
+	UserID     string
+	Amount     money.Amount
+	Period     string
+	PeriodDays int32
+
+	// StartDate is the first day of the first period (the zero time keeps the current one)
+	StartDate time.Time
+	Rollover  bool
+}
+
+type IBudgetRepository interface {
+	// CreateBudget creates the budget and returns its id
+	CreateBudget(context.Context, CreateBudgetParams) (string, error)
+	UpdateBudget(context.Context, UpdateBudgetParams) error
+	DeleteBudget(ctx context.Context, budgetID, userID string) error
+
+	// GetBudget returns the budget with the spending of the period that contains `at` (the zero time is now)
+	GetBudget(ctx context.Context, budgetID, userID string, at time.Time) (*gen.Budgetpayload, error)
+
+	// GetUserBudgets returns every budget of the user with the spending of the period that contains `at` (the zero time is now)
+	GetUserBudgets(ctx context.Context, userID string, at time.Time) ([]*gen.Budgetpayload, error)
+}
@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// CreateCategoryParams holds the fields of a new category
+type CreateCategoryParams struct {
+	UserID      string
+	Name        string
+	Description string
+}
+
+// UpdateCategoryParams holds the editable fields of a category
+type UpdateCategoryParams struct {
+	CategoryID  string
+	Name        string
+	Description string
+}
+
+type ICategoryRepository interface {
+	CreateCategory(context.Context, CreateCategoryParams) error
+	UpdateCategory(context.Context, UpdateCategoryParams) error
+	DeleteCategory(ctx context.Context, categoryID, userID string) error
+	GetUserCategories(ctx context.Context, userID string) ([]*gen.Categorypayload, error)
+}
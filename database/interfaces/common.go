@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"time"
+)
+
+// Page selects one page of a paginated list. Pages start at 1.
+type Page struct {
+	Number int32
+	Size   int32
+}
+
+// Period restricts a list to a time range.
+// A zero `From` or `To` falls back to the database default (the last 91 days).
+type Period struct {
+	From time.Time
+	To   time.Time
+}
@@ -0,0 +1,34 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"time"
+)
+
+// SaveFxRateParams holds an exchange rate quoted by a rate provider
+type SaveFxRateParams struct {
+	// BaseCurrency and QuoteCurrency are ISO 4217 codes, one unit of the base currency buys `Rate` of the quote currency
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          money.Rate
+
+	// Source names the provider of the rate (defaults to "manual")
+	Source string
+
+	// AsOf is when the rate was quoted (the zero time is now)
+	AsOf time.Time
+}
+
+type IFxRateRepository interface {
+	// SaveRate stores the rate, replacing a rate of the same pair quoted at the same time
+	SaveRate(context.Context, SaveFxRateParams) (*gen.Fxratepayload, error)
+
+	// GetRate returns the rate from one currency to another as of `at` (the zero time is now).
+	// Rates stored for the opposite direction are inverted.
+	GetRate(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*gen.Fxratepayload, error)
+
+	// GetRates returns the latest rate of every stored pair
+	GetRates(context.Context) ([]*gen.Fxratepayload, error)
+}
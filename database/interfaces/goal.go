@@ -0,0 +1,42 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+)
+
+// CreateGoalParams holds the fields of a new goal
+type CreateGoalParams struct {
+	UserID      string
+	Name        string
+	Target      money.Amount
+	Description string
+}
+
+// UpdateGoalParams holds the editable fields of a goal
+type UpdateGoalParams struct {
+	GoalID      string
+	UserID      string
+	Name        string
+	Target      money.Amount
+	Description string
+}
+
+// ContributeToGoalParams holds the fields of a contribution from an account towards a goal
+type ContributeToGoalParams struct {
+	UserID        string
+	GoalID        string
+	AccountNumber string
+	Amount        money.Amount
+	Description   string
+}
+
+type IGoalRepository interface {
+	// CreateGoal creates the goal and returns its id
+	CreateGoal(context.Context, CreateGoalParams) (string, error)
+	UpdateGoal(context.Context, UpdateGoalParams) error
+	DeleteGoal(ctx context.Context, goalID, userID string) error
+	GetUserGoals(ctx context.Context, userID string, page Page) ([]*gen.Goalpayload, error)
+	ContributeToGoal(context.Context, ContributeToGoalParams) error
+}
@@ -0,0 +1,99 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"time"
+)
+
+// Frequency is the unit a recurring schedule repeats in
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// LastDayOfMonth is the `DayOfMonth` of monthly schedules that run on the last day of the month
+const LastDayOfMonth = -1
+
+// Schedule is an RRULE-style recurrence (FREQ, INTERVAL, BYDAY and BYMONTHDAY) counted from the start date
+type Schedule struct {
+	Frequency Frequency
+
+	// RepeatEvery runs the schedule every n days, weeks or months (defaults to 1)
+	RepeatEvery int32
+
+	// DayOfWeek is the ISO day (1 = monday, 7 = sunday) of weekly schedules, 0 is the day of the start date
+	DayOfWeek int32
+
+	// DayOfMonth is the day of monthly schedules, clamped to the length of the month.
+	// `LastDayOfMonth` runs on the last day, 0 is the day of the start date.
+	DayOfMonth int32
+
+	// BusinessDays moves occurrences that fall on a weekend to the friday before
+	BusinessDays bool
+}
+
+// EveryDay repeats every day
+func EveryDay() Schedule {
+	return Schedule{Frequency: Daily, RepeatEvery: 1}
+}
+
+// EveryWeek repeats every week on the given day
+func EveryWeek(day time.Weekday) Schedule {
+	iso := int32(day)
+	if day == time.Sunday {
+		iso = 7
+	}
+	return Schedule{Frequency: Weekly, RepeatEvery: 1, DayOfWeek: iso}
+}
+
+// EveryMonthOn repeats every month on the given day (e.g. 1 for rent, `LastDayOfMonth` for the end of the month)
+func EveryMonthOn(day int32) Schedule {
+	return Schedule{Frequency: Monthly, RepeatEvery: 1, DayOfMonth: day}
+}
+
+// LastBusinessDay repeats every month on its last business day (e.g. salary)
+func LastBusinessDay() Schedule {
+	return Schedule{Frequency: Monthly, RepeatEvery: 1, DayOfMonth: LastDayOfMonth, BusinessDays: true}
+}
+
+// RecurringTransactionParams holds the fields of a recurring transaction
+type RecurringTransactionParams struct {
+	UserID        string
+	AccountNumber string
+	CategoryID    string
+	Type          TransactionType
+	Amount        money.Amount
+	Description   string
+	Schedule      Schedule
+
+	// StartDate is the first day of the schedule (defaults to today, an update keeps the current one)
+	StartDate time.Time
+
+	// EndDate is the last day of the schedule, the zero time repeats forever
+	EndDate time.Time
+}
+
+type IRecurringTransactionRepository interface {
+	// CreateRecurringTransaction creates the recurring transaction and returns its id
+	CreateRecurringTransaction(context.Context, RecurringTransactionParams) (string, error)
+
+	// UpdateRecurringTransaction changes the occurrences from today on, the materialized ones are left untouched
+	UpdateRecurringTransaction(ctx context.Context, recurringID string, params RecurringTransactionParams) error
+	DeleteRecurringTransaction(ctx context.Context, recurringID, userID string) error
+
+	// PauseRecurringTransaction stops materializing occurrences until the recurring transaction is resumed
+	PauseRecurringTransaction(ctx context.Context, recurringID, userID string) error
+
+	// ResumeRecurringTransaction continues with the next occurrence from today on, the ones missed while paused are not caught up on
+	ResumeRecurringTransaction(ctx context.Context, recurringID, userID string) error
+
+	// SkipOccurrence skips the occurrence on the given date, the zero time skips the next occurrence
+	SkipOccurrence(ctx context.Context, recurringID, userID string, date time.Time) error
+	GetUserRecurringTransactions(ctx context.Context, userID string) ([]*gen.Recurringtransactionpayload, error)
+	GetOccurrences(ctx context.Context, recurringID, userID string, page Page) ([]*gen.Recurringoccurrencepayload, error)
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// RenameTagParams holds the new name of a tag
+type RenameTagParams struct {
+	TagID  string
+	UserID string
+	Name   string
+}
+
+type ITagRepository interface {
+	// AddTags tags a transaction, tags the user has not used before are created
+	AddTags(ctx context.Context, transactionID, userID string, tags []string) error
+	// RemoveTags untags a transaction, the tags themselves are kept
+	RemoveTags(ctx context.Context, transactionID, userID string, tags []string) error
+	RenameTag(context.Context, RenameTagParams) (*gen.Tagpayload, error)
+	// DeleteTag deletes a tag and removes it from every transaction
+	DeleteTag(ctx context.Context, tagID, userID string) error
+	GetUserTags(ctx context.Context, userID string) ([]*gen.Tagpayload, error)
+}
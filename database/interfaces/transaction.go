@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
+)
+
+// TransactionType is the direction of a transaction
+type TransactionType string
+
+const (
+	Credit TransactionType = "CREDIT"
+	Debit  TransactionType = "DEBIT"
+)
+
+// TransactionEntryParams holds the fields of a deposit or withdrawal
+type TransactionEntryParams struct {
+	UserID        string
+	AccountNumber string
+	CategoryID    string
+	Amount        money.Amount
+	Description   string
+}
+
+// TransferParams holds the fields of a transfer between two accounts
+type TransferParams struct {
+	UserID            string
+	FromAccountNumber string
+	ToAccountNumber   string
+	Amount            money.Amount
+	Description       string
+}
+
+// UpdateTransactionParams holds the editable fields of a transaction
+type UpdateTransactionParams struct {
+	TransactionID string
+	UserID        string
+	AccountNumber string
+	CategoryID    string
+	Type          TransactionType
+	Amount        money.Amount
+	Description   string
+
+	// Splits replaces the lines of the transaction in the same call: nil keeps them, an empty slice removes the split.
+	// The lines must add up to `Amount`.
+	Splits []SplitLine
+}
+
+// SplitLine is the part of a split transaction that belongs to one category
+type SplitLine struct {
+	CategoryID string
+	Amount     money.Amount
+	Memo       string
+}
+
+// SplitTransactionParams holds the lines a transaction is split into
+type SplitTransactionParams struct {
+	TransactionID string
+	UserID        string
+	Lines         []SplitLine
+}
+
+// TransactionFilter selects the transactions of a user.
+// At most one of `AccountNumber`, `CategoryID`, `GoalID` and `Type` may be set, `Tags` combines with any of them.
+type TransactionFilter struct {
+	UserID        string
+	AccountNumber string
+	CategoryID    string
+	GoalID        string
+	Type          TransactionType
+	Period        Period
+	Page          Page
+
+	// Tags keeps the transactions with any of the tags, or all of them when `MatchAllTags` is set (case-insensitive)
+	Tags         []string
+	MatchAllTags bool
+}
+
+type ITransactionRepository interface {
+	Deposit(context.Context, TransactionEntryParams) error
+	Withdraw(context.Context, TransactionEntryParams) error
+	// Transfer moves money between two accounts, converting it when their currencies differ
+	Transfer(context.Context, TransferParams) (*gen.Transferpayload, error)
+	UpdateTransaction(context.Context, UpdateTransactionParams) error
+	DeleteTransaction(ctx context.Context, transactionID, userID string) error
+	GetTransaction(ctx context.Context, transactionID, userID string) (*gen.Transactionpayload, error)
+	GetUserTransactions(context.Context, TransactionFilter) ([]*gen.Transactionpayload, error)
+	// SplitTransaction replaces the lines of a transaction, the lines must add up to its amount
+	SplitTransaction(context.Context, SplitTransactionParams) ([]*gen.Transactionsplitpayload, error)
+	// DeleteTransactionSplit removes the lines of a transaction, so it is reported under its own category again
+	DeleteTransactionSplit(ctx context.Context, transactionID, userID string) error
+	GetTransactionSplits(ctx context.Context, transactionID, userID string) ([]*gen.Transactionsplitpayload, error)
+}
@@ -0,0 +1,48 @@
+package interfaces
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// CreateUserParams holds the fields of a new user
+type CreateUserParams struct {
+	Email       string
+	AuthID      string
+	PhoneNumber string
+	Password    string
+	Name        string
+	AvatarURL   string
+}
+
+// LoginUserParams holds the identity provider details of a user signing in.
+// The user is created on their first login.
+type LoginUserParams struct {
+	AuthID      string
+	Email       string
+	Name        string
+	PhoneNumber string
+	AvatarURL   string
+}
+
+// UpdateUserParams holds the editable fields of a user
+type UpdateUserParams struct {
+	UserID      string
+	Name        string
+	PhoneNumber string
+	AvatarURL   string
+}
+
+type IUserRepository interface {
+	CreateUser(context.Context, CreateUserParams) (*gen.Userpayload, error)
+	LoginUser(context.Context, LoginUserParams) (*gen.Userpayload, error)
+	LoginWithPassword(ctx context.Context, userID, password string) (*gen.Userpayload, error)
+	GetUserByID(ctx context.Context, userID string) (*gen.Userpayload, error)
+	GetUserByEmail(ctx context.Context, email string) (*gen.Userpayload, error)
+	GetUsers(context.Context) ([]*gen.Userpayload, error)
+	GetUserStats(ctx context.Context, email string) (*gen.Userstats, error)
+	UpdateUser(context.Context, UpdateUserParams) (*gen.Userpayload, error)
+	CreatePassword(ctx context.Context, userID, password string) error
+	RevokePassword(ctx context.Context, userID string) error
+	UpdateBaseCurrency(ctx context.Context, userID, currency string) error
+}
@@ -5,42 +5,45 @@ import (
 	"github.com/qwallet-expense-tracker/shared/database"
 	"github.com/qwallet-expense-tracker/shared/database/interfaces"
 	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 // AccountRepository implements the `IAccountRepository` interface
 type accountRepository struct {
-	interfaces.IAccountRepository
+	db database.Executor
 }
 
-// NewAccountRepository creates a new instance of the `accountRepository`
-func NewAccountRepository() interfaces.IAccountRepository {
-	return &accountRepository{}
-}
+// ensure every method of the `IAccountRepository` interface is implemented
+var _ interfaces.IAccountRepository = (*accountRepository)(nil)
 
-func (r *accountRepository) CreateAccount(ctx context.Context, userID string, accountName string, initialBalance float32) error {
-	conn := database.GetConn()
-	defer conn.Release()
+// NewAccountRepository creates a new instance of the `accountRepository`.
+// Pass the `*database.DB`, or `database.Bind(q)` inside `InTx` to take part in the transaction.
+func NewAccountRepository(db database.Executor) interfaces.IAccountRepository {
+	return &accountRepository{db: db}
+}
 
-	return gen.New(conn).CreateAccount(ctx, userID, accountName, initialBalance)
+func (r *accountRepository) CreateAccount(ctx context.Context, userID string, accountName string, initialBalance money.Amount, currency string) error {
+	return wrap("create account", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.CreateAccount(ctx, userID, accountName, initialBalance, currency)
+	}))
 }
 
 func (r *accountRepository) UpdateAccount(ctx context.Context, accountNumber, userID, accountName string) error {
-	conn := database.GetConn()
-	defer conn.Release()
-
-	return gen.New(conn).UpdateAccount(ctx, accountNumber, userID, accountName)
+	return wrap("update account", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateAccount(ctx, accountNumber, userID, accountName)
+	}))
 }
 
-func (r *accountRepository) GetUserAccounts(ctx context.Context, userID string) ([]*gen.Accountpayload, error) {
-	conn := database.GetConn()
-	defer conn.Release()
-
-	return gen.New(conn).GetAccounts(ctx, userID)
+func (r *accountRepository) GetUserAccounts(ctx context.Context, userID string) (accounts []*gen.Accountpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		accounts, err = q.GetAccounts(ctx, userID)
+		return err
+	})
+	return accounts, wrap("get user accounts", err)
 }
 
 func (r *accountRepository) DeleteAccount(ctx context.Context, accountNumber, userID string) error {
-	conn := database.GetConn()
-	defer conn.Release()
-
-	return gen.New(conn).DeleteAccount(ctx, accountNumber, userID)
+	return wrap("delete account", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteAccount(ctx, accountNumber, userID)
+	}))
 }
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// beneficiaryRepository implements the `IBeneficiaryRepository` interface
+type beneficiaryRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IBeneficiaryRepository` interface is implemented
+var _ interfaces.IBeneficiaryRepository = (*beneficiaryRepository)(nil)
+
+// NewBeneficiaryRepository creates a new instance of the `beneficiaryRepository`
+func NewBeneficiaryRepository(db database.Executor) interfaces.IBeneficiaryRepository {
+	return &beneficiaryRepository{db: db}
+}
+
+func (r *beneficiaryRepository) CreateBeneficiary(ctx context.Context, params interfaces.CreateBeneficiaryParams) error {
+	return wrap("create beneficiary", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.CreateBeneficiary(ctx, params.UserID, params.Name, params.AccountNumber, params.Description)
+	}))
+}
+
+func (r *beneficiaryRepository) UpdateBeneficiary(ctx context.Context, params interfaces.UpdateBeneficiaryParams) error {
+	return wrap("update beneficiary", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateBeneficiary(ctx, params.BeneficiaryID, params.UserID, params.Name, params.AccountNumber, params.Description)
+	}))
+}
+
+func (r *beneficiaryRepository) DeleteBeneficiary(ctx context.Context, beneficiaryID, userID string) error {
+	return wrap("delete beneficiary", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteBeneficiary(ctx, beneficiaryID, userID)
+	}))
+}
+
+func (r *beneficiaryRepository) GetBeneficiary(ctx context.Context, beneficiaryID, userID string) (beneficiary *gen.Beneficiarypayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		beneficiary, err = q.GetBeneficiary(ctx, beneficiaryID, userID)
+		return err
+	})
+	return beneficiary, wrap("get beneficiary", err)
+}
+
+func (r *beneficiaryRepository) GetUserBeneficiaries(ctx context.Context, userID string, page interfaces.Page) (beneficiaries []*gen.Beneficiarypayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		beneficiaries, err = q.GetBeneficiaries(ctx, userID, page.Number, page.Size)
+		return err
+	})
+	return beneficiaries, wrap("get user beneficiaries", err)
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"time"
+)
+
+// budgetRepository implements the `IBudgetRepository` interface
+type budgetRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IBudgetRepository` interface is implemented
+var _ interfaces.IBudgetRepository = (*budgetRepository)(nil)
+
+// NewBudgetRepository creates a new instance of the `budgetRepository`
+func NewBudgetRepository(db database.Executor) interfaces.IBudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+func (r *budgetRepository) CreateBudget(ctx context.Context, params interfaces.CreateBudgetParams) (budgetID string, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		budgetID, err = q.CreateBudget(ctx, params.UserID, params.CategoryID, params.Amount, params.Period, params.PeriodDays,
+			timestamp(params.StartDate), params.Rollover, params.Currency)
+		return err
+	})
+	return budgetID, wrap("create budget", err)
+}
+
+func (r *budgetRepository) UpdateBudget(ctx context.Context, params interfaces.UpdateBudgetParams) error {
+	return wrap("update budget", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateBudget(ctx, params.BudgetID, params.UserID, params.Amount, params.Period, params.PeriodDays,
+			timestamp(params.StartDate), params.Rollover)
+	}))
+}
+
+func (r *budgetRepository) DeleteBudget(ctx context.Context, budgetID, userID string) error {
+	return wrap("delete budget", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteBudget(ctx, budgetID, userID)
+	}))
+}
+
+func (r *budgetRepository) GetBudget(ctx context.Context, budgetID, userID string, at time.Time) (budget *gen.Budgetpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		budget, err = q.GetBudget(ctx, budgetID, userID, now(at))
+		return err
+	})
+	return budget, wrap("get budget", err)
+}
+
+func (r *budgetRepository) GetUserBudgets(ctx context.Context, userID string, at time.Time) (budgets []*gen.Budgetpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		budgets, err = q.ListUserBudgets(ctx, userID, now(at))
+		return err
+	})
+	return budgets, wrap("get user budgets", err)
+}
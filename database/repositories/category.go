@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// categoryRepository implements the `ICategoryRepository` interface
+type categoryRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `ICategoryRepository` interface is implemented
+var _ interfaces.ICategoryRepository = (*categoryRepository)(nil)
+
+// NewCategoryRepository creates a new instance of the `categoryRepository`
+func NewCategoryRepository(db database.Executor) interfaces.ICategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) CreateCategory(ctx context.Context, params interfaces.CreateCategoryParams) error {
+	return wrap("create category", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.CreateCategory(ctx, params.Name, params.Description, params.UserID)
+	}))
+}
+
+func (r *categoryRepository) UpdateCategory(ctx context.Context, params interfaces.UpdateCategoryParams) error {
+	return wrap("update category", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateCategory(ctx, params.CategoryID, params.Name, params.Description)
+	}))
+}
+
+func (r *categoryRepository) DeleteCategory(ctx context.Context, categoryID, userID string) error {
+	return wrap("delete category", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteCategory(ctx, categoryID, userID)
+	}))
+}
+
+func (r *categoryRepository) GetUserCategories(ctx context.Context, userID string) (categories []*gen.Categorypayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		categories, err = q.GetCategoriesForUser(ctx, userID)
+		return err
+	})
+	return categories, wrap("get user categories", err)
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// timestamp converts an optional time to a query parameter, the zero time becomes NULL
+func timestamp(t time.Time) pgtype.Timestamp {
+	if t.IsZero() {
+		return pgtype.Timestamp{}
+	}
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}
+}
+
+// now defaults the zero time to the current time
+func now(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"fmt"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+)
+
+// wrap adds the failed repository operation to an error (e.g. "create goal: ...").
+// Every repository method returns its errors through wrap so they read the same everywhere,
+// and database errors are translated into domain errors so callers can use `errors.Is`.
+func wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", op, qerrors.FromDB(err))
+}
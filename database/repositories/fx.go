@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"time"
+)
+
+// fxRateRepository implements the `IFxRateRepository` interface
+type fxRateRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IFxRateRepository` interface is implemented
+var _ interfaces.IFxRateRepository = (*fxRateRepository)(nil)
+
+// NewFxRateRepository creates a new instance of the `fxRateRepository`
+func NewFxRateRepository(db database.Executor) interfaces.IFxRateRepository {
+	return &fxRateRepository{db: db}
+}
+
+func (r *fxRateRepository) SaveRate(ctx context.Context, params interfaces.SaveFxRateParams) (rate *gen.Fxratepayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		rate, err = q.SaveFxRate(ctx, params.BaseCurrency, params.QuoteCurrency, params.Rate, params.Source, now(params.AsOf))
+		return err
+	})
+	return rate, wrap("save fx rate", err)
+}
+
+func (r *fxRateRepository) GetRate(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (rate *gen.Fxratepayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		rate, err = q.GetFxRate(ctx, fromCurrency, toCurrency, now(at))
+		return err
+	})
+	return rate, wrap("get fx rate", err)
+}
+
+func (r *fxRateRepository) GetRates(ctx context.Context) (rates []*gen.Fxratepayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		rates, err = q.ListFxRates(ctx)
+		return err
+	})
+	return rates, wrap("get fx rates", err)
+}
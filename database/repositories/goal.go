@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// goalRepository implements the `IGoalRepository` interface
+type goalRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IGoalRepository` interface is implemented
+var _ interfaces.IGoalRepository = (*goalRepository)(nil)
+
+// NewGoalRepository creates a new instance of the `goalRepository`
+func NewGoalRepository(db database.Executor) interfaces.IGoalRepository {
+	return &goalRepository{db: db}
+}
+
+func (r *goalRepository) CreateGoal(ctx context.Context, params interfaces.CreateGoalParams) (goalID string, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		goalID, err = q.CreateGoal(ctx, params.UserID, params.Name, params.Target, params.Description)
+		return err
+	})
+	return goalID, wrap("create goal", err)
+}
+
+func (r *goalRepository) UpdateGoal(ctx context.Context, params interfaces.UpdateGoalParams) error {
+	return wrap("update goal", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateGoal(ctx, params.GoalID, params.UserID, params.Name, params.Target, params.Description)
+	}))
+}
+
+func (r *goalRepository) DeleteGoal(ctx context.Context, goalID, userID string) error {
+	return wrap("delete goal", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteGoal(ctx, goalID, userID)
+	}))
+}
+
+func (r *goalRepository) GetUserGoals(ctx context.Context, userID string, page interfaces.Page) (goals []*gen.Goalpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		goals, err = q.ListUserGoals(ctx, userID, page.Number, page.Size)
+		return err
+	})
+	return goals, wrap("get user goals", err)
+}
+
+func (r *goalRepository) ContributeToGoal(ctx context.Context, params interfaces.ContributeToGoalParams) error {
+	return wrap("contribute to goal", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.ContributeToGoal(ctx, params.UserID, params.GoalID, params.Amount, params.Description, params.AccountNumber)
+	}))
+}
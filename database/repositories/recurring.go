@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"time"
+)
+
+// recurringTransactionRepository implements the `IRecurringTransactionRepository` interface
+type recurringTransactionRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IRecurringTransactionRepository` interface is implemented
+var _ interfaces.IRecurringTransactionRepository = (*recurringTransactionRepository)(nil)
+
+// NewRecurringTransactionRepository creates a new instance of the `recurringTransactionRepository`
+func NewRecurringTransactionRepository(db database.Executor) interfaces.IRecurringTransactionRepository {
+	return &recurringTransactionRepository{db: db}
+}
+
+func (r *recurringTransactionRepository) CreateRecurringTransaction(ctx context.Context, params interfaces.RecurringTransactionParams) (recurringID string, err error) {
+	s := withDefaults(params.Schedule)
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		recurringID, err = q.CreateRecurringTransaction(ctx, params.UserID, params.AccountNumber, params.CategoryID, string(params.Type),
+			params.Amount, params.Description, string(s.Frequency), s.RepeatEvery, s.DayOfWeek, s.DayOfMonth, s.BusinessDays,
+			timestamp(params.StartDate), timestamp(params.EndDate))
+		return err
+	})
+	return recurringID, wrap("create recurring transaction", err)
+}
+
+func (r *recurringTransactionRepository) UpdateRecurringTransaction(ctx context.Context, recurringID string, params interfaces.RecurringTransactionParams) error {
+	s := withDefaults(params.Schedule)
+	return wrap("update recurring transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateRecurringTransaction(ctx, recurringID, params.UserID, params.AccountNumber, params.CategoryID, string(params.Type),
+			params.Amount, params.Description, string(s.Frequency), s.RepeatEvery, s.DayOfWeek, s.DayOfMonth, s.BusinessDays,
+			timestamp(params.StartDate), timestamp(params.EndDate))
+	}))
+}
+
+func (r *recurringTransactionRepository) DeleteRecurringTransaction(ctx context.Context, recurringID, userID string) error {
+	return wrap("delete recurring transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteRecurringTransaction(ctx, recurringID, userID)
+	}))
+}
+
+func (r *recurringTransactionRepository) PauseRecurringTransaction(ctx context.Context, recurringID, userID string) error {
+	return wrap("pause recurring transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.PauseRecurringTransaction(ctx, recurringID, userID)
+	}))
+}
+
+func (r *recurringTransactionRepository) ResumeRecurringTransaction(ctx context.Context, recurringID, userID string) error {
+	return wrap("resume recurring transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.ResumeRecurringTransaction(ctx, recurringID, userID)
+	}))
+}
+
+func (r *recurringTransactionRepository) SkipOccurrence(ctx context.Context, recurringID, userID string, date time.Time) error {
+	return wrap("skip occurrence", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.SkipRecurringOccurrence(ctx, recurringID, userID, timestamp(date))
+	}))
+}
+
+func (r *recurringTransactionRepository) GetUserRecurringTransactions(ctx context.Context, userID string) (items []*gen.Recurringtransactionpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		items, err = q.ListUserRecurringTransactions(ctx, userID)
+		return err
+	})
+	return items, wrap("get user recurring transactions", err)
+}
+
+func (r *recurringTransactionRepository) GetOccurrences(ctx context.Context, recurringID, userID string, page interfaces.Page) (items []*gen.Recurringoccurrencepayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		items, err = q.ListRecurringOccurrences(ctx, recurringID, userID, page.Number, page.Size)
+		return err
+	})
+	return items, wrap("get occurrences", err)
+}
+
+// withDefaults repeats a schedule every period unless it says otherwise
+func withDefaults(s interfaces.Schedule) interfaces.Schedule {
+	if s.RepeatEvery == 0 {
+		s.RepeatEvery = 1
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+)
+
+// tagRepository implements the `ITagRepository` interface
+type tagRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `ITagRepository` interface is implemented
+var _ interfaces.ITagRepository = (*tagRepository)(nil)
+
+// NewTagRepository creates a new instance of the `tagRepository`
+func NewTagRepository(db database.Executor) interfaces.ITagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) AddTags(ctx context.Context, transactionID, userID string, tags []string) error {
+	if len(tags) == 0 {
+		return wrap("add tags", fmt.Errorf("%w: at least one tag is required", qerrors.ErrMissingField))
+	}
+	return wrap("add tags", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.TagTransaction(ctx, transactionID, userID, tags)
+	}))
+}
+
+func (r *tagRepository) RemoveTags(ctx context.Context, transactionID, userID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return wrap("remove tags", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UntagTransaction(ctx, transactionID, userID, tags)
+	}))
+}
+
+func (r *tagRepository) RenameTag(ctx context.Context, params interfaces.RenameTagParams) (tag *gen.Tagpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		tag, err = q.RenameTag(ctx, params.TagID, params.UserID, params.Name)
+		return err
+	})
+	return tag, wrap("rename tag", err)
+}
+
+func (r *tagRepository) DeleteTag(ctx context.Context, tagID, userID string) error {
+	return wrap("delete tag", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteTag(ctx, tagID, userID)
+	}))
+}
+
+func (r *tagRepository) GetUserTags(ctx context.Context, userID string) (tags []*gen.Tagpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		tags, err = q.GetTagsForUser(ctx, userID)
+		return err
+	})
+	return tags, wrap("get user tags", err)
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+	"github.com/qwallet-expense-tracker/shared/money"
+)
+
+// transactionRepository implements the `ITransactionRepository` interface
+type transactionRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `ITransactionRepository` interface is implemented
+var _ interfaces.ITransactionRepository = (*transactionRepository)(nil)
+
+// NewTransactionRepository creates a new instance of the `transactionRepository`
+func NewTransactionRepository(db database.Executor) interfaces.ITransactionRepository {
+	return &transactionRepository{db: db}
+}
+
+func (r *transactionRepository) Deposit(ctx context.Context, params interfaces.TransactionEntryParams) error {
+	return wrap("deposit", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.Deposit(ctx, params.UserID, params.AccountNumber, params.CategoryID, params.Amount, params.Description)
+	}))
+}
+
+func (r *transactionRepository) Withdraw(ctx context.Context, params interfaces.TransactionEntryParams) error {
+	return wrap("withdraw", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.Withdraw(ctx, params.UserID, params.AccountNumber, params.CategoryID, params.Amount, params.Description)
+	}))
+}
+
+func (r *transactionRepository) Transfer(ctx context.Context, params interfaces.TransferParams) (transfer *gen.Transferpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		transfer, err = q.Transfer(ctx, params.UserID, params.FromAccountNumber, params.ToAccountNumber, params.Amount, params.Description)
+		return err
+	})
+	return transfer, wrap("transfer", err)
+}
+
+func (r *transactionRepository) UpdateTransaction(ctx context.Context, params interfaces.UpdateTransactionParams) error {
+	categoryIDs, amounts, memos := splitLines(params.Splits)
+	return wrap("update transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateTransaction(ctx, params.TransactionID, params.UserID, params.AccountNumber, params.CategoryID, string(params.Type), params.Amount, params.Description, categoryIDs, amounts, memos)
+	}))
+}
+
+func (r *transactionRepository) DeleteTransaction(ctx context.Context, transactionID, userID string) error {
+	return wrap("delete transaction", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteTransaction(ctx, transactionID, userID)
+	}))
+}
+
+func (r *transactionRepository) GetTransaction(ctx context.Context, transactionID, userID string) (transaction *gen.Transactionpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		transaction, err = q.GetTransactionById(ctx, transactionID, userID)
+		return err
+	})
+	return transaction, wrap("get transaction", err)
+}
+
+func (r *transactionRepository) GetUserTransactions(ctx context.Context, filter interfaces.TransactionFilter) (transactions []*gen.Transactionpayload, err error) {
+	set := 0
+	for _, v := range []string{filter.AccountNumber, filter.CategoryID, filter.GoalID, string(filter.Type)} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, wrap("get user transactions", fmt.Errorf("%w: only one of account, category, goal or type can be filtered on", qerrors.ErrInvalidArgument))
+	}
+
+	from, to := timestamp(filter.Period.From), timestamp(filter.Period.To)
+	page := filter.Page
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		switch {
+		case filter.AccountNumber != "":
+			transactions, err = q.GetAccountTransactions(ctx, filter.UserID, filter.AccountNumber, from, to, page.Number, page.Size, filter.Tags, filter.MatchAllTags)
+		case filter.CategoryID != "":
+			transactions, err = q.GetCategoryTransactions(ctx, filter.UserID, filter.CategoryID, from, to, page.Number, page.Size, filter.Tags, filter.MatchAllTags)
+		case filter.GoalID != "":
+			transactions, err = q.GetGoalTransactions(ctx, filter.UserID, filter.GoalID, from, to, page.Number, page.Size, filter.Tags, filter.MatchAllTags)
+		case filter.Type != "":
+			transactions, err = q.GetTransactionsByType(ctx, filter.UserID, string(filter.Type), from, to, page.Number, page.Size, filter.Tags, filter.MatchAllTags)
+		default:
+			transactions, err = q.GetUserTransactions(ctx, filter.UserID, from, to, page.Number, page.Size, filter.Tags, filter.MatchAllTags)
+		}
+		return err
+	})
+	return transactions, wrap("get user transactions", err)
+}
+
+func (r *transactionRepository) SplitTransaction(ctx context.Context, params interfaces.SplitTransactionParams) (lines []*gen.Transactionsplitpayload, err error) {
+	if len(params.Lines) == 0 {
+		return nil, wrap("split transaction", fmt.Errorf("%w: a split needs at least one line", qerrors.ErrMissingField))
+	}
+
+	categoryIDs, amounts, memos := splitLines(params.Lines)
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		lines, err = q.SplitTransaction(ctx, params.TransactionID, params.UserID, categoryIDs, amounts, memos)
+		return err
+	})
+	return lines, wrap("split transaction", err)
+}
+
+func (r *transactionRepository) DeleteTransactionSplit(ctx context.Context, transactionID, userID string) error {
+	return wrap("delete transaction split", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.DeleteTransactionSplit(ctx, transactionID, userID)
+	}))
+}
+
+func (r *transactionRepository) GetTransactionSplits(ctx context.Context, transactionID, userID string) (lines []*gen.Transactionsplitpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		lines, err = q.GetTransactionSplits(ctx, transactionID, userID)
+		return err
+	})
+	return lines, wrap("get transaction splits", err)
+}
+
+// splitLines turns the lines into the columns sent to the database, nil lines stay nil (NULL)
+func splitLines(lines []interfaces.SplitLine) (categoryIDs []string, amounts []money.Amount, memos []string) {
+	if lines == nil {
+		return nil, nil, nil
+	}
+
+	categoryIDs = make([]string, 0, len(lines))
+	amounts = make([]money.Amount, 0, len(lines))
+	memos = make([]string, 0, len(lines))
+	for _, line := range lines {
+		categoryIDs = append(categoryIDs, line.CategoryID)
+		amounts = append(amounts, line.Amount)
+		memos = append(memos, line.Memo)
+	}
+	return categoryIDs, amounts, memos
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// userRepository implements the `IUserRepository` interface
+type userRepository struct {
+	db database.Executor
+}
+
+// ensure every method of the `IUserRepository` interface is implemented
+var _ interfaces.IUserRepository = (*userRepository)(nil)
+
+// NewUserRepository creates a new instance of the `userRepository`
+func NewUserRepository(db database.Executor) interfaces.IUserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) CreateUser(ctx context.Context, params interfaces.CreateUserParams) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.CreateUser(ctx, params.Email, params.AuthID, params.PhoneNumber, params.Password, params.Name, params.AvatarURL)
+		return err
+	})
+	return user, wrap("create user", err)
+}
+
+func (r *userRepository) LoginUser(ctx context.Context, params interfaces.LoginUserParams) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.LoginUser(ctx, params.AuthID, params.Email, params.Name, params.PhoneNumber, params.AvatarURL)
+		return err
+	})
+	return user, wrap("login user", err)
+}
+
+func (r *userRepository) LoginWithPassword(ctx context.Context, userID, password string) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.LoginWithPassword(ctx, userID, password)
+		return err
+	})
+	return user, wrap("login with password", err)
+}
+
+func (r *userRepository) GetUserByID(ctx context.Context, userID string) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.GetUserByID(ctx, userID)
+		return err
+	})
+	return user, wrap("get user by id", err)
+}
+
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.GetUserByEmail(ctx, email)
+		return err
+	})
+	return user, wrap("get user by email", err)
+}
+
+func (r *userRepository) GetUsers(ctx context.Context) (users []*gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		users, err = q.GetUsers(ctx)
+		return err
+	})
+	return users, wrap("get users", err)
+}
+
+func (r *userRepository) GetUserStats(ctx context.Context, email string) (stats *gen.Userstats, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		stats, err = q.GetUserStats(ctx, email)
+		return err
+	})
+	return stats, wrap("get user stats", err)
+}
+
+func (r *userRepository) UpdateUser(ctx context.Context, params interfaces.UpdateUserParams) (user *gen.Userpayload, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		user, err = q.UpdateUser(ctx, params.UserID, params.Name, params.PhoneNumber, params.AvatarURL)
+		return err
+	})
+	return user, wrap("update user", err)
+}
+
+func (r *userRepository) CreatePassword(ctx context.Context, userID, password string) error {
+	return wrap("create password", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.CreatePassword(ctx, userID, password)
+	}))
+}
+
+func (r *userRepository) RevokePassword(ctx context.Context, userID string) error {
+	return wrap("revoke password", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.RevokePassword(ctx, userID)
+	}))
+}
+
+func (r *userRepository) UpdateBaseCurrency(ctx context.Context, userID, currency string) error {
+	return wrap("update base currency", r.db.Execute(ctx, func(q gen.Querier) error {
+		return q.UpdateBaseCurrency(ctx, userID, currency)
+	}))
+}
@@ -7,6 +7,8 @@ package gen
 
 import (
 	"context"
+
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 const createAccount = `-- name: CreateAccount :exec
@@ -14,12 +16,18 @@ select
 from create_new_account(
         $1,
         $2,
-        $3
+        $3,
+        $4
      )
 `
 
-func (q *Queries) CreateAccount(ctx context.Context, userID string, accountName string, initialBalance float32) error {
-	_, err := q.db.Exec(ctx, createAccount, userID, accountName, initialBalance)
+func (q *Queries) CreateAccount(ctx context.Context, userID string, accountName string, initialBalance money.Amount, currency string) error {
+	_, err := q.db.Exec(ctx, createAccount,
+		userID,
+		accountName,
+		initialBalance,
+		currency,
+	)
 	return err
 }
 
@@ -37,7 +45,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, accountNumber string, userI
 }
 
 const getAccounts = `-- name: GetAccounts :many
-select name, balance, account_number, user_id, updated_at, is_deleted
+select name, balance, account_number, user_id, updated_at, is_deleted, currency, base_currency, base_balance
 from list_accounts_for_user($1)
 `
 
@@ -57,6 +65,9 @@ func (q *Queries) GetAccounts(ctx context.Context, userID string) ([]*Accountpay
 			&i.UserID,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.BaseCurrency,
+			&i.BaseBalance,
 		); err != nil {
 			return nil, err
 		}
@@ -7,21 +7,25 @@ package gen
 
 import (
 	"context"
+
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
-const createGoal = `-- name: CreateGoal :exec
+const createGoal = `-- name: CreateGoal :one
 select create_goal($1, $2,
-                   $3, $4)
+                   $3, $4)::varchar as goal_id
 `
 
-func (q *Queries) CreateGoal(ctx context.Context, userID string, name string, targetAmount float32, description string) error {
-	_, err := q.db.Exec(ctx, createGoal,
+func (q *Queries) CreateGoal(ctx context.Context, userID string, name string, targetAmount money.Amount, description string) (string, error) {
+	row := q.db.QueryRow(ctx, createGoal,
 		userID,
 		name,
 		targetAmount,
 		description,
 	)
-	return err
+	var goal_id string
+	err := row.Scan(&goal_id)
+	return goal_id, err
 }
 
 const deleteGoal = `-- name: DeleteGoal :exec
@@ -34,7 +38,7 @@ func (q *Queries) DeleteGoal(ctx context.Context, goalID string, userID string)
 }
 
 const listUserGoals = `-- name: ListUserGoals :many
-select id, name, target, description, balance, user_id, is_deleted
+select id, name, target, description, balance, user_id, is_deleted, currency
 from list_goals_for_user($1, $2, $3)
 `
 
@@ -55,6 +59,7 @@ func (q *Queries) ListUserGoals(ctx context.Context, userID string, pageNumber i
 			&i.Balance,
 			&i.UserID,
 			&i.IsDeleted,
+			&i.Currency,
 		); err != nil {
 			return nil, err
 		}
@@ -72,7 +77,7 @@ select update_goal($1, $2,
                    $5)
 `
 
-func (q *Queries) UpdateGoal(ctx context.Context, goalID string, userID string, name string, targetAmount float32, description string) error {
+func (q *Queries) UpdateGoal(ctx context.Context, goalID string, userID string, name string, targetAmount money.Amount, description string) error {
 	_, err := q.db.Exec(ctx, updateGoal,
 		goalID,
 		userID,
@@ -6,15 +6,21 @@ package gen
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 type Accountpayload struct {
-	Name          string    `json:"name"`
-	Balance       float32   `json:"balance"`
-	AccountNumber string    `json:"account_number"`
-	UserID        string    `json:"user_id"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	IsDeleted     bool      `json:"is_deleted"`
+	Name          string           `json:"name"`
+	Balance       money.Amount     `json:"balance"`
+	AccountNumber string           `json:"account_number"`
+	UserID        string           `json:"user_id"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	IsDeleted     bool             `json:"is_deleted"`
+	Currency      string           `json:"currency"`
+	BaseCurrency  string           `json:"base_currency"`
+	BaseBalance   money.NullAmount `json:"base_balance"`
 }
 
 type Beneficiarypayload struct {
@@ -27,6 +33,38 @@ type Beneficiarypayload struct {
 	IsDeleted     bool      `json:"is_deleted"`
 }
 
+type Budgetalertpayload struct {
+	ID          string       `json:"id"`
+	BudgetID    string       `json:"budget_id"`
+	UserID      string       `json:"user_id"`
+	CategoryID  string       `json:"category_id"`
+	Threshold   int32        `json:"threshold"`
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end"`
+	LimitAmount money.Amount `json:"limit_amount"`
+	Spent       money.Amount `json:"spent"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Currency    string       `json:"currency"`
+}
+
+type Budgetpayload struct {
+	ID             string       `json:"id"`
+	UserID         string       `json:"user_id"`
+	CategoryID     string       `json:"category_id"`
+	Amount         money.Amount `json:"amount"`
+	Period         string       `json:"period"`
+	PeriodDays     int32        `json:"period_days"`
+	StartDate      time.Time    `json:"start_date"`
+	Rollover       bool         `json:"rollover"`
+	PeriodStart    time.Time    `json:"period_start"`
+	PeriodEnd      time.Time    `json:"period_end"`
+	RolloverAmount money.Amount `json:"rollover_amount"`
+	LimitAmount    money.Amount `json:"limit_amount"`
+	Spent          money.Amount `json:"spent"`
+	Remaining      money.Amount `json:"remaining"`
+	Currency       string       `json:"currency"`
+}
+
 type Categorypayload struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
@@ -35,29 +73,143 @@ type Categorypayload struct {
 	IsDeleted   bool   `json:"is_deleted"`
 }
 
+type Fxratepayload struct {
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Rate          money.Rate `json:"rate"`
+	Source        string     `json:"source"`
+	AsOf          time.Time  `json:"as_of"`
+}
+
+type Goalcontributionpayload struct {
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	Target         money.Amount `json:"target"`
+	Description    string       `json:"description"`
+	Balance        money.Amount `json:"balance"`
+	UserID         string       `json:"user_id"`
+	Currency       string       `json:"currency"`
+	Amount         money.Amount `json:"amount"`
+	AmountCurrency string       `json:"amount_currency"`
+	AccountNumber  string       `json:"account_number"`
+	TransactionID  string       `json:"transaction_id"`
+	ContributedAt  time.Time    `json:"contributed_at"`
+}
+
 type Goalpayload struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Target      float32 `json:"target"`
-	Description string  `json:"description"`
-	Balance     float32 `json:"balance"`
-	UserID      string  `json:"user_id"`
-	IsDeleted   bool    `json:"is_deleted"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Target      money.Amount `json:"target"`
+	Description string       `json:"description"`
+	Balance     money.Amount `json:"balance"`
+	UserID      string       `json:"user_id"`
+	IsDeleted   bool         `json:"is_deleted"`
+	Currency    string       `json:"currency"`
+}
+
+type Outboxpayload struct {
+	ID          int64     `json:"id"`
+	Aggregate   string    `json:"aggregate"`
+	AggregateID string    `json:"aggregate_id"`
+	UserID      string    `json:"user_id"`
+	Operation   string    `json:"operation"`
+	Payload     []byte    `json:"payload"`
+	Attempts    int32     `json:"attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Recurringoccurrencepayload struct {
+	ID             string    `json:"id"`
+	RecurringID    string    `json:"recurring_id"`
+	UserID         string    `json:"user_id"`
+	OccurrenceDate time.Time `json:"occurrence_date"`
+	Status         string    `json:"status"`
+	TransactionID  string    `json:"transaction_id"`
+	Error          string    `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Recurringtransactionpayload struct {
+	ID            string             `json:"id"`
+	UserID        string             `json:"user_id"`
+	AccountNumber string             `json:"account_number"`
+	CategoryID    string             `json:"category_id"`
+	Type          string             `json:"type"`
+	Amount        money.Amount       `json:"amount"`
+	Description   string             `json:"description"`
+	Frequency     string             `json:"frequency"`
+	RepeatEvery   int32              `json:"repeat_every"`
+	DayOfWeek     int32              `json:"day_of_week"`
+	DayOfMonth    int32              `json:"day_of_month"`
+	BusinessDays  bool               `json:"business_days"`
+	StartDate     time.Time          `json:"start_date"`
+	EndDate       pgtype.Timestamptz `json:"end_date"`
+	NextRunAt     pgtype.Timestamptz `json:"next_run_at"`
+	Status        string             `json:"status"`
+}
+
+type Tagpayload struct {
+	ID               string    `json:"id"`
+	UserID           string    `json:"user_id"`
+	Name             string    `json:"name"`
+	TransactionCount int64     `json:"transaction_count"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 type Transactionpayload struct {
-	ID              string    `json:"id"`
-	UserID          string    `json:"user_id"`
-	AccountNumber   string    `json:"account_number"`
-	AccountName     string    `json:"account_name"`
-	CategoryID      string    `json:"category_id"`
-	Type            string    `json:"type"`
-	Amount          float32   `json:"amount"`
-	Description     string    `json:"description"`
-	ReferenceNumber string    `json:"reference_number"`
-	Status          string    `json:"status"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	IsDeleted       bool      `json:"is_deleted"`
+	ID              string           `json:"id"`
+	UserID          string           `json:"user_id"`
+	AccountNumber   string           `json:"account_number"`
+	AccountName     string           `json:"account_name"`
+	CategoryID      string           `json:"category_id"`
+	Type            string           `json:"type"`
+	Amount          money.Amount     `json:"amount"`
+	Description     string           `json:"description"`
+	ReferenceNumber string           `json:"reference_number"`
+	Status          string           `json:"status"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	IsDeleted       bool             `json:"is_deleted"`
+	Currency        string           `json:"currency"`
+	FxRate          money.Rate       `json:"fx_rate"`
+	BaseCurrency    string           `json:"base_currency"`
+	BaseAmount      money.NullAmount `json:"base_amount"`
+	Tags            []string         `json:"tags"`
+}
+
+type Transactionsplitpayload struct {
+	ID            string       `json:"id"`
+	TransactionID string       `json:"transaction_id"`
+	UserID        string       `json:"user_id"`
+	CategoryID    string       `json:"category_id"`
+	Amount        money.Amount `json:"amount"`
+	Memo          string       `json:"memo"`
+	Position      int32        `json:"position"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+type Transfereventpayload struct {
+	UserID              string       `json:"user_id"`
+	FromAccountNumber   string       `json:"from_account_number"`
+	ToAccountNumber     string       `json:"to_account_number"`
+	Description         string       `json:"description"`
+	DebitTransactionID  string       `json:"debit_transaction_id"`
+	CreditTransactionID string       `json:"credit_transaction_id"`
+	FromCurrency        string       `json:"from_currency"`
+	ToCurrency          string       `json:"to_currency"`
+	FxRate              money.Rate   `json:"fx_rate"`
+	DebitedAmount       money.Amount `json:"debited_amount"`
+	CreditedAmount      money.Amount `json:"credited_amount"`
+	CompletedAt         time.Time    `json:"completed_at"`
+}
+
+type Transferpayload struct {
+	DebitTransactionID  string       `json:"debit_transaction_id"`
+	CreditTransactionID string       `json:"credit_transaction_id"`
+	FromCurrency        string       `json:"from_currency"`
+	ToCurrency          string       `json:"to_currency"`
+	FxRate              money.Rate   `json:"fx_rate"`
+	DebitedAmount       money.Amount `json:"debited_amount"`
+	CreditedAmount      money.Amount `json:"credited_amount"`
 }
 
 type Userpayload struct {
@@ -70,14 +222,15 @@ type Userpayload struct {
 }
 
 type Userstats struct {
-	TotalAccounts     int64   `json:"total_accounts"`
-	TotalTransactions int64   `json:"total_transactions"`
-	TotalCategories   int64   `json:"total_categories"`
-	TotalGoals        int64   `json:"total_goals"`
-	AccountBalance    float32 `json:"account_balance"`
-	AccountNumber     string  `json:"account_number"`
-	TotalIncome       float32 `json:"total_income"`
-	TotalExpense      float32 `json:"total_expense"`
+	TotalAccounts     int64        `json:"total_accounts"`
+	TotalTransactions int64        `json:"total_transactions"`
+	TotalCategories   int64        `json:"total_categories"`
+	TotalGoals        int64        `json:"total_goals"`
+	AccountBalance    money.Amount `json:"account_balance"`
+	AccountNumber     string       `json:"account_number"`
+	TotalIncome       money.Amount `json:"total_income"`
+	TotalExpense      money.Amount `json:"total_expense"`
+	BaseCurrency      string       `json:"base_currency"`
 }
 type Orderable interface {
 	Less(other Orderable) bool
@@ -6,50 +6,86 @@ package gen
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 type Querier interface {
-	ContributeToGoal(ctx context.Context, userID string, goalID string, amount float32, description string, accountNumber string) error
-	CreateAccount(ctx context.Context, userID string, accountName string, initialBalance float32) error
+	ClaimProcessedMessage(ctx context.Context, consumer string, messageKey string, ttlSeconds int32) (bool, error)
+	ContributeToGoal(ctx context.Context, userID string, goalID string, amount money.Amount, description string, accountNumber string) error
+	CreateAccount(ctx context.Context, userID string, accountName string, initialBalance money.Amount, currency string) error
 	CreateBeneficiary(ctx context.Context, userID string, name string, accountNumber string, description string) error
+	CreateBudget(ctx context.Context, userID string, categoryID string, amount money.Amount, period string, periodDays int32, startDate pgtype.Timestamp, rollover bool, currency string) (string, error)
 	CreateCategory(ctx context.Context, name string, description string, userID string) error
-	CreateGoal(ctx context.Context, userID string, name string, targetAmount float32, description string) error
+	CreateGoal(ctx context.Context, userID string, name string, targetAmount money.Amount, description string) (string, error)
 	CreatePassword(ctx context.Context, userID string, password string) error
+	CreateRecurringTransaction(ctx context.Context, userID string, accountNumber string, categoryID string, transactionType string, amount money.Amount, description string, frequency string, repeatEvery int32, dayOfWeek int32, dayOfMonth int32, businessDays bool, startDate pgtype.Timestamp, endDate pgtype.Timestamp) (string, error)
 	CreateUser(ctx context.Context, email string, authID string, phoneNumber string, password string, name string, avatarUrl string) (*Userpayload, error)
 	DeleteAccount(ctx context.Context, accountNumber string, userID string) error
 	DeleteBeneficiary(ctx context.Context, beneficiaryID string, userID string) error
+	DeleteBudget(ctx context.Context, budgetID string, userID string) error
 	DeleteCategory(ctx context.Context, categoryID string, userID string) error
 	DeleteGoal(ctx context.Context, goalID string, userID string) error
+	DeleteRecurringTransaction(ctx context.Context, recurringID string, userID string) error
+	DeleteTag(ctx context.Context, tagID string, userID string) error
 	DeleteTransaction(ctx context.Context, transactionID string, userID string) error
-	Deposit(ctx context.Context, userID string, accountNumber string, categoryID string, amount float32, description string) error
-	GetAccountTransactions(ctx context.Context, userID string, accountNumber string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error)
+	DeleteTransactionSplit(ctx context.Context, transactionID string, userID string) error
+	Deposit(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error
+	GetAccountTransactions(ctx context.Context, userID string, accountNumber string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error)
 	GetAccounts(ctx context.Context, userID string) ([]*Accountpayload, error)
 	GetBeneficiaries(ctx context.Context, userID string, pageNumber int32, pageSize int32) ([]*Beneficiarypayload, error)
 	GetBeneficiary(ctx context.Context, beneficiaryID string, userID string) (*Beneficiarypayload, error)
+	GetBudget(ctx context.Context, budgetID string, userID string, at time.Time) (*Budgetpayload, error)
 	GetCategoriesForUser(ctx context.Context, userID string) ([]*Categorypayload, error)
-	GetCategoryTransactions(ctx context.Context, userID string, categoryID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error)
-	GetGoalTransactions(ctx context.Context, userID string, goalID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error)
+	GetCategoryTransactions(ctx context.Context, userID string, categoryID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error)
+	GetFxRate(ctx context.Context, fromCurrency string, toCurrency string, at time.Time) (*Fxratepayload, error)
+	GetGoalTransactions(ctx context.Context, userID string, goalID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error)
+	GetTagsForUser(ctx context.Context, userID string) ([]*Tagpayload, error)
 	GetTransactionById(ctx context.Context, transactionID string, userID string) (*Transactionpayload, error)
-	GetTransactionsByType(ctx context.Context, userID string, transactionType string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error)
+	GetTransactionSplits(ctx context.Context, transactionID string, userID string) ([]*Transactionsplitpayload, error)
+	GetTransactionsByType(ctx context.Context, userID string, transactionType string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error)
 	GetUserByEmail(ctx context.Context, email string) (*Userpayload, error)
 	GetUserByID(ctx context.Context, userID string) (*Userpayload, error)
 	GetUserStats(ctx context.Context, email string) (*Userstats, error)
-	GetUserTransactions(ctx context.Context, userID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error)
+	GetUserTransactions(ctx context.Context, userID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error)
 	GetUsers(ctx context.Context) ([]*Userpayload, error)
+	IsMessageProcessed(ctx context.Context, consumer string, messageKey string) (bool, error)
+	ListFxRates(ctx context.Context) ([]*Fxratepayload, error)
+	ListPendingOutboxEvents(ctx context.Context, batchSize int32) ([]*Outboxpayload, error)
+	ListRecurringOccurrences(ctx context.Context, recurringID string, userID string, pageNumber int32, pageSize int32) ([]*Recurringoccurrencepayload, error)
+	ListUserBudgets(ctx context.Context, userID string, at time.Time) ([]*Budgetpayload, error)
 	ListUserGoals(ctx context.Context, userID string, pageNumber int32, pageSize int32) ([]*Goalpayload, error)
+	ListUserRecurringTransactions(ctx context.Context, userID string) ([]*Recurringtransactionpayload, error)
 	LoginUser(ctx context.Context, authID string, email string, name string, phoneNumber string, avatarUrl string) (*Userpayload, error)
 	LoginWithPassword(ctx context.Context, userID string, password string) (*Userpayload, error)
+	MarkOutboxEventsPublished(ctx context.Context, eventIds []int64) error
+	MaterializeRecurringTransactions(ctx context.Context, now time.Time, batchSize int32, maxCatchUp int32) ([]*Recurringoccurrencepayload, error)
+	PauseRecurringTransaction(ctx context.Context, recurringID string, userID string) error
+	PurgeProcessedMessages(ctx context.Context) (int64, error)
+	PurgePublishedOutboxEvents(ctx context.Context, olderThan time.Time) (int64, error)
+	RecordOutboxEventFailure(ctx context.Context, eventID int64, error string, park bool) error
+	RequeueParkedOutboxEvents(ctx context.Context) (int64, error)
+	RenameTag(ctx context.Context, tagID string, userID string, name string) (*Tagpayload, error)
+	ResumeRecurringTransaction(ctx context.Context, recurringID string, userID string) error
 	RevokePassword(ctx context.Context, userID string) error
-	Transfer(ctx context.Context, userID string, fromAccountNumber string, toAccountNumber string, amount float32, description string) error
+	SaveFxRate(ctx context.Context, baseCurrency string, quoteCurrency string, rate money.Rate, source string, asOf time.Time) (*Fxratepayload, error)
+	SkipRecurringOccurrence(ctx context.Context, recurringID string, userID string, occurrenceDate pgtype.Timestamp) error
+	SplitTransaction(ctx context.Context, transactionID string, userID string, categoryIds []string, amounts []money.Amount, memos []string) ([]*Transactionsplitpayload, error)
+	TagTransaction(ctx context.Context, transactionID string, userID string, tags []string) error
+	Transfer(ctx context.Context, userID string, fromAccountNumber string, toAccountNumber string, amount money.Amount, description string) (*Transferpayload, error)
+	UntagTransaction(ctx context.Context, transactionID string, userID string, tags []string) error
 	UpdateAccount(ctx context.Context, accountNumber string, userID string, accountName string) error
+	UpdateBaseCurrency(ctx context.Context, userID string, currency string) error
 	UpdateBeneficiary(ctx context.Context, beneficiaryID string, userID string, name string, accountNumber string, description string) error
+	UpdateBudget(ctx context.Context, budgetID string, userID string, amount money.Amount, period string, periodDays int32, startDate pgtype.Timestamp, rollover bool) error
 	UpdateCategory(ctx context.Context, categoryID string, name string, description string) error
-	UpdateGoal(ctx context.Context, goalID string, userID string, name string, targetAmount float32, description string) error
-	UpdateTransaction(ctx context.Context, transactionID string, userID string, accountNumber string, categoryID string, transactionType string, amount float32, description string) error
+	UpdateGoal(ctx context.Context, goalID string, userID string, name string, targetAmount money.Amount, description string) error
+	UpdateRecurringTransaction(ctx context.Context, recurringID string, userID string, accountNumber string, categoryID string, transactionType string, amount money.Amount, description string, frequency string, repeatEvery int32, dayOfWeek int32, dayOfMonth int32, businessDays bool, startDate pgtype.Timestamp, endDate pgtype.Timestamp) error
+	UpdateTransaction(ctx context.Context, transactionID string, userID string, accountNumber string, categoryID string, transactionType string, amount money.Amount, description string, splitCategoryIds []string, splitAmounts []money.Amount, splitMemos []string) error
 	UpdateUser(ctx context.Context, userID string, name string, phoneNumber string, avatarUrl string) (*Userpayload, error)
-	Withdraw(ctx context.Context, userID string, accountNumber string, categoryID string, amount float32, description string) error
+	Withdraw(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error
 }
 
 var _ Querier = (*Queries)(nil)
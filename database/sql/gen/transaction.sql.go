@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/qwallet-expense-tracker/shared/money"
 )
 
 const contributeToGoal = `-- name: ContributeToGoal :exec
@@ -21,7 +22,7 @@ select contribute_to_goal(
        )
 `
 
-func (q *Queries) ContributeToGoal(ctx context.Context, userID string, goalID string, amount float32, description string, accountNumber string) error {
+func (q *Queries) ContributeToGoal(ctx context.Context, userID string, goalID string, amount money.Amount, description string, accountNumber string) error {
 	_, err := q.db.Exec(ctx, contributeToGoal,
 		userID,
 		goalID,
@@ -44,6 +45,18 @@ func (q *Queries) DeleteTransaction(ctx context.Context, transactionID string, u
 	return err
 }
 
+const deleteTransactionSplit = `-- name: DeleteTransactionSplit :exec
+select delete_transaction_split(
+               $1,
+               $2
+       )
+`
+
+func (q *Queries) DeleteTransactionSplit(ctx context.Context, transactionID string, userID string) error {
+	_, err := q.db.Exec(ctx, deleteTransactionSplit, transactionID, userID)
+	return err
+}
+
 const deposit = `-- name: Deposit :exec
 select create_transaction(
                $1,
@@ -51,11 +64,12 @@ select create_transaction(
                $3,
                'CREDIT',
                $4::decimal,
-               $5
+               $5,
+               null::timestamptz
        )
 `
 
-func (q *Queries) Deposit(ctx context.Context, userID string, accountNumber string, categoryID string, amount float32, description string) error {
+func (q *Queries) Deposit(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error {
 	_, err := q.db.Exec(ctx, deposit,
 		userID,
 		accountNumber,
@@ -67,18 +81,20 @@ func (q *Queries) Deposit(ctx context.Context, userID string, accountNumber stri
 }
 
 const getAccountTransactions = `-- name: GetAccountTransactions :many
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from list_transactions_for_user_by_account(
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7::varchar[],
+        $8::boolean
      )
 `
 
-func (q *Queries) GetAccountTransactions(ctx context.Context, userID string, accountNumber string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error) {
+func (q *Queries) GetAccountTransactions(ctx context.Context, userID string, accountNumber string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error) {
 	rows, err := q.db.Query(ctx, getAccountTransactions,
 		userID,
 		accountNumber,
@@ -86,6 +102,8 @@ func (q *Queries) GetAccountTransactions(ctx context.Context, userID string, acc
 		endDate,
 		pageNumber,
 		pageSize,
+		tags,
+		matchAllTags,
 	)
 	if err != nil {
 		return nil, err
@@ -107,6 +125,11 @@ func (q *Queries) GetAccountTransactions(ctx context.Context, userID string, acc
 			&i.Status,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.FxRate,
+			&i.BaseCurrency,
+			&i.BaseAmount,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -119,18 +142,20 @@ func (q *Queries) GetAccountTransactions(ctx context.Context, userID string, acc
 }
 
 const getCategoryTransactions = `-- name: GetCategoryTransactions :many
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from list_transactions_for_user_by_category(
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7::varchar[],
+        $8::boolean
      )
 `
 
-func (q *Queries) GetCategoryTransactions(ctx context.Context, userID string, categoryID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error) {
+func (q *Queries) GetCategoryTransactions(ctx context.Context, userID string, categoryID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error) {
 	rows, err := q.db.Query(ctx, getCategoryTransactions,
 		userID,
 		categoryID,
@@ -138,6 +163,8 @@ func (q *Queries) GetCategoryTransactions(ctx context.Context, userID string, ca
 		endDate,
 		pageNumber,
 		pageSize,
+		tags,
+		matchAllTags,
 	)
 	if err != nil {
 		return nil, err
@@ -159,6 +186,11 @@ func (q *Queries) GetCategoryTransactions(ctx context.Context, userID string, ca
 			&i.Status,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.FxRate,
+			&i.BaseCurrency,
+			&i.BaseAmount,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -171,18 +203,20 @@ func (q *Queries) GetCategoryTransactions(ctx context.Context, userID string, ca
 }
 
 const getGoalTransactions = `-- name: GetGoalTransactions :many
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from list_transactions_for_user_by_goal(
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7::varchar[],
+        $8::boolean
      )
 `
 
-func (q *Queries) GetGoalTransactions(ctx context.Context, userID string, goalID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error) {
+func (q *Queries) GetGoalTransactions(ctx context.Context, userID string, goalID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error) {
 	rows, err := q.db.Query(ctx, getGoalTransactions,
 		userID,
 		goalID,
@@ -190,6 +224,8 @@ func (q *Queries) GetGoalTransactions(ctx context.Context, userID string, goalID
 		endDate,
 		pageNumber,
 		pageSize,
+		tags,
+		matchAllTags,
 	)
 	if err != nil {
 		return nil, err
@@ -211,6 +247,11 @@ func (q *Queries) GetGoalTransactions(ctx context.Context, userID string, goalID
 			&i.Status,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.FxRate,
+			&i.BaseCurrency,
+			&i.BaseAmount,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -223,7 +264,7 @@ func (q *Queries) GetGoalTransactions(ctx context.Context, userID string, goalID
 }
 
 const getTransactionById = `-- name: GetTransactionById :one
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from get_transaction_by_id(
         $1,
         $2
@@ -246,23 +287,67 @@ func (q *Queries) GetTransactionById(ctx context.Context, transactionID string,
 		&i.Status,
 		&i.UpdatedAt,
 		&i.IsDeleted,
+		&i.Currency,
+		&i.FxRate,
+		&i.BaseCurrency,
+		&i.BaseAmount,
+		&i.Tags,
 	)
 	return &i, err
 }
 
+const getTransactionSplits = `-- name: GetTransactionSplits :many
+select id, transaction_id, user_id, category_id, amount, memo, position, updated_at
+from list_transaction_splits(
+        $1,
+        $2
+     )
+`
+
+func (q *Queries) GetTransactionSplits(ctx context.Context, transactionID string, userID string) ([]*Transactionsplitpayload, error) {
+	rows, err := q.db.Query(ctx, getTransactionSplits, transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Transactionsplitpayload{}
+	for rows.Next() {
+		var i Transactionsplitpayload
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Memo,
+			&i.Position,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTransactionsByType = `-- name: GetTransactionsByType :many
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from list_transactions_for_user_by_type(
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7::varchar[],
+        $8::boolean
      )
 `
 
-func (q *Queries) GetTransactionsByType(ctx context.Context, userID string, transactionType string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error) {
+func (q *Queries) GetTransactionsByType(ctx context.Context, userID string, transactionType string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error) {
 	rows, err := q.db.Query(ctx, getTransactionsByType,
 		userID,
 		transactionType,
@@ -270,6 +355,8 @@ func (q *Queries) GetTransactionsByType(ctx context.Context, userID string, tran
 		endDate,
 		pageNumber,
 		pageSize,
+		tags,
+		matchAllTags,
 	)
 	if err != nil {
 		return nil, err
@@ -291,6 +378,11 @@ func (q *Queries) GetTransactionsByType(ctx context.Context, userID string, tran
 			&i.Status,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.FxRate,
+			&i.BaseCurrency,
+			&i.BaseAmount,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -303,23 +395,27 @@ func (q *Queries) GetTransactionsByType(ctx context.Context, userID string, tran
 }
 
 const getUserTransactions = `-- name: GetUserTransactions :many
-select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted
+select id, user_id, account_number, account_name, category_id, type, amount, description, reference_number, status, updated_at, is_deleted, currency, fx_rate, base_currency, base_amount, tags
 from list_transactions_for_user(
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6::varchar[],
+        $7::boolean
      )
 `
 
-func (q *Queries) GetUserTransactions(ctx context.Context, userID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32) ([]*Transactionpayload, error) {
+func (q *Queries) GetUserTransactions(ctx context.Context, userID string, startDate pgtype.Timestamp, endDate pgtype.Timestamp, pageNumber int32, pageSize int32, tags []string, matchAllTags bool) ([]*Transactionpayload, error) {
 	rows, err := q.db.Query(ctx, getUserTransactions,
 		userID,
 		startDate,
 		endDate,
 		pageNumber,
 		pageSize,
+		tags,
+		matchAllTags,
 	)
 	if err != nil {
 		return nil, err
@@ -341,6 +437,11 @@ func (q *Queries) GetUserTransactions(ctx context.Context, userID string, startD
 			&i.Status,
 			&i.UpdatedAt,
 			&i.IsDeleted,
+			&i.Currency,
+			&i.FxRate,
+			&i.BaseCurrency,
+			&i.BaseAmount,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -352,25 +453,82 @@ func (q *Queries) GetUserTransactions(ctx context.Context, userID string, startD
 	return items, nil
 }
 
-const transfer = `-- name: Transfer :exec
-select account_to_account_transfer(
-               $1,
-               $2,
-               $3,
-               $4,
-               $5
-       )
+const splitTransaction = `-- name: SplitTransaction :many
+select id, transaction_id, user_id, category_id, amount, memo, position, updated_at
+from split_transaction(
+        $1,
+        $2,
+        $3::varchar[],
+        $4::numeric[],
+        $5::text[]
+     )
 `
 
-func (q *Queries) Transfer(ctx context.Context, userID string, fromAccountNumber string, toAccountNumber string, amount float32, description string) error {
-	_, err := q.db.Exec(ctx, transfer,
+func (q *Queries) SplitTransaction(ctx context.Context, transactionID string, userID string, categoryIds []string, amounts []money.Amount, memos []string) ([]*Transactionsplitpayload, error) {
+	rows, err := q.db.Query(ctx, splitTransaction,
+		transactionID,
+		userID,
+		categoryIds,
+		amounts,
+		memos,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Transactionsplitpayload{}
+	for rows.Next() {
+		var i Transactionsplitpayload
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.UserID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Memo,
+			&i.Position,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transfer = `-- name: Transfer :one
+select debit_transaction_id, credit_transaction_id, from_currency, to_currency, fx_rate, debited_amount, credited_amount
+from account_to_account_transfer(
+        $1,
+        $2,
+        $3,
+        $4,
+        $5
+     )
+`
+
+func (q *Queries) Transfer(ctx context.Context, userID string, fromAccountNumber string, toAccountNumber string, amount money.Amount, description string) (*Transferpayload, error) {
+	row := q.db.QueryRow(ctx, transfer,
 		userID,
 		fromAccountNumber,
 		toAccountNumber,
 		amount,
 		description,
 	)
-	return err
+	var i Transferpayload
+	err := row.Scan(
+		&i.DebitTransactionID,
+		&i.CreditTransactionID,
+		&i.FromCurrency,
+		&i.ToCurrency,
+		&i.FxRate,
+		&i.DebitedAmount,
+		&i.CreditedAmount,
+	)
+	return &i, err
 }
 
 const updateTransaction = `-- name: UpdateTransaction :exec
@@ -381,11 +539,14 @@ select update_transaction(
                $4,
                $5,
                $6::decimal,
-               $7
+               $7,
+               $8::varchar[],
+               $9::numeric[],
+               $10::text[]
        )
 `
 
-func (q *Queries) UpdateTransaction(ctx context.Context, transactionID string, userID string, accountNumber string, categoryID string, transactionType string, amount float32, description string) error {
+func (q *Queries) UpdateTransaction(ctx context.Context, transactionID string, userID string, accountNumber string, categoryID string, transactionType string, amount money.Amount, description string, splitCategoryIds []string, splitAmounts []money.Amount, splitMemos []string) error {
 	_, err := q.db.Exec(ctx, updateTransaction,
 		transactionID,
 		userID,
@@ -394,6 +555,9 @@ func (q *Queries) UpdateTransaction(ctx context.Context, transactionID string, u
 		transactionType,
 		amount,
 		description,
+		splitCategoryIds,
+		splitAmounts,
+		splitMemos,
 	)
 	return err
 }
@@ -405,11 +569,12 @@ select create_transaction(
                $3,
                'DEBIT',
                $4::decimal,
-               $5
+               $5,
+               null::timestamptz
        )
 `
 
-func (q *Queries) Withdraw(ctx context.Context, userID string, accountNumber string, categoryID string, amount float32, description string) error {
+func (q *Queries) Withdraw(ctx context.Context, userID string, accountNumber string, categoryID string, amount money.Amount, description string) error {
 	_, err := q.db.Exec(ctx, withdraw,
 		userID,
 		accountNumber,
@@ -92,7 +92,7 @@ func (q *Queries) GetUserByID(ctx context.Context, userID string) (*Userpayload,
 }
 
 const getUserStats = `-- name: GetUserStats :one
-select total_accounts, total_transactions, total_categories, total_goals, account_balance, account_number, total_income, total_expense
+select total_accounts, total_transactions, total_categories, total_goals, account_balance, account_number, total_income, total_expense, base_currency
 from get_user_stats($1::varchar)
 `
 
@@ -108,6 +108,7 @@ func (q *Queries) GetUserStats(ctx context.Context, email string) (*Userstats, e
 		&i.AccountNumber,
 		&i.TotalIncome,
 		&i.TotalExpense,
+		&i.BaseCurrency,
 	)
 	return &i, err
 }
@@ -206,6 +207,17 @@ func (q *Queries) RevokePassword(ctx context.Context, userID string) error {
 	return err
 }
 
+const updateBaseCurrency = `-- name: UpdateBaseCurrency :exec
+select update_user_base_currency(
+               $1::varchar,
+               $2::varchar)
+`
+
+func (q *Queries) UpdateBaseCurrency(ctx context.Context, userID string, currency string) error {
+	_, err := q.db.Exec(ctx, updateBaseCurrency, userID, currency)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 select id, email, name, phone_number, avatar_url, is_deleted
 from update_user(
@@ -99,6 +99,7 @@ create table if not exists UserMaster
     AuthID       varchar                 default null, -- token from auth ID
     PasswordHash varchar                 default null, -- biometric hash (PIN/Password/Biometric)
     AvatarUrl    varchar                 default '',   -- biometric hash (PIN/Password/Biometric)
+    BaseCurrency varchar(3)   not null   default 'GHS' check (BaseCurrency ~ '^[A-Z]{3}$'),
     CreatedAt    timestamptz  not null   default now(),
     UpdatedAt    timestamptz  not null   default now()
 );
@@ -111,6 +112,7 @@ create table if not exists AccountMaster
     UserID        varchar(36)    not null references UserMaster (ID) on delete cascade,
     AccountNumber varchar(255)   not null unique default gen_random_account_number(),
     Balance       numeric(10, 2) not null        default 0.00,
+    Currency      varchar(3)     not null        default 'GHS' check (Currency ~ '^[A-Z]{3}$'),
     Name          varchar(255)   not null        default 'Cash on hand',
     CreatedAt     timestamptz    not null        default now(),
     UpdatedAt     timestamptz    not null        default now(),
@@ -118,6 +120,7 @@ create table if not exists AccountMaster
     -- unique constraint
     constraint unique_account_name unique (Name, UserID)
 );
+comment on column AccountMaster.Balance is 'in the currency of the account';
 create index if not exists idx_user_id on AccountMaster (UserID);
 
 -- transaction category table
@@ -145,6 +148,8 @@ create table if not exists TransactionMaster
     AccountID       varchar(36)    not null references AccountMaster (ID) on delete cascade,
     CategoryID      varchar(36)    not null references TransactionCategoryMaster (ID) on delete cascade,
     Amount          numeric(10, 2) not null,
+    Currency        varchar(3)     not null default 'GHS',
+    FxRate          numeric(18, 8) not null default 1 check (FxRate > 0),
     ReferenceNumber varchar(255)   not null default gen_random_transaction_ref_number(),
     Type            varchar(10)    not null check (Type in ('CREDIT', 'DEBIT')),
     LastEditBy      varchar(36)    not null references UserMaster (ID) on delete cascade,
@@ -154,11 +159,105 @@ create table if not exists TransactionMaster
     UpdatedAt       timestamptz    not null default now()
 );
 comment on column TransactionMaster.Amount is 'cannot be negative';
+comment on column TransactionMaster.Currency is 'always the currency of the account, set by trigger';
+comment on column TransactionMaster.FxRate is 'rate from the debited to the credited currency used by a cross-currency transfer, 1 otherwise';
 create index if not exists idx_transaction_type on TransactionMaster (Type, AccountID);
 create index if not exists idx_transaction_status on TransactionMaster (Status, AccountID);
 create index if not exists idx_transaction_category_id on TransactionMaster (CategoryID);
 create index if not exists idx_transaction_user_id on TransactionMaster (UserID);
 create index if not exists idx_transaction_ref_number on TransactionMaster (ReferenceNumber);
+create index if not exists idx_transaction_budget on TransactionMaster (UserID, CategoryID, CreatedAt) where Type = 'DEBIT';
+
+-- transaction split table (lines of a transaction spread over several categories, the lines add up to the transaction amount)
+create table if not exists TransactionSplitMaster
+(
+    TID           bigint         not null default gen_random_shard_id(),
+    ID            varchar(36) primary key default gen_random_qwallet_id(),
+    TransactionID varchar(36)    not null references TransactionMaster (ID) on delete cascade,
+    UserID        varchar(36)    not null references UserMaster (ID) on delete cascade,
+    CategoryID    varchar(36)    not null references TransactionCategoryMaster (ID) on delete cascade,
+    Amount        numeric(10, 2) not null check (Amount > 0),
+    Memo          text           not null default '',
+    Position      int            not null,
+    CreatedAt     timestamptz    not null default now(),
+    UpdatedAt     timestamptz    not null default now(),
+
+    -- unique constraint
+    constraint unique_transaction_split_position unique (TransactionID, Position)
+);
+comment on column TransactionSplitMaster.Amount is 'in the currency of the transaction';
+comment on column TransactionSplitMaster.Position is 'order of the line in the split, starting at 1';
+create index if not exists idx_transaction_split_category_id on TransactionSplitMaster (CategoryID);
+create index if not exists idx_transaction_split_user_id on TransactionSplitMaster (UserID);
+
+-- tag table (free-form labels of a user, names are unique regardless of case)
+create table if not exists TagMaster
+(
+    TID       bigint      not null default gen_random_shard_id(),
+    ID        varchar(36) primary key default gen_random_qwallet_id(),
+    UserID    varchar(36) not null references UserMaster (ID) on delete cascade,
+    Name      varchar(50) not null check (Name = btrim(Name) and Name <> ''),
+    CreatedAt timestamptz not null default now(),
+    UpdatedAt timestamptz not null default now()
+);
+create unique index if not exists idx_tag_user_name on TagMaster (UserID, lower(Name));
+
+-- transaction tag table (tags of a transaction)
+create table if not exists TransactionTagMaster
+(
+    TransactionID varchar(36) not null references TransactionMaster (ID) on delete cascade,
+    TagID         varchar(36) not null references TagMaster (ID) on delete cascade,
+    UserID        varchar(36) not null references UserMaster (ID) on delete cascade,
+    CreatedAt     timestamptz not null default now(),
+    primary key (TransactionID, TagID)
+);
+create index if not exists idx_transaction_tag_tag_id on TransactionTagMaster (TagID);
+
+-- recurring transaction table (templates materialized into TransactionMaster by the scheduler on every scheduled date)
+create table if not exists RecurringTransactionMaster
+(
+    TID          bigint         not null default gen_random_shard_id(),
+    ID           varchar(36) primary key default gen_random_qwallet_id(),
+    UserID       varchar(36)    not null references UserMaster (ID) on delete cascade,
+    AccountID    varchar(36)    not null references AccountMaster (ID) on delete cascade,
+    CategoryID   varchar(36)    not null references TransactionCategoryMaster (ID) on delete cascade,
+    Type         varchar(10)    not null check (Type in ('CREDIT', 'DEBIT')),
+    Amount       numeric(10, 2) not null check (Amount > 0),
+    Description  text           not null,
+    Frequency    varchar(10)    not null check (Frequency in ('DAILY', 'WEEKLY', 'MONTHLY')),
+    RepeatEvery  int            not null default 1 check (RepeatEvery > 0),
+    DayOfWeek    int                     default null check (DayOfWeek between 1 and 7),
+    DayOfMonth   int                     default null check (DayOfMonth = -1 or DayOfMonth between 1 and 31),
+    BusinessDays boolean        not null default false,
+    StartDate    date           not null default current_date,
+    EndDate      date                    default null,
+    NextRunAt    date                    default null,
+    Status       varchar(10)    not null default 'ACTIVE' check (Status in ('ACTIVE', 'PAUSED', 'ENDED')),
+    CreatedAt    timestamptz    not null default now(),
+    UpdatedAt    timestamptz    not null default now()
+);
+comment on column RecurringTransactionMaster.DayOfWeek is 'ISO day of week (1 = monday) of WEEKLY schedules, defaults to the day of the start date';
+comment on column RecurringTransactionMaster.DayOfMonth is 'day of MONTHLY schedules (-1 = last day), defaults to the day of the start date';
+comment on column RecurringTransactionMaster.BusinessDays is 'occurrences on a weekend move to the friday before';
+comment on column RecurringTransactionMaster.NextRunAt is 'next scheduled date before the business day adjustment, null once the schedule ended';
+create index if not exists idx_recurring_user_id on RecurringTransactionMaster (UserID);
+create index if not exists idx_recurring_next_run_at on RecurringTransactionMaster (NextRunAt) where Status = 'ACTIVE';
+
+-- recurring occurrence table (one row per materialized or skipped date, the unique key makes materialization happen once)
+create table if not exists RecurringOccurrenceMaster
+(
+    ID             varchar(36) primary key default gen_random_qwallet_id(),
+    RecurringID    varchar(36) not null references RecurringTransactionMaster (ID) on delete cascade,
+    UserID         varchar(36) not null references UserMaster (ID) on delete cascade,
+    OccurrenceDate date        not null,
+    Status         varchar(10) not null check (Status in ('PENDING', 'COMPLETED', 'FAILED', 'SKIPPED')),
+    TransactionID  varchar(36)          default null references TransactionMaster (ID) on delete set null,
+    Error          text                 default null,
+    CreatedAt      timestamptz not null default now(),
+
+    -- unique constraint
+    constraint unique_recurring_occurrence unique (RecurringID, OccurrenceDate)
+);
 
 
 -- beneficiary table (stores beneficiary information - needed for fund transfer)
@@ -186,6 +285,7 @@ create table if not exists GoalMaster
     Name                  varchar(255)   not null,
     Target                numeric(10, 2) not null,
     Balance               numeric(10, 2) not null default 0.00,
+    Currency              varchar(3)     not null default 'GHS' check (Currency ~ '^[A-Z]{3}$'),
     Status                varchar(50)    not null default 'PENDING' check (Status in ('PENDING', 'COMPLETED', 'IN_PROGRESS', 'CANCELLED')),
     AmountContributed     numeric(10, 2) not null default 0.00,
     PercentageContributed numeric(5, 2)  not null default 0.00,
@@ -193,6 +293,7 @@ create table if not exists GoalMaster
     CreatedAt             timestamptz    not null default now(),
     UpdatedAt             timestamptz    not null default now()
 );
+comment on column GoalMaster.Currency is 'currency of the target, contributions from other currencies are converted into it';
 create index if not exists idx_goal_name on GoalMaster (Name);
 create index if not exists idx_goal_user_id on GoalMaster (UserID);
 
@@ -213,34 +314,140 @@ create table if not exists GoalTrashMaster
     UpdatedAt             timestamptz    not null default now()
 );
 
+-- outbox table (stores change events in the same transaction as the change - relayed to kafka)
+create table if not exists OutboxMaster
+(
+    ID          bigserial primary key,
+    Aggregate   varchar(50)  not null,
+    AggregateID varchar(255) not null,
+    UserID      varchar(36)  not null,
+    Operation   varchar(10)  not null check (Operation in ('INSERT', 'UPDATE', 'DELETE')),
+    Payload     jsonb        not null,
+    Attempts    int          not null default 0,
+    LastError   text                  default null,
+    CreatedAt   timestamptz  not null default now(),
+    PublishedAt timestamptz           default null,
+    ParkedAt    timestamptz           default null
+);
+comment on column OutboxMaster.UserID is 'not a foreign key: events for deleted users must still be relayed';
+comment on column OutboxMaster.ParkedAt is 'set once the event failed permanently or too often, parked events are skipped until requeued';
+create index if not exists idx_outbox_pending on OutboxMaster (ID) where PublishedAt is null and ParkedAt is null;
+create index if not exists idx_outbox_published_at on OutboxMaster (PublishedAt);
+
+-- processed messages (idempotent consumers - a message is handled once per consumer until its key expires)
+create table if not exists ProcessedMessageMaster
+(
+    Consumer    varchar(255) not null,
+    MessageKey  varchar(255) not null,
+    ProcessedAt timestamptz  not null default now(),
+    ExpiresAt   timestamptz  not null,
+    primary key (Consumer, MessageKey)
+);
+create index if not exists idx_processed_message_expires_at on ProcessedMessageMaster (ExpiresAt);
+
+-- budget table (spending limit of a category per period, unused amounts can roll over to the next period)
+create table if not exists BudgetMaster
+(
+    TID        bigint         not null default gen_random_shard_id(),
+    ID         varchar(36) primary key default gen_random_qwallet_id(),
+    UserID     varchar(36)    not null references UserMaster (ID) on delete cascade,
+    CategoryID varchar(36)    not null references TransactionCategoryMaster (ID) on delete cascade,
+    Amount     numeric(10, 2) not null check (Amount > 0),
+    Currency   varchar(3)     not null default 'GHS' check (Currency ~ '^[A-Z]{3}$'),
+    Period     varchar(10)    not null check (Period in ('WEEKLY', 'MONTHLY', 'CUSTOM')),
+    PeriodDays int                     default null check (PeriodDays > 0),
+    StartDate  date           not null default current_date,
+    Rollover   boolean        not null default false,
+    CreatedAt  timestamptz    not null default now(),
+    UpdatedAt  timestamptz    not null default now(),
+
+    -- unique constraint
+    constraint unique_budget_category unique (UserID, CategoryID),
+    constraint custom_budget_period_days check ((Period = 'CUSTOM') = (PeriodDays is not null))
+);
+comment on column BudgetMaster.PeriodDays is 'length of CUSTOM periods in days';
+comment on column BudgetMaster.Currency is 'currency of the amount, spending in other currencies is converted into it';
+create index if not exists idx_budget_user_id on BudgetMaster (UserID);
+
+-- budget alert table (spending thresholds crossed by a budget, each threshold is alerted once per period)
+create table if not exists BudgetAlertMaster
+(
+    ID          varchar(36) primary key default gen_random_qwallet_id(),
+    BudgetID    varchar(36)    not null references BudgetMaster (ID) on delete cascade,
+    UserID      varchar(36)    not null references UserMaster (ID) on delete cascade,
+    PeriodStart timestamptz    not null,
+    PeriodEnd   timestamptz    not null,
+    Threshold   int            not null check (Threshold in (80, 100)),
+    LimitAmount numeric(10, 2) not null,
+    Spent       numeric(10, 2) not null,
+    CreatedAt   timestamptz    not null default now(),
+
+    -- unique constraint
+    constraint unique_budget_alert unique (BudgetID, PeriodStart, Threshold)
+);
+
+-- fx rate table (exchange rates fetched from a rate provider, kept as history so amounts can be converted as of a date)
+create table if not exists FxRateMaster
+(
+    BaseCurrency  varchar(3)     not null check (BaseCurrency ~ '^[A-Z]{3}$'),
+    QuoteCurrency varchar(3)     not null check (QuoteCurrency ~ '^[A-Z]{3}$'),
+    Rate          numeric(18, 8) not null check (Rate > 0),
+    Source        varchar(50)    not null default 'manual',
+    AsOf          timestamptz    not null default now(),
+    CreatedAt     timestamptz    not null default now(),
+    primary key (BaseCurrency, QuoteCurrency, AsOf),
+    constraint fx_rate_distinct_currencies check (BaseCurrency <> QuoteCurrency)
+);
+comment on column FxRateMaster.Rate is 'amount of the quote currency bought by one unit of the base currency';
+
+-- columns added after AccountMaster was first created (create table if not exists leaves existing databases as they are)
+alter table AccountMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
 drop table if exists AccountPayload cascade;
 create table if not exists AccountPayload
 (
-    name           varchar not null,
-    balance        numeric not null,
-    account_number varchar not null,
-    user_id        varchar not null,
+    name           varchar     not null,
+    balance        numeric     not null,
+    account_number varchar     not null,
+    user_id        varchar     not null,
     updated_at     timestamptz,
-    is_deleted     boolean not null default false
+    is_deleted     boolean     not null default false,
+    currency       varchar     not null default 'GHS',
+    base_currency  varchar,
+    base_balance   numeric
 );
+comment on column AccountPayload.base_balance is 'balance in the base currency of the user, null when there is no exchange rate';
 
 drop function if exists create_new_account cascade;
 create or replace function create_new_account(
     p_user_id varchar,
     p_name varchar,
-    p_initial_balance numeric
+    p_initial_balance numeric,
+    p_currency varchar
 ) returns void as
 $$
 declare
     user_exists            boolean = false;
     existing_account_count int     = 0;
+    base_currency          varchar;
 begin
     select exists(select 1 from usermaster u where u.id = p_user_id)
     into user_exists;
 
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    -- accounts are in the base currency of the user unless told otherwise
+    if p_currency is null or p_currency = '' then
+        select u.basecurrency
+        from usermaster u
+        where u.id = p_user_id
+        into base_currency;
+        p_currency := base_currency;
     end if;
+    perform validate_currency(p_currency);
 
     select count(*)
     from accountmaster a
@@ -248,11 +455,11 @@ begin
     into existing_account_count;
 
     if existing_account_count > 0 then
-        raise exception 'An account with name % already exists', p_name;
+        raise exception 'An account with name % already exists', p_name using errcode = 'QW201';
     end if;
 
-    insert into accountmaster(userid, balance, name, accountnumber)
-    values (p_user_id, p_initial_balance, p_name, gen_random_account_number());
+    insert into accountmaster(userid, balance, currency, name, accountnumber)
+    values (p_user_id, p_initial_balance, p_currency, p_name, gen_random_account_number());
     raise notice 'Account created for user %', p_user_id;
 end;
 
@@ -263,8 +470,8 @@ create or replace function create_account_for_new_user() returns trigger as
 $$
 begin
     raise notice 'Creating account for new user %', new.id;
-    insert into accountmaster(userid, balance, accountnumber)
-    VALUES (new.id, 0.00, gen_random_account_number());
+    insert into accountmaster(userid, balance, currency, accountnumber)
+    VALUES (new.id, 0.00, new.basecurrency, gen_random_account_number());
     return new;
 end;
 $$ language plpgsql;
@@ -307,16 +514,26 @@ as
 $$
 begin
     return query
-        select a.name, a.balance, a.accountnumber, a.userid, a.updatedat, false
+        select a.name,
+               a.balance,
+               a.accountnumber,
+               a.userid,
+               a.updatedat,
+               false,
+               a.currency,
+               u.basecurrency,
+               convert_amount(a.balance, a.currency, u.basecurrency, now())
         from accountmaster a
+                 join usermaster u on u.id = a.userid
         where a.userid = p_user_id
         order by a.updatedat desc;
 end;
 $$ language plpgsql;
 
-drop function if exists recompute_account_balance cascade;
-create or replace function recompute_account_balance()
-    returns trigger as
+drop function if exists update_account_balance cascade;
+create or replace function update_account_balance(
+    p_account_id varchar
+) returns void as
 $$
 declare
     account_balance      numeric;
@@ -325,27 +542,88 @@ declare
 begin
     select coalesce(sum(t.amount), 0)
     from transactionmaster t
-    where t.accountid = new.accountid
+    where t.accountid = p_account_id
       and t.type = 'DEBIT'
     into total_expense_amount;
-    raise notice 'Total expense amount: %', total_expense_amount;
     select coalesce(sum(t.amount), 0)
     from transactionmaster t
-    where t.accountid = new.accountid
+    where t.accountid = p_account_id
       and t.type = 'CREDIT'
     into total_income_amount;
-    raise notice 'Total income amount: %', total_income_amount;
 
     account_balance := total_income_amount - total_expense_amount;
-    raise notice 'Account balance: %', account_balance;
 
     update accountmaster
     set balance = account_balance
-    where id = new.accountid;
+    where id = p_account_id;
+end;
+$$ language plpgsql;
+
+drop function if exists recompute_account_balance cascade;
+create or replace function recompute_account_balance()
+    returns trigger as
+$$
+begin
+    if tg_op <> 'DELETE' then
+        perform update_account_balance(new.accountid);
+    end if;
+
+    -- an update can also move the transaction away from its account
+    if tg_op = 'DELETE' or old.accountid <> new.accountid then
+        perform update_account_balance(old.accountid);
+    end if;
     return new;
 end;
 $$ language plpgsql;
 
+drop function if exists balance_effect cascade;
+create or replace function balance_effect(
+    p_type varchar,
+    p_amount numeric
+) returns numeric as
+$$
+begin
+    return case when p_type = 'CREDIT' then p_amount else -p_amount end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists lock_accounts cascade;
+create or replace function lock_accounts(
+    p_account_ids varchar[]
+) returns void as
+$$
+begin
+    -- always locked in the same order, so two changes of the same accounts cannot deadlock
+    perform 1
+    from accountmaster a
+    where a.id = any (p_account_ids)
+    order by a.id
+        for update;
+end;
+$$ language plpgsql;
+
+drop function if exists ensure_sufficient_funds cascade;
+create or replace function ensure_sufficient_funds(
+    p_account_id varchar,
+    p_withdrawal numeric
+) returns void as
+$$
+declare
+    account_balance numeric;
+begin
+    -- the row lock is held until the end of the transaction, so concurrent withdrawals are checked one after the other
+    select a.balance
+    into account_balance
+    from accountmaster a
+    where a.id = p_account_id
+        for update;
+
+    if p_withdrawal > 0 and account_balance - p_withdrawal < 0 then
+        raise exception 'Insufficient funds in account %', p_account_id using errcode = 'QW401';
+    end if;
+end;
+$$ language plpgsql;
+
 -- drop function if exists create_transaction_when_balance_is_non_zero cascade;
 create or replace function create_transaction_when_balance_is_non_zero()
     returns trigger as
@@ -362,7 +640,7 @@ begin
         into category_id;
 
         if category_id is null then
-            raise exception 'Category % does not exist', 'General';
+            raise exception 'Category % does not exist', 'General' using errcode = 'QW103';
         end if;
 
         insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
@@ -387,10 +665,10 @@ begin
         into user_id;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
-        select old.name, old.balance, old.accountnumber, old.userid, old.updatedat, true
+        select old.name, old.balance, old.accountnumber, old.userid, old.updatedat, true, old.currency, null, null
         into payload;
     else
         select u.id
@@ -400,13 +678,14 @@ begin
         into user_id;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
-        select new.name, new.balance, new.accountnumber, new.userid, new.updatedat, false
+        select new.name, new.balance, new.accountnumber, new.userid, new.updatedat, false, new.currency, null, null
         into payload;
     end if;
 
+    perform enqueue_outbox_event('accounts', payload.account_number, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('accounts', row_to_json(payload)::text);
     return new;
 end;
@@ -464,11 +743,11 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     if exists(select 1 from beneficiarymaster b where b.userid = p_user_id and b.accountid = account_id) then
-        raise exception 'Beneficiary for account % already exists', p_account_number;
+        raise exception 'Beneficiary for account % already exists', p_account_number using errcode = 'QW202';
     end if;
 
     if p_description is null or p_description = '' then
@@ -491,7 +770,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     delete
@@ -512,7 +791,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     return query
@@ -539,7 +818,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     select a.id
@@ -549,7 +828,7 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     update beneficiarymaster
@@ -575,15 +854,15 @@ declare
 begin
     select exists(select 1 from usermaster u where u.id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     return query
@@ -618,11 +897,11 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         if account_id is null then
-            raise exception 'Account % does not exist', account_number;
+            raise exception 'Account % does not exist', account_number using errcode = 'QW102';
         end if;
 
         select old.id, account_number, old.name, old.description, old.userid, old.updatedat, true
@@ -640,17 +919,18 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         if account_id is null then
-            raise exception 'Account % does not exist', account_number;
+            raise exception 'Account % does not exist', account_number using errcode = 'QW102';
         end if;
 
         select new.id, account_number, new.name, new.description, new.userid, new.updatedat, false
         into payload;
     end if;
 
+    perform enqueue_outbox_event('beneficiaries', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('beneficiaries', row_to_json(payload)::text);
     return new;
 end;
@@ -704,7 +984,7 @@ declare
 begin
     select exists(select 1 from transactioncategorymaster where id = p_category_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
     delete
@@ -726,7 +1006,7 @@ declare
 begin
     select exists(select 1 from transactioncategorymaster where id = p_category_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
     update transactioncategorymaster
@@ -831,7 +1111,7 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         select old.id, old.name, old.description, old.userid, true
@@ -843,13 +1123,14 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
         select new.id, new.name, new.description, new.userid, false
         into payload;
     end if;
 
+    perform enqueue_outbox_event('categories', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('categories', row_to_json(payload)::text);
     return new;
 end;
@@ -869,6 +1150,10 @@ create or replace trigger trigger_notify_categories
     for each row
 execute function notify_categories();
 
+-- columns added after GoalMaster was first created (create table if not exists leaves existing databases as they are)
+alter table GoalMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
 drop table if exists GoalPayload cascade;
 create table if not exists GoalPayload
 (
@@ -878,9 +1163,28 @@ create table if not exists GoalPayload
     description text           not null,
     balance     numeric(10, 2) not null,
     user_id     varchar        not null,
-    is_deleted  boolean        not null default false
+    is_deleted  boolean        not null default false,
+    currency    varchar        not null default 'GHS'
 );
 
+drop table if exists GoalContributionPayload cascade;
+create table if not exists GoalContributionPayload
+(
+    id              varchar     not null,
+    name            varchar     not null,
+    target          numeric     not null,
+    description     text        not null,
+    balance         numeric     not null,
+    user_id         varchar     not null,
+    currency        varchar     not null,
+    amount          numeric     not null,
+    amount_currency varchar     not null,
+    account_number  varchar     not null,
+    transaction_id  varchar     not null,
+    contributed_at  timestamptz not null
+);
+comment on column GoalContributionPayload.amount is 'in the currency of the account (amount_currency), not of the goal';
+
 drop function if exists create_goal cascade;
 create or replace function create_goal(
     p_user_id varchar,
@@ -894,21 +1198,24 @@ declare
     goal_id varchar;
 begin
     if p_target <= 0 then
-        raise exception 'Target amount must be greater than 0';
+        raise exception 'Target amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     select exists(select 1 from goalmaster g where g.userid = p_user_id and g.name = p_name)
     into exists;
     if exists then
-        raise exception 'Goal % already exists', p_name;
+        raise exception 'Goal % already exists', p_name using errcode = 'QW203';
     end if;
 
     if p_description is null then
         p_description := 'Create a goal to save money for a specific purpose';
     end if;
 
-    insert into goalmaster(userid, name, target, description)
-    values (p_user_id, p_name, p_target, p_description)
+    -- goals are in the base currency of the user
+    insert into goalmaster(userid, name, target, description, currency)
+    select p_user_id, p_name, p_target, p_description, u.basecurrency
+    from usermaster u
+    where u.id = p_user_id
     returning id
         into goal_id;
     return goal_id;
@@ -926,7 +1233,7 @@ declare
 begin
     select exists(select 1 from goalmaster g where g.id = p_goal_id and g.userid = p_user_id) into goal_exists;
     if not goal_exists then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
     end if;
 
     delete
@@ -947,7 +1254,7 @@ create or replace function update_goal(
 $$
 begin
     if p_target <= 0 then
-        raise exception 'Target amount must be greater than 0';
+        raise exception 'Target amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     update goalmaster
@@ -971,15 +1278,15 @@ $$
 declare
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     return query
-        select g.id, g.name, g.target, g.description, g.balance, g.userid, false
+        select g.id, g.name, g.target, g.description, g.balance, g.userid, false, g.currency
         from goalmaster g
         where userid = p_user_id
         order by updatedat desc
@@ -997,12 +1304,13 @@ declare
     goal_balance numeric;
 begin
 
-    select g.target, coalesce(sum(t.amount), 0) as total_contributed
+    -- contributions from accounts in other currencies are converted at the rate of the day they were made
+    select g.target, coalesce(sum(convert_amount(t.amount, t.currency, g.currency, t.createdat)), 0) as total_contributed
     into goal_data
     from goalmaster g
              left join transactionmaster t on t.referencenumber = g.id
     where g.id = new.referencenumber
-    group by g.target;
+    group by g.target, g.currency;
 
     if goal_data.target is null then
         raise notice 'Goal % does not exist', new.referencenumber;
@@ -1043,12 +1351,18 @@ declare
     accounts_array     varchar[];
     category_array     varchar[];
     amount_array       numeric[];
+    payload            goalpayload;
 begin
     if old.status = 'COMPLETED' then
         raise notice 'Goal % has already been completed', old.id;
         return old;
     end if;
 
+    -- a goal deleted before reaching its target was abandoned
+    select old.id, old.name, old.target, old.description, old.balance, old.userid, true, old.currency
+    into payload;
+    perform enqueue_outbox_event('goal_cancellations', old.id, old.userid, 'INSERT', to_jsonb(payload));
+
     select array_agg(distinct t.accountid), array_agg(t.categoryid), array_agg(t.amount)
     into accounts_array, category_array, amount_array
     from transactionmaster t
@@ -1095,10 +1409,10 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
-        select old.id, old.name, old.target, old.description, old.balance, old.userid, true
+        select old.id, old.name, old.target, old.description, old.balance, old.userid, true, old.currency
         into payload;
     else
         select u.id
@@ -1107,13 +1421,14 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
-        select new.id, new.name, new.target, new.description, new.balance, new.userid, false
+        select new.id, new.name, new.target, new.description, new.balance, new.userid, false, new.currency
         into payload;
     end if;
 
+    perform enqueue_outbox_event('goals', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('goals', row_to_json(payload)::text);
     return new;
 end;
@@ -1140,6 +1455,11 @@ create or replace trigger trigger_notify_goals
     for each row
 execute function notify_goals();
 
+-- columns added after TransactionMaster was first created (create table if not exists leaves existing databases as they are)
+alter table TransactionMaster
+    add column if not exists Currency varchar(3)     not null default 'GHS',
+    add column if not exists FxRate   numeric(18, 8) not null default 1 check (FxRate > 0);
+
 drop table if exists TransactionPayload cascade;
 create table TransactionPayload
 (
@@ -1154,7 +1474,55 @@ create table TransactionPayload
     reference_number varchar             not null,
     status           varchar             not null,
     updated_at       timestamptz                  default now(),
-    is_deleted       boolean             not null default false
+    is_deleted       boolean             not null default false,
+    currency         varchar             not null default 'GHS',
+    fx_rate          numeric             not null default 1,
+    base_currency    varchar,
+    base_amount      numeric,
+    tags             varchar[]
+);
+comment on column TransactionPayload.base_amount is 'amount in the base currency of the user as of the transaction, null when there is no exchange rate';
+
+drop table if exists TransferPayload cascade;
+create table TransferPayload
+(
+    debit_transaction_id  varchar not null,
+    credit_transaction_id varchar not null,
+    from_currency         varchar not null,
+    to_currency           varchar not null,
+    fx_rate               numeric not null,
+    debited_amount        numeric not null,
+    credited_amount       numeric not null
+);
+
+drop table if exists TransferEventPayload cascade;
+create table TransferEventPayload
+(
+    user_id               varchar     not null,
+    from_account_number   varchar     not null,
+    to_account_number     varchar     not null,
+    description           text        not null,
+    debit_transaction_id  varchar     not null,
+    credit_transaction_id varchar     not null,
+    from_currency         varchar     not null,
+    to_currency           varchar     not null,
+    fx_rate               numeric     not null,
+    debited_amount        numeric     not null,
+    credited_amount       numeric     not null,
+    completed_at          timestamptz not null
+);
+
+drop table if exists TransactionSplitPayload cascade;
+create table TransactionSplitPayload
+(
+    id             varchar     not null,
+    transaction_id varchar     not null,
+    user_id        varchar     not null,
+    category_id    varchar     not null,
+    amount         numeric     not null,
+    memo           varchar     not null,
+    position       int         not null,
+    updated_at     timestamptz not null
 );
 
 drop function if exists create_transaction cascade;
@@ -1164,12 +1532,14 @@ create or replace function create_transaction(
     p_category_id varchar,
     p_type varchar,
     p_amount numeric,
-    p_description varchar
-) returns void as
+    p_description varchar,
+    p_created_at timestamptz
+) returns varchar as
 $$
 declare
-    account_id  varchar;
-    category_id varchar;
+    account_id     varchar;
+    category_id    varchar;
+    transaction_id varchar;
 begin
     select a.id
     from accountmaster a
@@ -1178,7 +1548,7 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     select c.id
@@ -1188,11 +1558,18 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, account_id, category_id, p_type, p_amount, p_description, p_user_id, gen_random_transaction_ref_number());
+    perform ensure_sufficient_funds(account_id, case when p_type = 'DEBIT' then p_amount else 0 end);
+
+    -- backdated transactions (e.g. recurring occurrences caught up on) count in the period they belong to
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber, createdat)
+    values (p_user_id, account_id, category_id, p_type, p_amount, p_description, p_user_id, gen_random_transaction_ref_number(),
+            coalesce(p_created_at, now()))
+    returning id
+        into transaction_id;
+    return transaction_id;
 end;
 $$ language plpgsql;
 
@@ -1203,42 +1580,60 @@ create or replace function account_to_account_transfer(
     p_to_account_number varchar,
     p_amount numeric,
     p_description varchar
-) returns void as
+) returns setof transferpayload as
 $$
 declare
-    from_account_id  varchar;
-    to_account_id    varchar;
-    category_id      varchar;
-    from_description varchar;
-    to_description   varchar;
-    user_exists      bool = false;
+    from_account_id       varchar;
+    to_account_id         varchar;
+    from_currency         varchar;
+    to_currency           varchar;
+    transfer_rate         numeric;
+    credited_amount       numeric;
+    category_id           varchar;
+    from_description      varchar;
+    to_description        varchar;
+    debit_transaction_id  varchar;
+    credit_transaction_id varchar;
+    user_exists           bool = false;
+    payload               transfereventpayload;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     if p_amount <= 0 then
-        raise exception 'Amount must be greater than 0';
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     if p_to_account_number = p_from_account_number then
-        raise exception 'Cannot transfer to the same account';
+        raise exception 'Cannot transfer to the same account' using errcode = 'QW303';
     end if;
 
-    select a.id
+    select a.id, a.currency
     from accountmaster a
     where a.accountnumber = p_from_account_number
     limit 1
-    into from_account_id;
-    select a.id
+    into from_account_id, from_currency;
+    select a.id, a.currency
     from accountmaster a
     where a.accountnumber = p_to_account_number
     limit 1
-    into to_account_id;
+    into to_account_id, to_currency;
 
     if from_account_id is null or to_account_id is null then
-        raise exception 'One or both of the accounts do not exist';
+        raise exception 'One or both of the accounts do not exist' using errcode = 'QW102';
+    end if;
+
+    -- the rate used is recorded on both legs of the transfer
+    transfer_rate := fx_rate(from_currency, to_currency, now());
+    if transfer_rate is null then
+        raise exception 'No exchange rate from % to %', from_currency, to_currency using errcode = 'QW109';
+    end if;
+
+    credited_amount := round(p_amount * transfer_rate, currency_scale(to_currency));
+    if credited_amount <= 0 then
+        raise exception 'Amount is too small to be converted to %', to_currency using errcode = 'QW301';
     end if;
 
     select c.id
@@ -1249,17 +1644,22 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', 'General';
+        raise exception 'Category % does not exist', 'General' using errcode = 'QW103';
     end if;
 
+    perform lock_accounts(array [from_account_id, to_account_id]);
+    perform ensure_sufficient_funds(from_account_id, p_amount);
+
     if p_description is null or p_description = '' then
         to_description := 'Transfer to ' || p_to_account_number;
     else
         to_description := p_description;
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, from_account_id, category_id, 'DEBIT', p_amount, to_description, p_user_id, gen_random_transaction_ref_number());
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, fxrate, description, lasteditby, referencenumber)
+    values (p_user_id, from_account_id, category_id, 'DEBIT', p_amount, transfer_rate, to_description, p_user_id, gen_random_transaction_ref_number())
+    returning id
+        into debit_transaction_id;
 
     if p_description is null or p_description = '' then
         from_description := 'Transfer from ' || p_from_account_number;
@@ -1267,8 +1667,18 @@ begin
         from_description := p_description;
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, to_account_id, category_id, 'CREDIT', p_amount, from_description, p_user_id, gen_random_transaction_ref_number());
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, fxrate, description, lasteditby, referencenumber)
+    values (p_user_id, to_account_id, category_id, 'CREDIT', credited_amount, transfer_rate, from_description, p_user_id, gen_random_transaction_ref_number())
+    returning id
+        into credit_transaction_id;
+
+    select p_user_id, p_from_account_number, p_to_account_number, coalesce(p_description, ''), debit_transaction_id,
+           credit_transaction_id, from_currency, to_currency, transfer_rate, p_amount, credited_amount, now()
+    into payload;
+    perform enqueue_outbox_event('transfers', debit_transaction_id, p_user_id, 'INSERT', to_jsonb(payload));
+
+    return query
+        select debit_transaction_id, credit_transaction_id, from_currency, to_currency, transfer_rate, p_amount, credited_amount;
 end;
 
 $$
@@ -1281,11 +1691,24 @@ create or replace function delete_transaction(
 ) returns void as
 $$
 declare
+    account_id  varchar;
+    effect      numeric;
     user_exists bool = false;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    -- deleting a credit takes its amount away from the account
+    select t.accountid, balance_effect(t.type, t.amount)
+    into account_id, effect
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id;
+
+    if account_id is not null then
+        perform ensure_sufficient_funds(account_id, effect);
     end if;
 
     delete
@@ -1303,16 +1726,21 @@ create or replace function update_transaction(
     p_category_id varchar,
     p_type varchar,
     p_amount numeric,
-    p_description varchar
+    p_description varchar,
+    p_split_category_ids varchar[],
+    p_split_amounts numeric[],
+    p_split_memos text[]
 ) returns void as
 $$
 declare
-    account_id  varchar;
-    user_exists bool = false;
+    account_id     varchar;
+    old_account_id varchar;
+    old_effect     numeric;
+    user_exists    bool = false;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     select a.id
@@ -1322,7 +1750,24 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    select t.accountid, balance_effect(t.type, t.amount)
+    into old_account_id, old_effect
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id;
+
+    -- each account must afford what the edit takes away from it
+    if old_account_id is not null then
+        perform lock_accounts(array [old_account_id, account_id]);
+        if old_account_id = account_id then
+            perform ensure_sufficient_funds(account_id, old_effect - balance_effect(p_type, p_amount));
+        else
+            perform ensure_sufficient_funds(old_account_id, old_effect);
+            perform ensure_sufficient_funds(account_id, -balance_effect(p_type, p_amount));
+        end if;
     end if;
 
     update transactionmaster
@@ -1333,6 +1778,169 @@ begin
         description = p_description
     where id = p_transaction_id
       and userid = p_user_id;
+
+    if not found then
+        return;
+    end if;
+
+    -- null keeps the current lines, an empty array removes the split
+    if p_split_category_ids is not null then
+        perform replace_transaction_splits(p_transaction_id, p_user_id, p_split_category_ids, p_split_amounts, p_split_memos);
+    end if;
+    perform check_transaction_splits(p_transaction_id);
+end;
+$$ language plpgsql;
+
+drop function if exists replace_transaction_splits cascade;
+create or replace function replace_transaction_splits(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_category_ids varchar[],
+    p_amounts numeric[],
+    p_memos text[]
+) returns void as
+$$
+declare
+    line_count int;
+begin
+    line_count := coalesce(array_length(p_category_ids, 1), 0);
+    if coalesce(array_length(p_amounts, 1), 0) <> line_count or coalesce(array_length(p_memos, 1), 0) <> line_count then
+        raise exception 'Every split line needs a category, an amount and a memo' using errcode = 'QW304';
+    end if;
+
+    for idx in 1..line_count
+        loop
+            if p_amounts[idx] is null or p_amounts[idx] <= 0 then
+                raise exception 'Split line % must have an amount greater than 0', idx using errcode = 'QW301';
+            end if;
+
+            if not exists(select 1 from transactioncategorymaster c where c.id = p_category_ids[idx] and c.userid = p_user_id) then
+                raise exception 'Category % does not exist', p_category_ids[idx] using errcode = 'QW103';
+            end if;
+        end loop;
+
+    delete
+    from transactionsplitmaster
+    where transactionid = p_transaction_id;
+
+    insert into transactionsplitmaster(transactionid, userid, categoryid, amount, memo, position)
+    select p_transaction_id, p_user_id, l.category_id, l.amount, coalesce(l.memo, ''), l.position
+    from unnest(p_category_ids, p_amounts, p_memos) with ordinality as l(category_id, amount, memo, position);
+end;
+$$ language plpgsql;
+
+drop function if exists check_transaction_splits cascade;
+create or replace function check_transaction_splits(
+    p_transaction_id varchar
+) returns void as
+$$
+declare
+    transaction_amount numeric;
+    split_total        numeric;
+begin
+    select t.amount, (select sum(s.amount) from transactionsplitmaster s where s.transactionid = t.id)
+    from transactionmaster t
+    where t.id = p_transaction_id
+    into transaction_amount, split_total;
+
+    -- a transaction without lines is not split
+    if split_total is not null and split_total <> transaction_amount then
+        raise exception 'Split lines add up to % but the transaction amount is %', split_total, transaction_amount using errcode = 'QW306';
+    end if;
+end;
+$$ language plpgsql;
+
+drop function if exists split_transaction cascade;
+create or replace function split_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_category_ids varchar[],
+    p_amounts numeric[],
+    p_memos text[]
+) returns setof transactionsplitpayload as
+$$
+declare
+    user_exists bool = false;
+begin
+    select exists(select 1 from usermaster where id = p_user_id) into user_exists;
+    if not user_exists then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    -- the lock makes concurrent splits of the same transaction replace each other instead of interleaving
+    perform 1
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id
+        for update;
+    if not found then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    if coalesce(array_length(p_category_ids, 1), 0) = 0 then
+        raise exception 'A split needs at least one line' using errcode = 'QW304';
+    end if;
+
+    perform replace_transaction_splits(p_transaction_id, p_user_id, p_category_ids, p_amounts, p_memos);
+    perform check_transaction_splits(p_transaction_id);
+
+    return query
+        select *
+        from list_transaction_splits(p_transaction_id, p_user_id);
+end;
+$$ language plpgsql;
+
+drop function if exists delete_transaction_split cascade;
+create or replace function delete_transaction_split(
+    p_transaction_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    user_exists bool = false;
+begin
+    select exists(select 1 from usermaster where id = p_user_id) into user_exists;
+    if not user_exists then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    perform 1
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id
+        for update;
+    if not found then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    -- the transaction is reported under its own category again
+    delete
+    from transactionsplitmaster
+    where transactionid = p_transaction_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_transaction_splits cascade;
+create or replace function list_transaction_splits(
+    p_transaction_id varchar,
+    p_user_id varchar
+)
+    returns setof transactionsplitpayload
+as
+$$
+declare
+    transaction_exists bool = false;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    return query
+        select s.id, s.transactionid, s.userid, s.categoryid, s.amount, s.memo, s.position, s.updatedat
+        from transactionsplitmaster s
+        where s.transactionid = p_transaction_id
+        order by s.position;
 end;
 $$ language plpgsql;
 
@@ -1348,12 +1956,12 @@ declare
 begin
     select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
     if not transaction_exists then
-        raise exception 'Transaction % does not exist', p_transaction_id;
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
     end if;
 
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     return query
@@ -1368,9 +1976,15 @@ begin
                t.referencenumber,
                t.status,
                t.updatedat,
-               false
+               false,
+               t.currency,
+               t.fxrate,
+               u.basecurrency,
+               convert_amount(t.amount, t.currency, u.basecurrency, t.createdat),
+               transaction_tags(t.id)
         from transactionmaster t
                  left join accountmaster a on t.accountid = a.id
+                 join usermaster u on u.id = t.userid
         where t.id = p_transaction_id
           and t.userid = p_user_id;
 end;
@@ -1382,18 +1996,20 @@ create or replace function list_transactions_for_user(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
 $$
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -1405,24 +2021,31 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -1433,18 +2056,20 @@ create or replace function list_transactions_for_user_by_type(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
 $$
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -1456,25 +2081,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and t.type = p_type
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and t.type = p_type
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -1485,7 +2117,9 @@ create or replace function list_transactions_for_user_by_category(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
@@ -1494,11 +2128,11 @@ declare
     category_exists bool = false;
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -1511,29 +2145,38 @@ begin
 
     select exists(select 1 from transactioncategorymaster where id = p_category_id and userid = p_user_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
+    -- a split transaction is listed once per line of the category, with the amount and memo of the line
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and t.categoryid = p_category_id
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     coalesce(s.categoryid, t.categoryid),
+                     t.type,
+                     coalesce(s.amount, t.amount),
+                     coalesce(nullif(s.memo, ''), t.description),
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(coalesce(s.amount, t.amount), t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+                       left join transactionsplitmaster s on s.transactionid = t.id
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and coalesce(s.categoryid, t.categoryid) = p_category_id
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by t.updatedat desc, s.position
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -1544,7 +2187,9 @@ create or replace function list_transactions_for_user_by_account(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 ) returns setof transactionpayload
 as
 $$
@@ -1558,15 +2203,15 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -1578,25 +2223,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and a.accountnumber = p_account_number
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and a.accountnumber = p_account_number
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -1607,7 +2259,9 @@ create or replace function list_transactions_for_user_by_goal(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 ) returns setof transactionpayload
 as
 $$
@@ -1621,15 +2275,15 @@ begin
     into goal_id;
 
     if goal_id is null then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -1641,25 +2295,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.referencenumber = p_goal_id
-          and t.updatedat between p_start_date and p_end_date
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.referencenumber = p_goal_id
+                and t.updatedat between p_start_date and p_end_date
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -1673,13 +2334,18 @@ create or replace function contribute_to_goal(
 ) returns varchar as
 $$
 declare
-    category_id    varchar;
-    account_id     varchar;
-    goal_exists    boolean = false;
-    transaction_id varchar;
+    category_id      varchar;
+    account_id       varchar;
+    account_currency varchar;
+    goal_currency    varchar;
+    goal_status      varchar;
+    goal_exists      boolean = false;
+    transaction_id   varchar;
+    goal             goalpayload;
+    contribution     goalcontributionpayload;
 begin
     if p_amount <= 0 then
-        raise exception 'Amount must be greater than 0';
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     select c.id
@@ -1690,26 +2356,41 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', 'Goals';
+        raise exception 'Category % does not exist', 'Goals' using errcode = 'QW103';
     end if;
 
-    select a.id
+    select a.id, a.currency
     from accountmaster a
     where a.userid = p_user_id
       and a.accountnumber = p_account_number
     limit 1
-    into account_id;
+    into account_id, account_currency;
 
     if account_id is null then
-        raise exception 'Account does not exist';
+        raise exception 'Account does not exist' using errcode = 'QW102';
     end if;
 
     select exists(select 1 from goalmaster g where g.id = p_goal_id and g.userid = p_user_id) into goal_exists;
 
     if goal_exists = false then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
+    end if;
+
+    -- locked so that only one of concurrent contributions completes the goal
+    select g.currency, g.status
+    into goal_currency, goal_status
+    from goalmaster g
+    where g.id = p_goal_id
+        for update;
+
+    -- the goal balance converts the contribution into the currency of the goal
+    if fx_rate(account_currency, goal_currency, now()) is null then
+        raise exception 'No exchange rate from % to %', account_currency, goal_currency using errcode = 'QW109';
     end if;
 
+    -- both legs are on the account, the debit still needs the funds
+    perform ensure_sufficient_funds(account_id, p_amount);
+
     insert into transactionmaster(userid, categoryid, type, amount, description, lasteditby, accountid, referencenumber)
     values (p_user_id, category_id, 'DEBIT', p_amount, p_description, p_user_id, account_id, gen_random_transaction_ref_number());
 
@@ -1717,10 +2398,49 @@ begin
     values (p_user_id, category_id, 'CREDIT', p_amount, p_description, p_user_id, account_id, p_goal_id)
     returning id into transaction_id;
 
+    -- the credit updated the goal (see update_goal_balance)
+    select g.id, g.name, g.target, g.description, g.balance, g.userid, false, g.currency
+    into goal
+    from goalmaster g
+    where g.id = p_goal_id;
+
+    select goal.id, goal.name, goal.target, goal.description, goal.balance, goal.user_id, goal.currency, p_amount,
+           account_currency, p_account_number, transaction_id, now()
+    into contribution;
+    perform enqueue_outbox_event('goal_contributions', transaction_id, p_user_id, 'INSERT', to_jsonb(contribution));
+
+    if goal_status <> 'COMPLETED' and (select g.status from goalmaster g where g.id = p_goal_id) = 'COMPLETED' then
+        perform enqueue_outbox_event('goal_completions', goal.id, p_user_id, 'INSERT', to_jsonb(goal));
+    end if;
+
     return transaction_id;
 end;
 $$ language plpgsql;
 
+drop function if exists set_transaction_currency cascade;
+create or replace function set_transaction_currency()
+    returns trigger as
+$$
+declare
+    account_currency varchar;
+begin
+    -- a transaction is always in the currency of its account
+    select a.currency
+    from accountmaster a
+    where a.id = new.accountid
+    into account_currency;
+
+    -- the amount is not converted, so it can only move between accounts of the same currency
+    if tg_op = 'UPDATE' and account_currency is distinct from old.currency then
+        raise exception 'Cannot move transaction % from % to an account in %', old.id, old.currency, account_currency
+            using errcode = 'QW305';
+    end if;
+
+    new.currency := account_currency;
+    return new;
+end;
+$$ language plpgsql;
+
 drop function if exists notify_transactions cascade;
 create or replace function notify_transactions() returns trigger as
 $$
@@ -1749,7 +2469,12 @@ begin
                    old.referencenumber,
                    old.status,
                    old.updatedat,
-                   true
+                   true,
+                   old.currency,
+                   old.fxrate,
+                   null,
+                   null,
+                   null
             into payload;
         end if;
     else
@@ -1771,11 +2496,17 @@ begin
                    new.referencenumber,
                    new.status,
                    new.updatedat,
-                   false
+                   false,
+                   new.currency,
+                   new.fxrate,
+                   null,
+                   null,
+                   null
             into payload;
         end if;
     end if;
 
+    perform enqueue_outbox_event('transactions', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('transactions', row_to_json(payload)::text);
     return new;
 end;
@@ -1788,9 +2519,23 @@ create or replace trigger trigger_update_updated_at_column
     for each row
 execute function update_updated_at_column();
 
+drop trigger if exists trigger_update_updated_at_column on transactionsplitmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on transactionsplitmaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_set_transaction_currency on transactionmaster cascade;
+create or replace trigger trigger_set_transaction_currency
+    before insert or update of accountid
+    on transactionmaster
+    for each row
+execute function set_transaction_currency();
+
 drop trigger if exists trigger_recompute_account_balance on transactionmaster cascade;
 create or replace trigger trigger_recompute_account_balance
-    after insert
+    after insert or update of accountid, type, amount or delete
     on transactionmaster
     for each row
 execute function recompute_account_balance();
@@ -1809,6 +2554,10 @@ create or replace trigger trigger_notify_transactions
     for each row
 execute function notify_transactions();
 
+-- columns added after UserMaster was first created (create table if not exists leaves existing databases as they are)
+alter table UserMaster
+    add column if not exists BaseCurrency varchar(3) not null default 'GHS' check (BaseCurrency ~ '^[A-Z]{3}$');
+
 drop table if exists UserStats cascade;
 create table if not exists UserStats
 (
@@ -1819,8 +2568,10 @@ create table if not exists UserStats
     account_balance    numeric not null,
     account_number     varchar not null,
     total_income       numeric not null,
-    total_expense      numeric not null
+    total_expense      numeric not null,
+    base_currency      varchar not null
 );
+comment on column UserStats.account_balance is 'balances, income and expenses are converted into the base currency of the user';
 
 drop table if exists UserPayload cascade;
 create table if not exists UserPayload
@@ -1876,7 +2627,7 @@ begin
     select exists(select 1 from usermaster u where u.email = p_email)
     into exists;
     if exists then
-        raise exception 'User with email % already exists', p_email;
+        raise exception 'User with email % already exists', p_email using errcode = 'QW204';
     else
         if p_password is null or length(p_password) = 0 then
             raise notice 'Password is null, setting default password as empty string';
@@ -1928,10 +2679,10 @@ begin
                 where u.id = p_user_id
                 limit 1;
         else
-            raise exception 'Invalid password';
+            raise exception 'Invalid password' using errcode = 'QW501';
         end if;
     else
-        raise exception 'User with id % does not exist', p_user_id;
+        raise exception 'User with id % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
 end;
@@ -1954,7 +2705,7 @@ declare
 begin
     -- check if user authentication token is passed
     if p_auth_id is null or length(p_auth_id) = 0 then
-        raise exception 'Login failed. Auth token is required';
+        raise exception 'Login failed. Auth token is required' using errcode = 'QW304';
     end if;
 
     -- check if user already exists
@@ -1962,7 +2713,7 @@ begin
 
     if not user_exists then
         if p_name is null or length(p_name) = 0 then
-            raise exception 'Login failed. User display name is required';
+            raise exception 'Login failed. User display name is required' using errcode = 'QW304';
         end if;
 
         insert into usermaster(name, email, authid, phonenumber, avatarurl)
@@ -2038,7 +2789,7 @@ declare
     password_hash varchar;
 begin
     if p_password is null or length(p_password) = 0 then
-        raise exception 'Password cannot be null or empty';
+        raise exception 'Password cannot be null or empty' using errcode = 'QW304';
     else
         raise notice 'Hashing password';
         password_hash := hash_password(p_password);
@@ -2104,7 +2855,26 @@ begin
         return query
             select p_user_id, p_email, p_name, p_phone_number, p_avatar_url, false;
     else
-        raise exception 'User with id % does not exist', p_user_id;
+        raise exception 'User with id % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+end;
+$$ language plpgsql;
+
+drop function if exists update_user_base_currency cascade;
+create or replace function update_user_base_currency(
+    p_user_id varchar,
+    p_currency varchar
+) returns void as
+$$
+begin
+    perform validate_currency(p_currency);
+
+    update usermaster
+    set basecurrency = p_currency
+    where id = p_user_id;
+
+    if not found then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 end;
 $$ language plpgsql;
@@ -2137,55 +2907,1680 @@ create or replace function get_user_stats(
 as
 $$
 declare
-    user_id varchar;
+    user_id       varchar;
+    base_currency varchar;
+    missing_rate  varchar;
 begin
-    select u.id
+    select u.id, u.basecurrency
     from usermaster u
     where u.email = p_user_email
     limit 1
-    into user_id;
+    into user_id, base_currency;
 
     if user_id is null then
-        raise exception 'User % does not exist', p_user_email;
+        raise exception 'User % does not exist', p_user_email using errcode = 'QW101';
+    end if;
+
+    -- totals are reported in the base currency, so every other currency of the user needs a rate
+    select c.currency
+    from (select a.currency from accountmaster a where a.userid = user_id
+          union
+          select t.currency from transactionmaster t where t.userid = user_id) c
+    where fx_rate(c.currency, base_currency, now()) is null
+    limit 1
+    into missing_rate;
+
+    if missing_rate is not null then
+        raise exception 'No exchange rate from % to %', missing_rate, base_currency using errcode = 'QW109';
     end if;
 
     return query
-        select (select count(*) from accountmaster a where a.userid = user_id)                                             as total_accounts,
-               (select count(*) from transactionmaster t where t.userid = user_id)                                         as total_transactions,
-               (select count(*) from transactioncategorymaster c where c.userid = user_id)                                 as total_categories,
-               (select count(*) from goalmaster g where g.userid = user_id)                                                as total_goals,
-               (select coalesce(sum(a.balance), 0) from accountmaster a where a.userid = user_id)                          as total_account_balance,
-               (select a.accountnumber from accountmaster a where a.userid = user_id limit 1)                              as account_number,
-               (select coalesce(sum(t.amount), 0) from transactionmaster t where t.userid = user_id and t.type = 'CREDIT') as total_income,
-               (select coalesce(sum(t.amount), 0) from transactionmaster t where t.userid = user_id and t.type = 'DEBIT')  as total_expenses;
+        select (select count(*) from accountmaster a where a.userid = user_id)                                                                                                 as total_accounts,
+               (select count(*) from transactionmaster t where t.userid = user_id)                                                                                             as total_transactions,
+               (select count(*) from transactioncategorymaster c where c.userid = user_id)                                                                                     as total_categories,
+               (select count(*) from goalmaster g where g.userid = user_id)                                                                                                    as total_goals,
+               (select coalesce(sum(convert_amount(a.balance, a.currency, base_currency, now())), 0) from accountmaster a where a.userid = user_id)                              as total_account_balance,
+               (select a.accountnumber from accountmaster a where a.userid = user_id limit 1)                                                                                  as account_number,
+               (select coalesce(sum(convert_amount(t.amount, t.currency, base_currency, t.createdat)), 0) from transactionmaster t where t.userid = user_id and t.type = 'CREDIT') as total_income,
+               (select coalesce(sum(convert_amount(t.amount, t.currency, base_currency, t.createdat)), 0) from transactionmaster t where t.userid = user_id and t.type = 'DEBIT')  as total_expenses,
+               base_currency;
 end;
 
 $$ language plpgsql;
 
-drop trigger if exists trigger_create_account_for_new_user on usermaster cascade;
-create or replace trigger trigger_create_account_for_new_user
-    after insert
-    on usermaster
-    for each row
-execute function create_account_for_new_user();
+drop function if exists notify_users cascade;
+create or replace function notify_users()
+    returns trigger as
+$$
+declare
+    payload userpayload;
+begin
+    if tg_op = 'DELETE' then
+        select old.id, old.email, old.name, coalesce(old.phonenumber, ''), coalesce(old.avatarurl, ''), true
+        into payload;
+    else
+        select new.id, new.email, new.name, coalesce(new.phonenumber, ''), coalesce(new.avatarurl, ''), false
+        into payload;
+    end if;
 
-drop trigger if exists trigger_delete_user_data on usermaster cascade;
-create or replace trigger trigger_delete_user_data
-    after delete
-    on usermaster
-    for each row
-execute function delete_user_data();
+    -- the events of a user are keyed by the user itself
+    perform enqueue_outbox_event('users', payload.id, payload.id, tg_op, to_jsonb(payload));
+    return new;
+end;
+$$ language plpgsql;
 
-drop trigger if exists trigger_create_default_categories on usermaster cascade;
-create or replace trigger trigger_create_default_categories
-    after insert
-    on usermaster
-    for each row
-execute function create_categories_for_new_user();
+drop table if exists OutboxPayload cascade;
+create table if not exists OutboxPayload
+(
+    id           bigint      not null,
+    aggregate    varchar     not null,
+    aggregate_id varchar     not null,
+    user_id      varchar     not null,
+    operation    varchar     not null,
+    payload      jsonb       not null,
+    attempts     int         not null,
+    created_at   timestamptz not null
+);
 
-drop trigger if exists trigger_update_updated_at_column on usermaster cascade;
-create or replace trigger trigger_update_updated_at_column
-    before update
-    on usermaster
-    for each row
-execute function update_updated_at_column();
+drop function if exists enqueue_outbox_event cascade;
+create or replace function enqueue_outbox_event(
+    p_aggregate varchar,
+    p_aggregate_id varchar,
+    p_user_id varchar,
+    p_operation varchar,
+    p_payload jsonb
+) returns void as
+$$
+declare
+    event_id bigint;
+begin
+    if p_aggregate_id is null or p_user_id is null then
+        raise notice 'Skipping outbox event for % without aggregate or user id', p_aggregate;
+        return;
+    end if;
+
+    insert into outboxmaster(aggregate, aggregateid, userid, operation, payload)
+    values (p_aggregate, p_aggregate_id, p_user_id, p_operation, p_payload)
+    returning id into event_id;
+
+    -- wake up the relay (delivered on commit only)
+    perform pg_notify('outbox', event_id::text);
+end;
+$$ language plpgsql;
+
+drop function if exists list_pending_outbox_events cascade;
+create or replace function list_pending_outbox_events(
+    p_batch_size int
+)
+    returns setof outboxpayload
+as
+$$
+begin
+    if p_batch_size < 1 then
+        raise exception 'Batch size must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    return query
+        select o.id, o.aggregate, o.aggregateid, o.userid, o.operation, o.payload, o.attempts, o.createdat
+        from outboxmaster o
+        where o.publishedat is null
+          and o.parkedat is null
+        order by o.id
+        limit p_batch_size;
+end;
+$$ language plpgsql;
+
+drop function if exists mark_outbox_events_published cascade;
+create or replace function mark_outbox_events_published(
+    p_event_ids bigint[]
+) returns void as
+$$
+begin
+    update outboxmaster
+    set publishedat = now(),
+        attempts    = attempts + 1,
+        lasterror   = null
+    where id = any (p_event_ids)
+      and publishedat is null;
+end;
+$$ language plpgsql;
+
+drop function if exists record_outbox_event_failure cascade;
+create or replace function record_outbox_event_failure(
+    p_event_id bigint,
+    p_error varchar,
+    p_park boolean
+) returns void as
+$$
+begin
+    update outboxmaster
+    set attempts  = attempts + 1,
+        lasterror = p_error,
+        parkedat  = case when p_park then now() end
+    where id = p_event_id;
+end;
+$$ language plpgsql;
+
+drop function if exists requeue_parked_outbox_events cascade;
+create or replace function requeue_parked_outbox_events() returns bigint as
+$$
+declare
+    requeued_count bigint;
+begin
+    update outboxmaster
+    set parkedat = null,
+        attempts = 0
+    where parkedat is not null
+      and publishedat is null;
+    get diagnostics requeued_count = row_count;
+    return requeued_count;
+end;
+$$ language plpgsql;
+
+drop function if exists purge_published_outbox_events cascade;
+create or replace function purge_published_outbox_events(
+    p_older_than timestamptz
+) returns bigint as
+$$
+declare
+    deleted_count bigint;
+begin
+    delete
+    from outboxmaster
+    where publishedat is not null
+      and publishedat < p_older_than;
+    get diagnostics deleted_count = row_count;
+    return deleted_count;
+end;
+$$ language plpgsql;
+
+drop function if exists claim_processed_message cascade;
+create or replace function claim_processed_message(
+    p_consumer varchar,
+    p_message_key varchar,
+    p_ttl_seconds int
+) returns boolean as
+$$
+declare
+    claimed_count int;
+begin
+    if p_consumer is null or p_message_key is null then
+        raise exception 'Consumer and message key are required' using errcode = 'QW304';
+    end if;
+
+    if p_ttl_seconds < 1 then
+        raise exception 'TTL must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    -- a concurrent claim of the same message waits here until the first one commits or rolls back
+    insert into processedmessagemaster(consumer, messagekey, expiresat)
+    values (p_consumer, p_message_key, now() + make_interval(secs => p_ttl_seconds))
+    on conflict (consumer, messagekey) do update
+        set processedat = now(),
+            expiresat   = excluded.expiresat
+    where processedmessagemaster.expiresat <= now();
+    get diagnostics claimed_count = row_count;
+    return claimed_count > 0;
+end;
+$$ language plpgsql;
+
+drop function if exists is_message_processed cascade;
+create or replace function is_message_processed(
+    p_consumer varchar,
+    p_message_key varchar
+) returns boolean as
+$$
+begin
+    return exists(select 1
+                  from processedmessagemaster p
+                  where p.consumer = p_consumer
+                    and p.messagekey = p_message_key
+                    and p.expiresat > now());
+end;
+$$ language plpgsql;
+
+drop function if exists purge_processed_messages cascade;
+create or replace function purge_processed_messages() returns bigint as
+$$
+declare
+    deleted_count bigint;
+begin
+    delete
+    from processedmessagemaster
+    where expiresat <= now();
+    get diagnostics deleted_count = row_count;
+    return deleted_count;
+end;
+$$ language plpgsql;
+
+-- columns added after BudgetMaster was first created (create table if not exists leaves existing databases as they are)
+alter table BudgetMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
+drop table if exists BudgetPayload cascade;
+create table if not exists BudgetPayload
+(
+    id              varchar     not null,
+    user_id         varchar     not null,
+    category_id     varchar     not null,
+    amount          numeric     not null,
+    period          varchar     not null,
+    period_days     int         not null,
+    start_date      timestamptz not null,
+    rollover        boolean     not null,
+    period_start    timestamptz not null,
+    period_end      timestamptz not null,
+    rollover_amount numeric     not null,
+    limit_amount    numeric     not null,
+    spent           numeric     not null,
+    remaining       numeric     not null,
+    currency        varchar     not null default 'GHS'
+);
+
+drop table if exists BudgetAlertPayload cascade;
+create table if not exists BudgetAlertPayload
+(
+    id           varchar     not null,
+    budget_id    varchar     not null,
+    user_id      varchar     not null,
+    category_id  varchar     not null,
+    threshold    int         not null,
+    period_start timestamptz not null,
+    period_end   timestamptz not null,
+    limit_amount numeric     not null,
+    spent        numeric     not null,
+    created_at   timestamptz not null,
+    currency     varchar     not null default 'GHS'
+);
+
+drop function if exists budget_period_start cascade;
+create or replace function budget_period_start(
+    p_period varchar,
+    p_period_days int,
+    p_start_date date,
+    p_index int
+) returns timestamptz as
+$$
+begin
+    return case p_period
+               when 'MONTHLY' then p_start_date + make_interval(months => p_index)
+               when 'WEEKLY' then p_start_date + make_interval(days => 7 * p_index)
+               else p_start_date + make_interval(days => p_period_days * p_index)
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists budget_period_index cascade;
+create or replace function budget_period_index(
+    p_period varchar,
+    p_period_days int,
+    p_start_date date,
+    p_at timestamptz
+) returns int as
+$$
+declare
+    elapsed interval;
+begin
+    -- a budget that has not started yet reports its first period
+    if p_at::date < p_start_date then
+        return 0;
+    end if;
+
+    if p_period = 'MONTHLY' then
+        elapsed := age(p_at::date, p_start_date);
+        return (extract(year from elapsed) * 12 + extract(month from elapsed))::int;
+    elsif p_period = 'WEEKLY' then
+        return (p_at::date - p_start_date) / 7;
+    else
+        return (p_at::date - p_start_date) / p_period_days;
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists budget_status cascade;
+create or replace function budget_status(
+    p_budget budgetmaster,
+    p_at timestamptz
+) returns budgetpayload as
+$$
+declare
+    current_index    int;
+    carry            numeric := 0;
+    budget_period    record;
+    missing_currency varchar;
+    summed_from      timestamptz;
+    summed_to        timestamptz;
+    result           budgetpayload;
+begin
+    current_index := budget_period_index(p_budget.period, p_budget.perioddays, p_budget.startdate, p_at);
+    summed_from := budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate,
+                                       case when p_budget.rollover then 0 else current_index end);
+    summed_to := budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, current_index + 1);
+
+    -- spending in other currencies is converted at the rate of the day it was spent,
+    -- only the spending that is summed below needs a rate
+    select t.currency
+    from transactionmaster t
+             left join transactionsplitmaster s on s.transactionid = t.id
+    where t.userid = p_budget.userid
+      and coalesce(s.categoryid, t.categoryid) = p_budget.categoryid
+      and t.type = 'DEBIT'
+      and t.status not in ('FAILED', 'CANCELLED')
+      and t.createdat >= summed_from
+      and t.createdat < summed_to
+      and t.currency <> p_budget.currency
+      and fx_rate(t.currency, p_budget.currency, t.createdat) is null
+    limit 1
+    into missing_currency;
+
+    if missing_currency is not null then
+        raise exception 'No exchange rate from % to %', missing_currency, p_budget.currency using errcode = 'QW109';
+    end if;
+
+    -- unused amounts carry over from one period to the next, so rollover budgets replay every period since the start
+    for budget_period in
+        select s.idx,
+               budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, s.idx)     as period_start,
+               budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, s.idx + 1) as period_end
+        from generate_series(case when p_budget.rollover then 0 else current_index end, current_index) as s(idx)
+        order by s.idx
+        loop
+            select p_budget.id,
+                   p_budget.userid,
+                   p_budget.categoryid,
+                   p_budget.amount,
+                   p_budget.period,
+                   coalesce(p_budget.perioddays, 0),
+                   p_budget.startdate::timestamptz,
+                   p_budget.rollover,
+                   budget_period.period_start,
+                   budget_period.period_end,
+                   carry,
+                   p_budget.amount + carry,
+                   coalesce(sum(convert_amount(coalesce(s.amount, t.amount), t.currency, p_budget.currency, t.createdat)), 0),
+                   p_budget.amount + carry -
+                   coalesce(sum(convert_amount(coalesce(s.amount, t.amount), t.currency, p_budget.currency, t.createdat)), 0),
+                   p_budget.currency
+            into result
+            from transactionmaster t
+                     left join transactionsplitmaster s on s.transactionid = t.id
+            where t.userid = p_budget.userid
+              and coalesce(s.categoryid, t.categoryid) = p_budget.categoryid
+              and t.type = 'DEBIT'
+              and t.status not in ('FAILED', 'CANCELLED')
+              and t.createdat >= budget_period.period_start
+              and t.createdat < budget_period.period_end;
+
+            carry := greatest(result.remaining, 0);
+        end loop;
+
+    return result;
+end;
+$$ language plpgsql stable;
+
+drop function if exists create_budget cascade;
+create or replace function create_budget(
+    p_user_id varchar,
+    p_category_id varchar,
+    p_amount numeric,
+    p_period varchar,
+    p_period_days int,
+    p_start_date timestamp,
+    p_rollover boolean,
+    p_currency varchar
+) returns varchar as
+$$
+declare
+    budget_id varchar;
+begin
+    if p_amount <= 0 then
+        raise exception 'Budget amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_period not in ('WEEKLY', 'MONTHLY', 'CUSTOM') then
+        raise exception 'Budget period % is not supported', p_period using errcode = 'QW304';
+    end if;
+
+    if p_period = 'CUSTOM' and coalesce(p_period_days, 0) < 1 then
+        raise exception 'Custom budget periods must be at least 1 day long' using errcode = 'QW304';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    if exists(select 1 from budgetmaster b where b.userid = p_user_id and b.categoryid = p_category_id) then
+        raise exception 'Category % already has a budget', p_category_id using errcode = 'QW205';
+    end if;
+
+    -- budgets are in the base currency of the user unless told otherwise
+    if p_currency is null or p_currency = '' then
+        select u.basecurrency
+        from usermaster u
+        where u.id = p_user_id
+        into p_currency;
+    end if;
+    perform validate_currency(p_currency);
+
+    insert into budgetmaster(userid, categoryid, amount, currency, period, perioddays, startdate, rollover)
+    values (p_user_id, p_category_id, p_amount, p_currency, p_period,
+            case when p_period = 'CUSTOM' then p_period_days end,
+            coalesce(p_start_date::date, date_trunc('month', now())::date),
+            coalesce(p_rollover, false))
+    returning id
+        into budget_id;
+    return budget_id;
+end;
+$$ language plpgsql;
+
+drop function if exists update_budget cascade;
+create or replace function update_budget(
+    p_budget_id varchar,
+    p_user_id varchar,
+    p_amount numeric,
+    p_period varchar,
+    p_period_days int,
+    p_start_date timestamp,
+    p_rollover boolean
+) returns void as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    if p_amount <= 0 then
+        raise exception 'Budget amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_period not in ('WEEKLY', 'MONTHLY', 'CUSTOM') then
+        raise exception 'Budget period % is not supported', p_period using errcode = 'QW304';
+    end if;
+
+    if p_period = 'CUSTOM' and coalesce(p_period_days, 0) < 1 then
+        raise exception 'Custom budget periods must be at least 1 day long' using errcode = 'QW304';
+    end if;
+
+    update budgetmaster
+    set amount     = p_amount,
+        period     = p_period,
+        perioddays = case when p_period = 'CUSTOM' then p_period_days end,
+        startdate  = coalesce(p_start_date::date, startdate),
+        rollover   = coalesce(p_rollover, rollover)
+    where id = p_budget_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_budget cascade;
+create or replace function delete_budget(
+    p_budget_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    delete
+    from budgetmaster
+    where id = p_budget_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists get_budget cascade;
+create or replace function get_budget(
+    p_budget_id varchar,
+    p_user_id varchar,
+    p_at timestamptz
+)
+    returns setof budgetpayload
+as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    return query
+        select s.*
+        from budgetmaster b
+                 cross join lateral budget_status(b, coalesce(p_at, now())) s
+        where b.id = p_budget_id
+          and b.userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_budgets_for_user cascade;
+create or replace function list_budgets_for_user(
+    p_user_id varchar,
+    p_at timestamptz
+)
+    returns setof budgetpayload
+as
+$$
+begin
+    return query
+        select s.*
+        from budgetmaster b
+                 cross join lateral budget_status(b, coalesce(p_at, now())) s
+        where b.userid = p_user_id
+        order by b.createdat;
+end;
+$$ language plpgsql;
+
+drop function if exists alert_budget_thresholds cascade;
+create or replace function alert_budget_thresholds(
+    p_user_id varchar,
+    p_category_id varchar,
+    p_at timestamptz
+) returns void as
+$$
+declare
+    budget            budgetmaster;
+    budget_state      budgetpayload;
+    threshold_percent int;
+begin
+    select *
+    into budget
+    from budgetmaster b
+    where b.userid = p_user_id
+      and b.categoryid = p_category_id;
+
+    if budget.id is null then
+        return;
+    end if;
+
+    -- a missing exchange rate must not fail the transaction that triggered the check
+    begin
+        budget_state := budget_status(budget, p_at);
+    exception
+        when sqlstate 'QW109' then
+            raise notice 'Budget % skipped: %', budget.id, sqlerrm;
+            return;
+    end;
+
+    -- each threshold is alerted once per period, the unique key of budgetalertmaster drops repeats
+    foreach threshold_percent in array array [80, 100]
+        loop
+            if budget_state.spent * 100 >= budget_state.limit_amount * threshold_percent then
+                insert into budgetalertmaster(budgetid, userid, periodstart, periodend, threshold, limitamount, spent)
+                values (budget.id, budget.userid, budget_state.period_start, budget_state.period_end, threshold_percent,
+                        budget_state.limit_amount, budget_state.spent)
+                on conflict (budgetid, periodstart, threshold) do nothing;
+            end if;
+        end loop;
+end;
+$$ language plpgsql;
+
+drop function if exists check_budget_thresholds cascade;
+create or replace function check_budget_thresholds()
+    returns trigger as
+$$
+begin
+    if new.type = 'DEBIT' then
+        perform alert_budget_thresholds(new.userid, new.categoryid, new.createdat);
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+drop function if exists check_split_budget_thresholds cascade;
+create or replace function check_split_budget_thresholds()
+    returns trigger as
+$$
+declare
+    parent transactionmaster;
+begin
+    select *
+    into parent
+    from transactionmaster t
+    where t.id = new.transactionid;
+
+    -- split lines count against the budget of their own category
+    if parent.type = 'DEBIT' then
+        perform alert_budget_thresholds(new.userid, new.categoryid, parent.createdat);
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+drop function if exists notify_budget_alerts cascade;
+create or replace function notify_budget_alerts()
+    returns trigger as
+$$
+declare
+    payload budgetalertpayload;
+begin
+    select new.id,
+           new.budgetid,
+           new.userid,
+           b.categoryid,
+           new.threshold,
+           new.periodstart,
+           new.periodend,
+           new.limitamount,
+           new.spent,
+           new.createdat,
+           b.currency
+    into payload
+    from budgetmaster b
+    where b.id = new.budgetid;
+
+    perform enqueue_outbox_event('budget_alerts', payload.id, payload.user_id, tg_op, to_jsonb(payload));
+    perform pg_notify('budget_alerts', row_to_json(payload)::text);
+    return new;
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on budgetmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on budgetmaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_check_budget_thresholds on transactionmaster cascade;
+create or replace trigger trigger_check_budget_thresholds
+    after insert or update
+    on transactionmaster
+    for each row
+execute function check_budget_thresholds();
+
+drop trigger if exists trigger_check_split_budget_thresholds on transactionsplitmaster cascade;
+create or replace trigger trigger_check_split_budget_thresholds
+    after insert or update
+    on transactionsplitmaster
+    for each row
+execute function check_split_budget_thresholds();
+
+drop trigger if exists trigger_notify_budget_alerts on budgetalertmaster cascade;
+create or replace trigger trigger_notify_budget_alerts
+    after insert
+    on budgetalertmaster
+    for each row
+execute function notify_budget_alerts();
+
+drop table if exists RecurringTransactionPayload cascade;
+create table if not exists RecurringTransactionPayload
+(
+    id             varchar     not null,
+    user_id        varchar     not null,
+    account_number varchar     not null,
+    category_id    varchar     not null,
+    type           varchar     not null,
+    amount         numeric     not null,
+    description    text        not null,
+    frequency      varchar     not null,
+    repeat_every   int         not null,
+    day_of_week    int         not null,
+    day_of_month   int         not null,
+    business_days  boolean     not null,
+    start_date     timestamptz not null,
+    end_date       timestamptz,
+    next_run_at    timestamptz,
+    status         varchar     not null
+);
+
+drop table if exists RecurringOccurrencePayload cascade;
+create table if not exists RecurringOccurrencePayload
+(
+    id              varchar     not null,
+    recurring_id    varchar     not null,
+    user_id         varchar     not null,
+    occurrence_date timestamptz not null,
+    status          varchar     not null,
+    transaction_id  varchar     not null,
+    error           text        not null,
+    created_at      timestamptz not null
+);
+
+drop function if exists recurring_next_date cascade;
+create or replace function recurring_next_date(
+    p_recurring recurringtransactionmaster,
+    p_from date
+) returns date as
+$$
+declare
+    from_date    date := greatest(p_from, p_recurring.startdate);
+    first_date   date;
+    next_date    date;
+    month_index  int;
+    month_start  date;
+    month_end    date;
+    day_of_month int  := coalesce(p_recurring.dayofmonth, extract(day from p_recurring.startdate)::int);
+begin
+    if p_recurring.frequency = 'DAILY' then
+        next_date := p_recurring.startdate +
+                     ceil((from_date - p_recurring.startdate)::numeric / p_recurring.repeatevery)::int * p_recurring.repeatevery;
+    elsif p_recurring.frequency = 'WEEKLY' then
+        -- the first occurrence is the first matching weekday on or after the start date
+        first_date := p_recurring.startdate +
+                      (coalesce(p_recurring.dayofweek, extract(isodow from p_recurring.startdate)::int) -
+                       extract(isodow from p_recurring.startdate)::int + 7) % 7;
+        next_date := first_date +
+                     greatest(ceil((from_date - first_date)::numeric / (7 * p_recurring.repeatevery))::int, 0) * 7 * p_recurring.repeatevery;
+    else
+        -- months are counted from the month of the start date, the day is clamped to the length of the month
+        month_index := ((extract(year from from_date) - extract(year from p_recurring.startdate)) * 12 +
+                        extract(month from from_date) - extract(month from p_recurring.startdate))::int;
+        month_index := month_index - month_index % p_recurring.repeatevery;
+        loop
+            month_start := (date_trunc('month', p_recurring.startdate) + make_interval(months => month_index))::date;
+            month_end := (month_start + interval '1 month' - interval '1 day')::date;
+            if day_of_month = -1 then
+                next_date := month_end;
+            else
+                next_date := least(month_start + day_of_month - 1, month_end);
+            end if;
+            exit when next_date >= from_date;
+            month_index := month_index + p_recurring.repeatevery;
+        end loop;
+    end if;
+
+    if p_recurring.enddate is not null and next_date > p_recurring.enddate then
+        return null;
+    end if;
+    return next_date;
+end;
+$$ language plpgsql stable;
+
+drop function if exists recurring_due_date cascade;
+create or replace function recurring_due_date(
+    p_recurring recurringtransactionmaster,
+    p_date date
+) returns date as
+$$
+begin
+    -- business-day schedules move weekend occurrences to the friday before
+    if p_date is null or not p_recurring.businessdays then
+        return p_date;
+    end if;
+
+    return case extract(isodow from p_date)
+               when 6 then p_date - 1
+               when 7 then p_date - 2
+               else p_date
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists validate_recurring_schedule cascade;
+create or replace function validate_recurring_schedule(
+    p_type varchar,
+    p_amount numeric,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_start_date date,
+    p_end_date date
+) returns void as
+$$
+begin
+    if p_amount <= 0 then
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_type not in ('CREDIT', 'DEBIT') then
+        raise exception 'Transaction type % is not supported', p_type using errcode = 'QW304';
+    end if;
+
+    if p_frequency not in ('DAILY', 'WEEKLY', 'MONTHLY') then
+        raise exception 'Frequency % is not supported', p_frequency using errcode = 'QW304';
+    end if;
+
+    if p_repeat_every < 1 then
+        raise exception 'Repeat interval must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    if p_day_of_week is not null and p_day_of_week not between 1 and 7 then
+        raise exception 'Day of week must be between 1 (monday) and 7 (sunday)' using errcode = 'QW304';
+    end if;
+
+    if p_day_of_month is not null and p_day_of_month <> -1 and p_day_of_month not between 1 and 31 then
+        raise exception 'Day of month must be between 1 and 31, or -1 for the last day' using errcode = 'QW304';
+    end if;
+
+    if p_end_date is not null and p_end_date < p_start_date then
+        raise exception 'End date must not be before the start date' using errcode = 'QW304';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists create_recurring_transaction cascade;
+create or replace function create_recurring_transaction(
+    p_user_id varchar,
+    p_account_number varchar,
+    p_category_id varchar,
+    p_type varchar,
+    p_amount numeric,
+    p_description varchar,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_business_days boolean,
+    p_start_date timestamp,
+    p_end_date timestamp
+) returns varchar as
+$$
+declare
+    account_id varchar;
+    recurring  recurringtransactionmaster;
+begin
+    perform validate_recurring_schedule(p_type, p_amount, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+                                        nullif(p_day_of_month, 0), coalesce(p_start_date::date, current_date), p_end_date::date);
+
+    select a.id
+    into account_id
+    from accountmaster a
+    where a.accountnumber = p_account_number
+      and a.userid = p_user_id;
+
+    if account_id is null then
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    insert into recurringtransactionmaster(userid, accountid, categoryid, type, amount, description, frequency, repeatevery, dayofweek,
+                                           dayofmonth, businessdays, startdate, enddate)
+    values (p_user_id, account_id, p_category_id, p_type, p_amount, p_description, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+            nullif(p_day_of_month, 0), coalesce(p_business_days, false), coalesce(p_start_date::date, current_date), p_end_date::date)
+    returning *
+        into recurring;
+
+    update recurringtransactionmaster
+    set nextrunat = recurring_next_date(recurring, recurring.startdate)
+    where id = recurring.id;
+    return recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists update_recurring_transaction cascade;
+create or replace function update_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_account_number varchar,
+    p_category_id varchar,
+    p_type varchar,
+    p_amount numeric,
+    p_description varchar,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_business_days boolean,
+    p_start_date timestamp,
+    p_end_date timestamp
+) returns void as
+$$
+declare
+    account_id varchar;
+    recurring  recurringtransactionmaster;
+    next_date  date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id
+        for update;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    perform validate_recurring_schedule(p_type, p_amount, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+                                        nullif(p_day_of_month, 0), coalesce(p_start_date::date, recurring.startdate),
+                                        p_end_date::date);
+
+    select a.id
+    into account_id
+    from accountmaster a
+    where a.accountnumber = p_account_number
+      and a.userid = p_user_id;
+
+    if account_id is null then
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    recurring.accountid := account_id;
+    recurring.categoryid := p_category_id;
+    recurring.type := p_type;
+    recurring.amount := p_amount;
+    recurring.description := p_description;
+    recurring.frequency := p_frequency;
+    recurring.repeatevery := p_repeat_every;
+    recurring.dayofweek := nullif(p_day_of_week, 0);
+    recurring.dayofmonth := nullif(p_day_of_month, 0);
+    recurring.businessdays := coalesce(p_business_days, recurring.businessdays);
+    recurring.startdate := coalesce(p_start_date::date, recurring.startdate);
+    recurring.enddate := p_end_date::date;
+
+    -- edits only apply to future occurrences, the ones already materialized keep their transactions
+    next_date := recurring_next_date(recurring, current_date);
+
+    update recurringtransactionmaster
+    set accountid    = recurring.accountid,
+        categoryid   = recurring.categoryid,
+        type         = recurring.type,
+        amount       = recurring.amount,
+        description  = recurring.description,
+        frequency    = recurring.frequency,
+        repeatevery  = recurring.repeatevery,
+        dayofweek    = recurring.dayofweek,
+        dayofmonth   = recurring.dayofmonth,
+        businessdays = recurring.businessdays,
+        startdate    = recurring.startdate,
+        enddate      = recurring.enddate,
+        nextrunat    = next_date,
+        status       = case
+                           when recurring.status = 'PAUSED' then 'PAUSED'
+                           when next_date is null then 'ENDED'
+                           else 'ACTIVE'
+            end
+    where id = recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_recurring_transaction cascade;
+create or replace function delete_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from recurringtransactionmaster r where r.id = p_recurring_id and r.userid = p_user_id) then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    delete
+    from recurringtransactionmaster
+    where id = p_recurring_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists pause_recurring_transaction cascade;
+create or replace function pause_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from recurringtransactionmaster r where r.id = p_recurring_id and r.userid = p_user_id) then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    update recurringtransactionmaster
+    set status = 'PAUSED'
+    where id = p_recurring_id
+      and userid = p_user_id
+      and status = 'ACTIVE';
+end;
+$$ language plpgsql;
+
+drop function if exists resume_recurring_transaction cascade;
+create or replace function resume_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    recurring recurringtransactionmaster;
+    next_date date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id
+        for update;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    if recurring.status <> 'PAUSED' then
+        return;
+    end if;
+
+    -- occurrences missed while paused are not caught up on
+    next_date := recurring_next_date(recurring, current_date);
+
+    update recurringtransactionmaster
+    set nextrunat = next_date,
+        status    = case when next_date is null then 'ENDED' else 'ACTIVE' end
+    where id = recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists skip_recurring_occurrence cascade;
+create or replace function skip_recurring_occurrence(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_occurrence_date timestamp
+) returns void as
+$$
+declare
+    recurring       recurringtransactionmaster;
+    occurrence_date date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    -- without a date, the next occurrence is skipped
+    occurrence_date := coalesce(p_occurrence_date::date, recurring_due_date(recurring, recurring.nextrunat));
+    if occurrence_date is null or occurrence_date < current_date then
+        raise exception 'Only future occurrences can be skipped' using errcode = 'QW304';
+    end if;
+
+    if exists(select 1
+              from recurringoccurrencemaster o
+              where o.recurringid = recurring.id
+                and o.occurrencedate = occurrence_date
+                and o.status <> 'SKIPPED') then
+        raise exception 'The occurrence on % was already processed', occurrence_date using errcode = 'QW304';
+    end if;
+
+    insert into recurringoccurrencemaster(recurringid, userid, occurrencedate, status)
+    values (recurring.id, recurring.userid, occurrence_date, 'SKIPPED')
+    on conflict (recurringid, occurrencedate) do nothing;
+end;
+$$ language plpgsql;
+
+drop function if exists list_recurring_transactions_for_user cascade;
+create or replace function list_recurring_transactions_for_user(
+    p_user_id varchar
+)
+    returns setof recurringtransactionpayload
+as
+$$
+begin
+    return query
+        select r.id,
+               r.userid,
+               a.accountnumber,
+               r.categoryid,
+               r.type,
+               r.amount,
+               r.description,
+               r.frequency,
+               r.repeatevery,
+               coalesce(r.dayofweek, 0),
+               coalesce(r.dayofmonth, 0),
+               r.businessdays,
+               r.startdate::timestamptz,
+               r.enddate::timestamptz,
+               recurring_due_date(r, r.nextrunat)::timestamptz,
+               r.status
+        from recurringtransactionmaster r
+                 join accountmaster a on a.id = r.accountid
+        where r.userid = p_user_id
+        order by r.createdat;
+end;
+$$ language plpgsql;
+
+drop function if exists list_recurring_occurrences cascade;
+create or replace function list_recurring_occurrences(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_page_number int,
+    p_page_size int
+)
+    returns setof recurringoccurrencepayload
+as
+$$
+begin
+    if p_page_number < 1 then
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
+    end if;
+
+    if p_page_size < 1 then
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
+    end if;
+
+    return query
+        select o.id,
+               o.recurringid,
+               o.userid,
+               o.occurrencedate::timestamptz,
+               o.status,
+               coalesce(o.transactionid, ''),
+               coalesce(o.error, ''),
+               o.createdat
+        from recurringoccurrencemaster o
+        where o.recurringid = p_recurring_id
+          and o.userid = p_user_id
+        order by o.occurrencedate desc
+        limit p_page_size offset (p_page_number - 1) * p_page_size;
+end;
+$$ language plpgsql;
+
+drop function if exists materialize_recurring_transactions cascade;
+create or replace function materialize_recurring_transactions(
+    p_now timestamptz,
+    p_batch_size int,
+    p_max_catch_up int
+)
+    returns setof recurringoccurrencepayload
+as
+$$
+declare
+    today           date := p_now::date;
+    recurring       recurringtransactionmaster;
+    account_number  varchar;
+    occurrence_date date;
+    occurrence      recurringoccurrencemaster;
+    caught_up       int;
+begin
+    if p_batch_size < 1 then
+        raise exception 'Batch size must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    if p_max_catch_up < 1 then
+        raise exception 'Catch-up limit must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    -- skip locked lets several schedulers share the due templates, each one is materialized by a single transaction
+    for recurring in
+        select *
+        from recurringtransactionmaster r
+        where r.status = 'ACTIVE'
+          and r.nextrunat is not null
+          and recurring_due_date(r, r.nextrunat) <= today
+        order by r.nextrunat
+        limit p_batch_size for update skip locked
+        loop
+            select a.accountnumber into account_number from accountmaster a where a.id = recurring.accountid;
+            caught_up := 0;
+
+            -- catch up on the occurrences missed while no scheduler was running, at most p_max_catch_up per call:
+            -- a template that is still due afterwards continues from nextrunat on a later call
+            while recurring.nextrunat is not null
+                and recurring_due_date(recurring, recurring.nextrunat) <= today
+                and caught_up < p_max_catch_up
+                loop
+                    caught_up := caught_up + 1;
+                    occurrence_date := recurring_due_date(recurring, recurring.nextrunat);
+                    occurrence := null;
+
+                    -- skipped occurrences (and business days moved onto the same date) already have a row
+                    insert into recurringoccurrencemaster(recurringid, userid, occurrencedate, status)
+                    values (recurring.id, recurring.userid, occurrence_date, 'PENDING')
+                    on conflict (recurringid, occurrencedate) do nothing
+                    returning * into occurrence;
+
+                    if occurrence.id is not null then
+                        begin
+                            occurrence.transactionid := create_transaction(recurring.userid, account_number, recurring.categoryid,
+                                                                           recurring.type, recurring.amount, recurring.description,
+                                                                           case
+                                                                               when occurrence_date < today then occurrence_date::timestamptz
+                                                                               else p_now end);
+                            occurrence.status := 'COMPLETED';
+                        exception
+                            -- QW000 matches every QWxxx code. Business errors (e.g. insufficient funds) are recorded as failed and
+                            -- not retried; anything else (serialization failures, deadlocks, timeouts) aborts the whole run so it is retried
+                            when sqlstate 'QW000' or check_violation then
+                                occurrence.status := 'FAILED';
+                                occurrence.error := sqlerrm;
+                        end;
+
+                        update recurringoccurrencemaster
+                        set status        = occurrence.status,
+                            transactionid = occurrence.transactionid,
+                            error         = occurrence.error
+                        where id = occurrence.id;
+
+                        return next (occurrence.id, occurrence.recurringid, occurrence.userid, occurrence.occurrencedate::timestamptz,
+                                     occurrence.status, coalesce(occurrence.transactionid, ''), coalesce(occurrence.error, ''),
+                                     occurrence.createdat)::recurringoccurrencepayload;
+                    end if;
+
+                    recurring.nextrunat := recurring_next_date(recurring, recurring.nextrunat + 1);
+                end loop;
+
+            update recurringtransactionmaster
+            set nextrunat = recurring.nextrunat,
+                status    = case when recurring.nextrunat is null then 'ENDED' else status end
+            where id = recurring.id;
+        end loop;
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on recurringtransactionmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on recurringtransactionmaster
+    for each row
+execute function update_updated_at_column();
+
+drop table if exists FxRatePayload cascade;
+create table if not exists FxRatePayload
+(
+    base_currency  varchar     not null,
+    quote_currency varchar     not null,
+    rate           numeric     not null,
+    source         varchar     not null,
+    as_of          timestamptz not null
+);
+
+drop function if exists validate_currency cascade;
+create or replace function validate_currency(
+    p_currency varchar
+) returns void as
+$$
+begin
+    if p_currency is null or p_currency !~ '^[A-Z]{3}$' then
+        raise exception 'Currency % is not a 3-letter ISO 4217 code', p_currency using errcode = 'QW305';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists currency_scale cascade;
+create or replace function currency_scale(
+    p_currency varchar
+) returns int as
+$$
+begin
+    -- ISO 4217 minor units, capped at the 2 decimal places kept by amount columns
+    return case
+               when p_currency in ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'UYI', 'VND',
+                                   'VUV', 'XAF', 'XOF', 'XPF') then 0
+               else 2
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists find_fx_rate cascade;
+create or replace function find_fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns setof fxratepayload as
+$$
+begin
+    if p_from_currency = p_to_currency then
+        return query select p_from_currency, p_to_currency, 1::numeric, 'identity'::varchar, p_at;
+        return;
+    end if;
+
+    -- the latest rate known at the time, stored in either direction.
+    -- amounts older than the first known rate are converted with that rate.
+    return query
+        select p_from_currency,
+               p_to_currency,
+               case when r.basecurrency = p_from_currency then r.rate else round(1 / r.rate, 8) end,
+               r.source,
+               r.asof
+        from fxratemaster r
+        where (r.basecurrency = p_from_currency and r.quotecurrency = p_to_currency)
+           or (r.basecurrency = p_to_currency and r.quotecurrency = p_from_currency)
+        order by r.asof <= p_at desc,
+                 case when r.asof <= p_at then r.asof end desc,
+                 r.asof,
+                 r.basecurrency = p_from_currency desc
+        limit 1;
+end;
+$$ language plpgsql stable;
+
+drop function if exists fx_rate cascade;
+create or replace function fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns numeric as
+$$
+begin
+    -- null when the pair has never been quoted
+    return (select f.rate from find_fx_rate(p_from_currency, p_to_currency, p_at) f);
+end;
+$$ language plpgsql stable;
+
+drop function if exists convert_amount cascade;
+create or replace function convert_amount(
+    p_amount numeric,
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns numeric as
+$$
+begin
+    -- null when there is no rate for the pair
+    return round(p_amount * fx_rate(p_from_currency, p_to_currency, p_at), currency_scale(p_to_currency));
+end;
+$$ language plpgsql stable;
+
+drop function if exists save_fx_rate cascade;
+create or replace function save_fx_rate(
+    p_base_currency varchar,
+    p_quote_currency varchar,
+    p_rate numeric,
+    p_source varchar,
+    p_as_of timestamptz
+) returns setof fxratepayload as
+$$
+begin
+    perform validate_currency(p_base_currency);
+    perform validate_currency(p_quote_currency);
+
+    if p_base_currency = p_quote_currency then
+        raise exception 'Cannot quote % against itself', p_base_currency using errcode = 'QW305';
+    end if;
+
+    if p_rate is null or p_rate <= 0 then
+        raise exception 'Rate must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_as_of is null then
+        p_as_of := now();
+    end if;
+
+    return query
+        insert into fxratemaster(basecurrency, quotecurrency, rate, source, asof)
+            values (p_base_currency, p_quote_currency, p_rate, coalesce(nullif(p_source, ''), 'manual'), p_as_of)
+            on conflict (basecurrency, quotecurrency, asof) do update
+                set rate = excluded.rate,
+                    source = excluded.source
+            returning basecurrency, quotecurrency, rate, source, asof;
+end;
+$$ language plpgsql;
+
+drop function if exists get_fx_rate cascade;
+create or replace function get_fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns setof fxratepayload as
+$$
+declare
+    payload fxratepayload;
+begin
+    perform validate_currency(p_from_currency);
+    perform validate_currency(p_to_currency);
+
+    if p_at is null then
+        p_at := now();
+    end if;
+
+    select *
+    from find_fx_rate(p_from_currency, p_to_currency, p_at)
+    into payload;
+
+    if payload.rate is null then
+        raise exception 'No exchange rate from % to %', p_from_currency, p_to_currency using errcode = 'QW109';
+    end if;
+    return next payload;
+end;
+$$ language plpgsql stable;
+
+drop function if exists list_fx_rates cascade;
+create or replace function list_fx_rates()
+    returns setof fxratepayload
+as
+$$
+begin
+    -- the latest rate of every pair
+    return query
+        select distinct on (r.basecurrency, r.quotecurrency) r.basecurrency, r.quotecurrency, r.rate, r.source, r.asof
+        from fxratemaster r
+        order by r.basecurrency, r.quotecurrency, r.asof desc;
+end;
+$$ language plpgsql stable;
+
+drop table if exists TagPayload cascade;
+create table if not exists TagPayload
+(
+    id                varchar     not null,
+    user_id           varchar     not null,
+    name              varchar     not null,
+    transaction_count bigint      not null,
+    updated_at        timestamptz not null
+);
+
+drop function if exists validate_tag cascade;
+create or replace function validate_tag(
+    p_name varchar
+) returns void as
+$$
+begin
+    if p_name is null or btrim(p_name) = '' or length(btrim(p_name)) > 50 then
+        raise exception 'Tag % must be 1 to 50 characters', p_name using errcode = 'QW307';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists transaction_tags cascade;
+create or replace function transaction_tags(
+    p_transaction_id varchar
+) returns varchar[] as
+$$
+begin
+    return array(select g.name
+                 from transactiontagmaster tt
+                          join tagmaster g on g.id = tt.tagid
+                 where tt.transactionid = p_transaction_id
+                 order by lower(g.name));
+end;
+$$ language plpgsql stable;
+
+drop function if exists tagged_transactions cascade;
+create or replace function tagged_transactions(
+    p_user_id varchar,
+    p_tags varchar[],
+    p_match_all boolean
+) returns setof varchar as
+$$
+-- resolves the tags once through idx_tag_user_name and walks idx_transaction_tag_tag_id,
+-- so the lists can filter with a semi join instead of a lookup per transaction
+select tt.transactionid
+from tagmaster g
+         join transactiontagmaster tt on tt.tagid = g.id
+where g.userid = p_user_id
+  and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name))
+group by tt.transactionid
+having not p_match_all
+    or count(*) = (select count(distinct lower(btrim(w.name))) from unnest(p_tags) as w(name));
+$$ language sql stable;
+
+drop function if exists tag_transaction cascade;
+create or replace function tag_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_tags varchar[]
+) returns void as
+$$
+declare
+    transaction_exists bool = false;
+    tag_name           varchar;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    if p_tags is null or cardinality(p_tags) = 0 then
+        raise exception 'At least one tag is required' using errcode = 'QW304';
+    end if;
+
+    foreach tag_name in array p_tags
+        loop
+            perform validate_tag(tag_name);
+        end loop;
+
+    -- tags are created on first use, an existing tag keeps the case it was created with
+    insert into tagmaster(userid, name)
+    select distinct on (lower(btrim(w.name))) p_user_id, btrim(w.name)
+    from unnest(p_tags) as w(name)
+    on conflict (userid, lower(name)) do nothing;
+
+    insert into transactiontagmaster(transactionid, tagid, userid)
+    select p_transaction_id, g.id, p_user_id
+    from tagmaster g
+    where g.userid = p_user_id
+      and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name))
+    on conflict (transactionid, tagid) do nothing;
+end;
+$$ language plpgsql;
+
+drop function if exists untag_transaction cascade;
+create or replace function untag_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_tags varchar[]
+) returns void as
+$$
+declare
+    transaction_exists bool = false;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    -- the tags themselves are kept, even when no transaction uses them anymore
+    delete
+    from transactiontagmaster tt
+        using tagmaster g
+    where tt.tagid = g.id
+      and tt.transactionid = p_transaction_id
+      and g.userid = p_user_id
+      and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name));
+end;
+$$ language plpgsql;
+
+drop function if exists rename_tag cascade;
+create or replace function rename_tag(
+    p_tag_id varchar,
+    p_user_id varchar,
+    p_name varchar
+) returns setof tagpayload as
+$$
+declare
+    tag_exists bool = false;
+begin
+    select exists(select 1 from tagmaster where id = p_tag_id and userid = p_user_id) into tag_exists;
+    if not tag_exists then
+        raise exception 'Tag % does not exist', p_tag_id using errcode = 'QW110';
+    end if;
+
+    perform validate_tag(p_name);
+
+    if exists(select 1
+              from tagmaster g
+              where g.userid = p_user_id
+                and g.id <> p_tag_id
+                and lower(g.name) = lower(btrim(p_name))) then
+        raise exception 'A tag named % already exists', btrim(p_name) using errcode = 'QW206';
+    end if;
+
+    update tagmaster
+    set name = btrim(p_name)
+    where id = p_tag_id;
+
+    return query
+        select *
+        from list_tags_for_user(p_user_id) t
+        where t.id = p_tag_id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_tag cascade;
+create or replace function delete_tag(
+    p_tag_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    tag_exists bool = false;
+begin
+    select exists(select 1 from tagmaster where id = p_tag_id and userid = p_user_id) into tag_exists;
+    if not tag_exists then
+        raise exception 'Tag % does not exist', p_tag_id using errcode = 'QW110';
+    end if;
+
+    -- removes the tag from every transaction
+    delete
+    from tagmaster
+    where id = p_tag_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_tags_for_user cascade;
+create or replace function list_tags_for_user(
+    p_user_id varchar
+)
+    returns setof tagpayload
+as
+$$
+begin
+    return query
+        select g.id, g.userid, g.name, count(tt.transactionid), g.updatedat
+        from tagmaster g
+                 left join transactiontagmaster tt on tt.tagid = g.id
+        where g.userid = p_user_id
+        group by g.id
+        order by lower(g.name);
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on tagmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on tagmaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_create_account_for_new_user on usermaster cascade;
+create or replace trigger trigger_create_account_for_new_user
+    after insert
+    on usermaster
+    for each row
+execute function create_account_for_new_user();
+
+drop trigger if exists trigger_delete_user_data on usermaster cascade;
+create or replace trigger trigger_delete_user_data
+    after delete
+    on usermaster
+    for each row
+execute function delete_user_data();
+
+drop trigger if exists trigger_create_default_categories on usermaster cascade;
+create or replace trigger trigger_create_default_categories
+    after insert
+    on usermaster
+    for each row
+execute function create_categories_for_new_user();
+
+drop trigger if exists trigger_update_updated_at_column on usermaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on usermaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_notify_users on usermaster cascade;
+create or replace trigger trigger_notify_users
+    after insert or update of name, email, phonenumber, avatarurl or delete
+    on usermaster
+    for each row
+execute function notify_users();
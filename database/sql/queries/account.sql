@@ -3,7 +3,8 @@ select
 from create_new_account(
         @user_id,
         @account_name,
-        @initial_balance
+        @initial_balance,
+        @currency
      );
 
 -- name: DeleteAccount :exec
@@ -0,0 +1,36 @@
+-- name: CreateBudget :one
+select create_budget(
+               @user_id::varchar,
+               @category_id::varchar,
+               @amount::numeric,
+               @period::varchar,
+               @period_days::int,
+               @start_date::timestamp,
+               @rollover::boolean,
+               @currency::varchar
+       )::varchar as budget_id;
+
+-- name: UpdateBudget :exec
+select update_budget(
+               @budget_id::varchar,
+               @user_id::varchar,
+               @amount::numeric,
+               @period::varchar,
+               @period_days::int,
+               @start_date::timestamp,
+               @rollover::boolean
+       );
+
+-- name: DeleteBudget :exec
+select delete_budget(
+               @budget_id::varchar,
+               @user_id::varchar
+       );
+
+-- name: GetBudget :one
+select *
+from get_budget(@budget_id::varchar, @user_id::varchar, @at::timestamptz);
+
+-- name: ListUserBudgets :many
+select *
+from list_budgets_for_user(@user_id::varchar, @at::timestamptz);
@@ -0,0 +1,17 @@
+-- name: SaveFxRate :one
+select *
+from save_fx_rate(
+        @base_currency::varchar,
+        @quote_currency::varchar,
+        @rate::numeric,
+        @source::varchar,
+        @as_of::timestamptz
+     );
+
+-- name: GetFxRate :one
+select *
+from get_fx_rate(@from_currency::varchar, @to_currency::varchar, @at::timestamptz);
+
+-- name: ListFxRates :many
+select *
+from list_fx_rates();
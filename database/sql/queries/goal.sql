@@ -1,6 +1,6 @@
--- name: CreateGoal :exec
+-- name: CreateGoal :one
 select create_goal(@user_id, @name,
-                   @target_amount, @description);
+                   @target_amount, @description)::varchar as goal_id;
 
 -- name: UpdateGoal :exec
 select update_goal(@goal_id, @user_id,
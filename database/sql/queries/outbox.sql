@@ -0,0 +1,19 @@
+-- name: ListPendingOutboxEvents :many
+select *
+from list_pending_outbox_events(@batch_size::int);
+
+-- name: MarkOutboxEventsPublished :exec
+select mark_outbox_events_published(@event_ids::bigint[]);
+
+-- name: RecordOutboxEventFailure :exec
+select record_outbox_event_failure(
+               @event_id::bigint,
+               @error::varchar,
+               @park::boolean
+       );
+
+-- name: RequeueParkedOutboxEvents :one
+select requeue_parked_outbox_events()::bigint as requeued_count;
+
+-- name: PurgePublishedOutboxEvents :one
+select purge_published_outbox_events(@older_than::timestamptz)::bigint as deleted_count;
@@ -0,0 +1,15 @@
+-- name: ClaimProcessedMessage :one
+select claim_processed_message(
+               @consumer::varchar,
+               @message_key::varchar,
+               @ttl_seconds::int
+       )::boolean as claimed;
+
+-- name: IsMessageProcessed :one
+select is_message_processed(
+               @consumer::varchar,
+               @message_key::varchar
+       )::boolean as processed;
+
+-- name: PurgeProcessedMessages :one
+select purge_processed_messages()::bigint as deleted_count;
@@ -0,0 +1,76 @@
+-- name: CreateRecurringTransaction :one
+select create_recurring_transaction(
+               @user_id::varchar,
+               @account_number::varchar,
+               @category_id::varchar,
+               @transaction_type::varchar,
+               @amount::numeric,
+               @description::varchar,
+               @frequency::varchar,
+               @repeat_every::int,
+               @day_of_week::int,
+               @day_of_month::int,
+               @business_days::boolean,
+               @start_date::timestamp,
+               @end_date::timestamp
+       )::varchar as recurring_id;
+
+-- name: UpdateRecurringTransaction :exec
+select update_recurring_transaction(
+               @recurring_id::varchar,
+               @user_id::varchar,
+               @account_number::varchar,
+               @category_id::varchar,
+               @transaction_type::varchar,
+               @amount::numeric,
+               @description::varchar,
+               @frequency::varchar,
+               @repeat_every::int,
+               @day_of_week::int,
+               @day_of_month::int,
+               @business_days::boolean,
+               @start_date::timestamp,
+               @end_date::timestamp
+       );
+
+-- name: DeleteRecurringTransaction :exec
+select delete_recurring_transaction(
+               @recurring_id::varchar,
+               @user_id::varchar
+       );
+
+-- name: PauseRecurringTransaction :exec
+select pause_recurring_transaction(
+               @recurring_id::varchar,
+               @user_id::varchar
+       );
+
+-- name: ResumeRecurringTransaction :exec
+select resume_recurring_transaction(
+               @recurring_id::varchar,
+               @user_id::varchar
+       );
+
+-- name: SkipRecurringOccurrence :exec
+select skip_recurring_occurrence(
+               @recurring_id::varchar,
+               @user_id::varchar,
+               @occurrence_date::timestamp
+       );
+
+-- name: ListUserRecurringTransactions :many
+select *
+from list_recurring_transactions_for_user(@user_id::varchar);
+
+-- name: ListRecurringOccurrences :many
+select *
+from list_recurring_occurrences(
+        @recurring_id::varchar,
+        @user_id::varchar,
+        @page_number::int,
+        @page_size::int
+     );
+
+-- name: MaterializeRecurringTransactions :many
+select *
+from materialize_recurring_transactions(@now::timestamptz, @batch_size::int, @max_catch_up::int);
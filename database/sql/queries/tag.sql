@@ -0,0 +1,31 @@
+-- name: TagTransaction :exec
+select tag_transaction(
+               @transaction_id::varchar,
+               @user_id::varchar,
+               @tags::varchar[]
+       );
+
+-- name: UntagTransaction :exec
+select untag_transaction(
+               @transaction_id::varchar,
+               @user_id::varchar,
+               @tags::varchar[]
+       );
+
+-- name: RenameTag :one
+select *
+from rename_tag(
+        @tag_id::varchar,
+        @user_id::varchar,
+        @name::varchar
+     );
+
+-- name: DeleteTag :exec
+select delete_tag(
+               @tag_id::varchar,
+               @user_id::varchar
+       );
+
+-- name: GetTagsForUser :many
+select *
+from list_tags_for_user(@user_id::varchar);
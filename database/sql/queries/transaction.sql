@@ -6,7 +6,9 @@ from list_transactions_for_user_by_category(
         @start_date,
         @end_date,
         @page_number,
-        @page_size
+        @page_size,
+        @tags::varchar[],
+        @match_all_tags::boolean
      );
 
 -- name: GetAccountTransactions :many
@@ -17,7 +19,9 @@ from list_transactions_for_user_by_account(
         @start_date,
         @end_date,
         @page_number,
-        @page_size
+        @page_size,
+        @tags::varchar[],
+        @match_all_tags::boolean
      );
 
 -- name: GetTransactionsByType :many
@@ -28,7 +32,9 @@ from list_transactions_for_user_by_type(
         @start_date,
         @end_date,
         @page_number,
-        @page_size
+        @page_size,
+        @tags::varchar[],
+        @match_all_tags::boolean
      );
 
 -- name: GetUserTransactions :many
@@ -38,7 +44,9 @@ from list_transactions_for_user(
         @start_date,
         @end_date,
         @page_number,
-        @page_size
+        @page_size,
+        @tags::varchar[],
+        @match_all_tags::boolean
      );
 
 -- name: GetGoalTransactions :many
@@ -49,7 +57,9 @@ from list_transactions_for_user_by_goal(
         @start_date,
         @end_date,
         @page_number,
-        @page_size
+        @page_size,
+        @tags::varchar[],
+        @match_all_tags::boolean
      );
 
 -- name: Deposit :exec
@@ -59,7 +69,8 @@ select create_transaction(
                @category_id,
                'CREDIT',
                @amount::decimal,
-               @description
+               @description,
+               null::timestamptz
        );
 
 -- name: Withdraw :exec
@@ -69,7 +80,8 @@ select create_transaction(
                @category_id,
                'DEBIT',
                @amount::decimal,
-               @description
+               @description,
+               null::timestamptz
        );
 
 -- name: UpdateTransaction :exec
@@ -80,7 +92,10 @@ select update_transaction(
                @category_id,
                @transaction_type,
                @amount::decimal,
-               @description
+               @description,
+               @split_category_ids::varchar[],
+               @split_amounts::numeric[],
+               @split_memos::text[]
        );
 
 -- name: DeleteTransaction :exec
@@ -89,6 +104,29 @@ select delete_transaction(
                @user_id
        );
 
+-- name: SplitTransaction :many
+select *
+from split_transaction(
+        @transaction_id,
+        @user_id,
+        @category_ids::varchar[],
+        @amounts::numeric[],
+        @memos::text[]
+     );
+
+-- name: DeleteTransactionSplit :exec
+select delete_transaction_split(
+               @transaction_id,
+               @user_id
+       );
+
+-- name: GetTransactionSplits :many
+select *
+from list_transaction_splits(
+        @transaction_id,
+        @user_id
+     );
+
 -- name: GetTransactionById :one
 select *
 from get_transaction_by_id(
@@ -96,14 +134,15 @@ from get_transaction_by_id(
         @user_id
      );
 
--- name: Transfer :exec
-select account_to_account_transfer(
-               @user_id,
-               @from_account_number,
-               @to_account_number,
-               @amount,
-               @description
-       );
+-- name: Transfer :one
+select *
+from account_to_account_transfer(
+        @user_id,
+        @from_account_number,
+        @to_account_number,
+        @amount,
+        @description
+     );
 
 -- name: ContributeToGoal :exec
 select contribute_to_goal(
@@ -57,4 +57,9 @@ from update_user(
         @user_id::varchar,
         @name::varchar,
         @phone_number::varchar,
-        @avatar_url::varchar);
\ No newline at end of file
+        @avatar_url::varchar);
+
+-- name: UpdateBaseCurrency :exec
+select update_user_base_currency(
+               @user_id::varchar,
+               @currency::varchar);
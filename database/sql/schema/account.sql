@@ -1,3 +1,7 @@
+-- columns added after AccountMaster was first created (create table if not exists leaves existing databases as they are)
+alter table AccountMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
 drop table if exists AccountPayload cascade;
 create table if not exists AccountPayload
 (
@@ -6,38 +10,54 @@ create table if not exists AccountPayload
     account_number varchar     not null,
     user_id        varchar     not null,
     updated_at     timestamptz,
-    is_deleted     boolean     not null default false
+    is_deleted     boolean     not null default false,
+    currency       varchar     not null default 'GHS',
+    base_currency  varchar,
+    base_balance   numeric
 );
+comment on column AccountPayload.base_balance is 'balance in the base currency of the user, null when there is no exchange rate';
 
 drop function if exists create_new_account cascade;
 create or replace function create_new_account(
     p_user_id varchar,
     p_name varchar,
-    p_initial_balance numeric
+    p_initial_balance numeric,
+    p_currency varchar
 ) returns void as
 $$
 declare
     user_exists            boolean = false;
     existing_account_count int     = 0;
+    base_currency          varchar;
 begin
     select exists(select 1 from usermaster u where u.id = p_user_id)
     into user_exists;
 
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
+    -- accounts are in the base currency of the user unless told otherwise
+    if p_currency is null or p_currency = '' then
+        select u.basecurrency
+        from usermaster u
+        where u.id = p_user_id
+        into base_currency;
+        p_currency := base_currency;
+    end if;
+    perform validate_currency(p_currency);
+
     select count(*)
     from accountmaster a
     where a.name = p_name
     into existing_account_count;
 
     if existing_account_count > 0 then
-        raise exception 'An account with name % already exists', p_name;
+        raise exception 'An account with name % already exists', p_name using errcode = 'QW201';
     end if;
 
-    insert into accountmaster(userid, balance, name, accountnumber)
-    values (p_user_id, p_initial_balance, p_name, gen_random_account_number());
+    insert into accountmaster(userid, balance, currency, name, accountnumber)
+    values (p_user_id, p_initial_balance, p_currency, p_name, gen_random_account_number());
     raise notice 'Account created for user %', p_user_id;
 end;
 
@@ -48,8 +68,8 @@ create or replace function create_account_for_new_user() returns trigger as
 $$
 begin
     raise notice 'Creating account for new user %', new.id;
-    insert into accountmaster(userid, balance, accountnumber)
-    VALUES (new.id, 0.00, gen_random_account_number());
+    insert into accountmaster(userid, balance, currency, accountnumber)
+    VALUES (new.id, 0.00, new.basecurrency, gen_random_account_number());
     return new;
 end;
 $$ language plpgsql;
@@ -92,16 +112,26 @@ as
 $$
 begin
     return query
-        select a.name, a.balance, a.accountnumber, a.userid, a.updatedat, false
+        select a.name,
+               a.balance,
+               a.accountnumber,
+               a.userid,
+               a.updatedat,
+               false,
+               a.currency,
+               u.basecurrency,
+               convert_amount(a.balance, a.currency, u.basecurrency, now())
         from accountmaster a
+                 join usermaster u on u.id = a.userid
         where a.userid = p_user_id
         order by a.updatedat desc;
 end;
 $$ language plpgsql;
 
-drop function if exists recompute_account_balance cascade;
-create or replace function recompute_account_balance()
-    returns trigger as
+drop function if exists update_account_balance cascade;
+create or replace function update_account_balance(
+    p_account_id varchar
+) returns void as
 $$
 declare
     account_balance      numeric;
@@ -110,27 +140,88 @@ declare
 begin
     select coalesce(sum(t.amount), 0)
     from transactionmaster t
-    where t.accountid = new.accountid
+    where t.accountid = p_account_id
       and t.type = 'DEBIT'
     into total_expense_amount;
-    raise notice 'Total expense amount: %', total_expense_amount;
     select coalesce(sum(t.amount), 0)
     from transactionmaster t
-    where t.accountid = new.accountid
+    where t.accountid = p_account_id
       and t.type = 'CREDIT'
     into total_income_amount;
-    raise notice 'Total income amount: %', total_income_amount;
 
     account_balance := total_income_amount - total_expense_amount;
-    raise notice 'Account balance: %', account_balance;
 
     update accountmaster
     set balance = account_balance
-    where id = new.accountid;
+    where id = p_account_id;
+end;
+$$ language plpgsql;
+
+drop function if exists recompute_account_balance cascade;
+create or replace function recompute_account_balance()
+    returns trigger as
+$$
+begin
+    if tg_op <> 'DELETE' then
+        perform update_account_balance(new.accountid);
+    end if;
+
+    -- an update can also move the transaction away from its account
+    if tg_op = 'DELETE' or old.accountid <> new.accountid then
+        perform update_account_balance(old.accountid);
+    end if;
     return new;
 end;
 $$ language plpgsql;
 
+drop function if exists balance_effect cascade;
+create or replace function balance_effect(
+    p_type varchar,
+    p_amount numeric
+) returns numeric as
+$$
+begin
+    return case when p_type = 'CREDIT' then p_amount else -p_amount end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists lock_accounts cascade;
+create or replace function lock_accounts(
+    p_account_ids varchar[]
+) returns void as
+$$
+begin
+    -- always locked in the same order, so two changes of the same accounts cannot deadlock
+    perform 1
+    from accountmaster a
+    where a.id = any (p_account_ids)
+    order by a.id
+        for update;
+end;
+$$ language plpgsql;
+
+drop function if exists ensure_sufficient_funds cascade;
+create or replace function ensure_sufficient_funds(
+    p_account_id varchar,
+    p_withdrawal numeric
+) returns void as
+$$
+declare
+    account_balance numeric;
+begin
+    -- the row lock is held until the end of the transaction, so concurrent withdrawals are checked one after the other
+    select a.balance
+    into account_balance
+    from accountmaster a
+    where a.id = p_account_id
+        for update;
+
+    if p_withdrawal > 0 and account_balance - p_withdrawal < 0 then
+        raise exception 'Insufficient funds in account %', p_account_id using errcode = 'QW401';
+    end if;
+end;
+$$ language plpgsql;
+
 drop function if exists create_transaction_when_balance_is_non_zero cascade;
 create or replace function create_transaction_when_balance_is_non_zero()
     returns trigger as
@@ -147,7 +238,7 @@ begin
         into category_id;
 
         if category_id is null then
-            raise exception 'Category % does not exist', 'General';
+            raise exception 'Category % does not exist', 'General' using errcode = 'QW103';
         end if;
 
         insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
@@ -172,10 +263,10 @@ begin
         into user_id;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
-        select old.name, old.balance, old.accountnumber, old.userid, old.updatedat, true
+        select old.name, old.balance, old.accountnumber, old.userid, old.updatedat, true, old.currency, null, null
         into payload;
     else
         select u.id
@@ -185,13 +276,14 @@ begin
         into user_id;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
-        select new.name, new.balance, new.accountnumber, new.userid, new.updatedat, false
+        select new.name, new.balance, new.accountnumber, new.userid, new.updatedat, false, new.currency, null, null
         into payload;
     end if;
 
+    perform enqueue_outbox_event('accounts', payload.account_number, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('accounts', row_to_json(payload)::text);
     return new;
 end;
@@ -29,11 +29,11 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     if exists(select 1 from beneficiarymaster b where b.userid = p_user_id and b.accountid = account_id) then
-        raise exception 'Beneficiary for account % already exists', p_account_number;
+        raise exception 'Beneficiary for account % already exists', p_account_number using errcode = 'QW202';
     end if;
 
     if p_description is null or p_description = '' then
@@ -56,7 +56,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     delete
@@ -77,7 +77,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     return query
@@ -104,7 +104,7 @@ declare
 begin
     select exists(select 1 from beneficiarymaster b where b.id = p_beneficiary_id and b.userid = p_user_id) into beneficiary_exists;
     if not beneficiary_exists then
-        raise exception 'Beneficiary % does not exist', p_beneficiary_id;
+        raise exception 'Beneficiary % does not exist', p_beneficiary_id using errcode = 'QW105';
     end if;
 
     select a.id
@@ -114,7 +114,7 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     update beneficiarymaster
@@ -140,15 +140,15 @@ declare
 begin
     select exists(select 1 from usermaster u where u.id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     return query
@@ -183,11 +183,11 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         if account_id is null then
-            raise exception 'Account % does not exist', account_number;
+            raise exception 'Account % does not exist', account_number using errcode = 'QW102';
         end if;
 
         select old.id, account_number, old.name, old.description, old.userid, old.updatedat, true
@@ -205,17 +205,18 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         if account_id is null then
-            raise exception 'Account % does not exist', account_number;
+            raise exception 'Account % does not exist', account_number using errcode = 'QW102';
         end if;
 
         select new.id, account_number, new.name, new.description, new.userid, new.updatedat, false
         into payload;
     end if;
 
+    perform enqueue_outbox_event('beneficiaries', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('beneficiaries', row_to_json(payload)::text);
     return new;
 end;
@@ -0,0 +1,449 @@
+-- columns added after BudgetMaster was first created (create table if not exists leaves existing databases as they are)
+alter table BudgetMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
+drop table if exists BudgetPayload cascade;
+create table if not exists BudgetPayload
+(
+    id              varchar     not null,
+    user_id         varchar     not null,
+    category_id     varchar     not null,
+    amount          numeric     not null,
+    period          varchar     not null,
+    period_days     int         not null,
+    start_date      timestamptz not null,
+    rollover        boolean     not null,
+    period_start    timestamptz not null,
+    period_end      timestamptz not null,
+    rollover_amount numeric     not null,
+    limit_amount    numeric     not null,
+    spent           numeric     not null,
+    remaining       numeric     not null,
+    currency        varchar     not null default 'GHS'
+);
+
+drop table if exists BudgetAlertPayload cascade;
+create table if not exists BudgetAlertPayload
+(
+    id           varchar     not null,
+    budget_id    varchar     not null,
+    user_id      varchar     not null,
+    category_id  varchar     not null,
+    threshold    int         not null,
+    period_start timestamptz not null,
+    period_end   timestamptz not null,
+    limit_amount numeric     not null,
+    spent        numeric     not null,
+    created_at   timestamptz not null,
+    currency     varchar     not null default 'GHS'
+);
+
+drop function if exists budget_period_start cascade;
+create or replace function budget_period_start(
+    p_period varchar,
+    p_period_days int,
+    p_start_date date,
+    p_index int
+) returns timestamptz as
+$$
+begin
+    return case p_period
+               when 'MONTHLY' then p_start_date + make_interval(months => p_index)
+               when 'WEEKLY' then p_start_date + make_interval(days => 7 * p_index)
+               else p_start_date + make_interval(days => p_period_days * p_index)
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists budget_period_index cascade;
+create or replace function budget_period_index(
+    p_period varchar,
+    p_period_days int,
+    p_start_date date,
+    p_at timestamptz
+) returns int as
+$$
+declare
+    elapsed interval;
+begin
+    -- a budget that has not started yet reports its first period
+    if p_at::date < p_start_date then
+        return 0;
+    end if;
+
+    if p_period = 'MONTHLY' then
+        elapsed := age(p_at::date, p_start_date);
+        return (extract(year from elapsed) * 12 + extract(month from elapsed))::int;
+    elsif p_period = 'WEEKLY' then
+        return (p_at::date - p_start_date) / 7;
+    else
+        return (p_at::date - p_start_date) / p_period_days;
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists budget_status cascade;
+create or replace function budget_status(
+    p_budget budgetmaster,
+    p_at timestamptz
+) returns budgetpayload as
+$$
+declare
+    current_index    int;
+    carry            numeric := 0;
+    budget_period    record;
+    missing_currency varchar;
+    summed_from      timestamptz;
+    summed_to        timestamptz;
+    result           budgetpayload;
+begin
+    current_index := budget_period_index(p_budget.period, p_budget.perioddays, p_budget.startdate, p_at);
+    summed_from := budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate,
+                                       case when p_budget.rollover then 0 else current_index end);
+    summed_to := budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, current_index + 1);
+
+    -- spending in other currencies is converted at the rate of the day it was spent,
+    -- only the spending that is summed below needs a rate
+    select t.currency
+    from transactionmaster t
+             left join transactionsplitmaster s on s.transactionid = t.id
+    where t.userid = p_budget.userid
+      and coalesce(s.categoryid, t.categoryid) = p_budget.categoryid
+      and t.type = 'DEBIT'
+      and t.status not in ('FAILED', 'CANCELLED')
+      and t.createdat >= summed_from
+      and t.createdat < summed_to
+      and t.currency <> p_budget.currency
+      and fx_rate(t.currency, p_budget.currency, t.createdat) is null
+    limit 1
+    into missing_currency;
+
+    if missing_currency is not null then
+        raise exception 'No exchange rate from % to %', missing_currency, p_budget.currency using errcode = 'QW109';
+    end if;
+
+    -- unused amounts carry over from one period to the next, so rollover budgets replay every period since the start
+    for budget_period in
+        select s.idx,
+               budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, s.idx)     as period_start,
+               budget_period_start(p_budget.period, p_budget.perioddays, p_budget.startdate, s.idx + 1) as period_end
+        from generate_series(case when p_budget.rollover then 0 else current_index end, current_index) as s(idx)
+        order by s.idx
+        loop
+            select p_budget.id,
+                   p_budget.userid,
+                   p_budget.categoryid,
+                   p_budget.amount,
+                   p_budget.period,
+                   coalesce(p_budget.perioddays, 0),
+                   p_budget.startdate::timestamptz,
+                   p_budget.rollover,
+                   budget_period.period_start,
+                   budget_period.period_end,
+                   carry,
+                   p_budget.amount + carry,
+                   coalesce(sum(convert_amount(coalesce(s.amount, t.amount), t.currency, p_budget.currency, t.createdat)), 0),
+                   p_budget.amount + carry -
+                   coalesce(sum(convert_amount(coalesce(s.amount, t.amount), t.currency, p_budget.currency, t.createdat)), 0),
+                   p_budget.currency
+            into result
+            from transactionmaster t
+                     left join transactionsplitmaster s on s.transactionid = t.id
+            where t.userid = p_budget.userid
+              and coalesce(s.categoryid, t.categoryid) = p_budget.categoryid
+              and t.type = 'DEBIT'
+              and t.status not in ('FAILED', 'CANCELLED')
+              and t.createdat >= budget_period.period_start
+              and t.createdat < budget_period.period_end;
+
+            carry := greatest(result.remaining, 0);
+        end loop;
+
+    return result;
+end;
+$$ language plpgsql stable;
+
+drop function if exists create_budget cascade;
+create or replace function create_budget(
+    p_user_id varchar,
+    p_category_id varchar,
+    p_amount numeric,
+    p_period varchar,
+    p_period_days int,
+    p_start_date timestamp,
+    p_rollover boolean,
+    p_currency varchar
+) returns varchar as
+$$
+declare
+    budget_id varchar;
+begin
+    if p_amount <= 0 then
+        raise exception 'Budget amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_period not in ('WEEKLY', 'MONTHLY', 'CUSTOM') then
+        raise exception 'Budget period % is not supported', p_period using errcode = 'QW304';
+    end if;
+
+    if p_period = 'CUSTOM' and coalesce(p_period_days, 0) < 1 then
+        raise exception 'Custom budget periods must be at least 1 day long' using errcode = 'QW304';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    if exists(select 1 from budgetmaster b where b.userid = p_user_id and b.categoryid = p_category_id) then
+        raise exception 'Category % already has a budget', p_category_id using errcode = 'QW205';
+    end if;
+
+    -- budgets are in the base currency of the user unless told otherwise
+    if p_currency is null or p_currency = '' then
+        select u.basecurrency
+        from usermaster u
+        where u.id = p_user_id
+        into p_currency;
+    end if;
+    perform validate_currency(p_currency);
+
+    insert into budgetmaster(userid, categoryid, amount, currency, period, perioddays, startdate, rollover)
+    values (p_user_id, p_category_id, p_amount, p_currency, p_period,
+            case when p_period = 'CUSTOM' then p_period_days end,
+            coalesce(p_start_date::date, date_trunc('month', now())::date),
+            coalesce(p_rollover, false))
+    returning id
+        into budget_id;
+    return budget_id;
+end;
+$$ language plpgsql;
+
+drop function if exists update_budget cascade;
+create or replace function update_budget(
+    p_budget_id varchar,
+    p_user_id varchar,
+    p_amount numeric,
+    p_period varchar,
+    p_period_days int,
+    p_start_date timestamp,
+    p_rollover boolean
+) returns void as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    if p_amount <= 0 then
+        raise exception 'Budget amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_period not in ('WEEKLY', 'MONTHLY', 'CUSTOM') then
+        raise exception 'Budget period % is not supported', p_period using errcode = 'QW304';
+    end if;
+
+    if p_period = 'CUSTOM' and coalesce(p_period_days, 0) < 1 then
+        raise exception 'Custom budget periods must be at least 1 day long' using errcode = 'QW304';
+    end if;
+
+    update budgetmaster
+    set amount     = p_amount,
+        period     = p_period,
+        perioddays = case when p_period = 'CUSTOM' then p_period_days end,
+        startdate  = coalesce(p_start_date::date, startdate),
+        rollover   = coalesce(p_rollover, rollover)
+    where id = p_budget_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_budget cascade;
+create or replace function delete_budget(
+    p_budget_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    delete
+    from budgetmaster
+    where id = p_budget_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists get_budget cascade;
+create or replace function get_budget(
+    p_budget_id varchar,
+    p_user_id varchar,
+    p_at timestamptz
+)
+    returns setof budgetpayload
+as
+$$
+begin
+    if not exists(select 1 from budgetmaster b where b.id = p_budget_id and b.userid = p_user_id) then
+        raise exception 'Budget % does not exist', p_budget_id using errcode = 'QW107';
+    end if;
+
+    return query
+        select s.*
+        from budgetmaster b
+                 cross join lateral budget_status(b, coalesce(p_at, now())) s
+        where b.id = p_budget_id
+          and b.userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_budgets_for_user cascade;
+create or replace function list_budgets_for_user(
+    p_user_id varchar,
+    p_at timestamptz
+)
+    returns setof budgetpayload
+as
+$$
+begin
+    return query
+        select s.*
+        from budgetmaster b
+                 cross join lateral budget_status(b, coalesce(p_at, now())) s
+        where b.userid = p_user_id
+        order by b.createdat;
+end;
+$$ language plpgsql;
+
+drop function if exists alert_budget_thresholds cascade;
+create or replace function alert_budget_thresholds(
+    p_user_id varchar,
+    p_category_id varchar,
+    p_at timestamptz
+) returns void as
+$$
+declare
+    budget            budgetmaster;
+    budget_state      budgetpayload;
+    threshold_percent int;
+begin
+    select *
+    into budget
+    from budgetmaster b
+    where b.userid = p_user_id
+      and b.categoryid = p_category_id;
+
+    if budget.id is null then
+        return;
+    end if;
+
+    -- a missing exchange rate must not fail the transaction that triggered the check
+    begin
+        budget_state := budget_status(budget, p_at);
+    exception
+        when sqlstate 'QW109' then
+            raise notice 'Budget % skipped: %', budget.id, sqlerrm;
+            return;
+    end;
+
+    -- each threshold is alerted once per period, the unique key of budgetalertmaster drops repeats
+    foreach threshold_percent in array array [80, 100]
+        loop
+            if budget_state.spent * 100 >= budget_state.limit_amount * threshold_percent then
+                insert into budgetalertmaster(budgetid, userid, periodstart, periodend, threshold, limitamount, spent)
+                values (budget.id, budget.userid, budget_state.period_start, budget_state.period_end, threshold_percent,
+                        budget_state.limit_amount, budget_state.spent)
+                on conflict (budgetid, periodstart, threshold) do nothing;
+            end if;
+        end loop;
+end;
+$$ language plpgsql;
+
+drop function if exists check_budget_thresholds cascade;
+create or replace function check_budget_thresholds()
+    returns trigger as
+$$
+begin
+    if new.type = 'DEBIT' then
+        perform alert_budget_thresholds(new.userid, new.categoryid, new.createdat);
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+drop function if exists check_split_budget_thresholds cascade;
+create or replace function check_split_budget_thresholds()
+    returns trigger as
+$$
+declare
+    parent transactionmaster;
+begin
+    select *
+    into parent
+    from transactionmaster t
+    where t.id = new.transactionid;
+
+    -- split lines count against the budget of their own category
+    if parent.type = 'DEBIT' then
+        perform alert_budget_thresholds(new.userid, new.categoryid, parent.createdat);
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+drop function if exists notify_budget_alerts cascade;
+create or replace function notify_budget_alerts()
+    returns trigger as
+$$
+declare
+    payload budgetalertpayload;
+begin
+    select new.id,
+           new.budgetid,
+           new.userid,
+           b.categoryid,
+           new.threshold,
+           new.periodstart,
+           new.periodend,
+           new.limitamount,
+           new.spent,
+           new.createdat,
+           b.currency
+    into payload
+    from budgetmaster b
+    where b.id = new.budgetid;
+
+    perform enqueue_outbox_event('budget_alerts', payload.id, payload.user_id, tg_op, to_jsonb(payload));
+    perform pg_notify('budget_alerts', row_to_json(payload)::text);
+    return new;
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on budgetmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on budgetmaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_check_budget_thresholds on transactionmaster cascade;
+create or replace trigger trigger_check_budget_thresholds
+    after insert or update
+    on transactionmaster
+    for each row
+execute function check_budget_thresholds();
+
+drop trigger if exists trigger_check_split_budget_thresholds on transactionsplitmaster cascade;
+create or replace trigger trigger_check_split_budget_thresholds
+    after insert or update
+    on transactionsplitmaster
+    for each row
+execute function check_split_budget_thresholds();
+
+drop trigger if exists trigger_notify_budget_alerts on budgetalertmaster cascade;
+create or replace trigger trigger_notify_budget_alerts
+    after insert
+    on budgetalertmaster
+    for each row
+execute function notify_budget_alerts();
@@ -32,7 +32,7 @@ declare
 begin
     select exists(select 1 from transactioncategorymaster where id = p_category_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
     delete
@@ -54,7 +54,7 @@ declare
 begin
     select exists(select 1 from transactioncategorymaster where id = p_category_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
     update transactioncategorymaster
@@ -159,7 +159,7 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
         select old.id, old.name, old.description, old.userid, true
@@ -171,13 +171,14 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
         select new.id, new.name, new.description, new.userid, false
         into payload;
     end if;
 
+    perform enqueue_outbox_event('categories', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('categories', row_to_json(payload)::text);
     return new;
 end;
@@ -0,0 +1,172 @@
+drop table if exists FxRatePayload cascade;
+create table if not exists FxRatePayload
+(
+    base_currency  varchar     not null,
+    quote_currency varchar     not null,
+    rate           numeric     not null,
+    source         varchar     not null,
+    as_of          timestamptz not null
+);
+
+drop function if exists validate_currency cascade;
+create or replace function validate_currency(
+    p_currency varchar
+) returns void as
+$$
+begin
+    if p_currency is null or p_currency !~ '^[A-Z]{3}$' then
+        raise exception 'Currency % is not a 3-letter ISO 4217 code', p_currency using errcode = 'QW305';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists currency_scale cascade;
+create or replace function currency_scale(
+    p_currency varchar
+) returns int as
+$$
+begin
+    -- ISO 4217 minor units, capped at the 2 decimal places kept by amount columns
+    return case
+               when p_currency in ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'UYI', 'VND',
+                                   'VUV', 'XAF', 'XOF', 'XPF') then 0
+               else 2
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists find_fx_rate cascade;
+create or replace function find_fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns setof fxratepayload as
+$$
+begin
+    if p_from_currency = p_to_currency then
+        return query select p_from_currency, p_to_currency, 1::numeric, 'identity'::varchar, p_at;
+        return;
+    end if;
+
+    -- the latest rate known at the time, stored in either direction.
+    -- amounts older than the first known rate are converted with that rate.
+    return query
+        select p_from_currency,
+               p_to_currency,
+               case when r.basecurrency = p_from_currency then r.rate else round(1 / r.rate, 8) end,
+               r.source,
+               r.asof
+        from fxratemaster r
+        where (r.basecurrency = p_from_currency and r.quotecurrency = p_to_currency)
+           or (r.basecurrency = p_to_currency and r.quotecurrency = p_from_currency)
+        order by r.asof <= p_at desc,
+                 case when r.asof <= p_at then r.asof end desc,
+                 r.asof,
+                 r.basecurrency = p_from_currency desc
+        limit 1;
+end;
+$$ language plpgsql stable;
+
+drop function if exists fx_rate cascade;
+create or replace function fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns numeric as
+$$
+begin
+    -- null when the pair has never been quoted
+    return (select f.rate from find_fx_rate(p_from_currency, p_to_currency, p_at) f);
+end;
+$$ language plpgsql stable;
+
+drop function if exists convert_amount cascade;
+create or replace function convert_amount(
+    p_amount numeric,
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns numeric as
+$$
+begin
+    -- null when there is no rate for the pair
+    return round(p_amount * fx_rate(p_from_currency, p_to_currency, p_at), currency_scale(p_to_currency));
+end;
+$$ language plpgsql stable;
+
+drop function if exists save_fx_rate cascade;
+create or replace function save_fx_rate(
+    p_base_currency varchar,
+    p_quote_currency varchar,
+    p_rate numeric,
+    p_source varchar,
+    p_as_of timestamptz
+) returns setof fxratepayload as
+$$
+begin
+    perform validate_currency(p_base_currency);
+    perform validate_currency(p_quote_currency);
+
+    if p_base_currency = p_quote_currency then
+        raise exception 'Cannot quote % against itself', p_base_currency using errcode = 'QW305';
+    end if;
+
+    if p_rate is null or p_rate <= 0 then
+        raise exception 'Rate must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_as_of is null then
+        p_as_of := now();
+    end if;
+
+    return query
+        insert into fxratemaster(basecurrency, quotecurrency, rate, source, asof)
+            values (p_base_currency, p_quote_currency, p_rate, coalesce(nullif(p_source, ''), 'manual'), p_as_of)
+            on conflict (basecurrency, quotecurrency, asof) do update
+                set rate = excluded.rate,
+                    source = excluded.source
+            returning basecurrency, quotecurrency, rate, source, asof;
+end;
+$$ language plpgsql;
+
+drop function if exists get_fx_rate cascade;
+create or replace function get_fx_rate(
+    p_from_currency varchar,
+    p_to_currency varchar,
+    p_at timestamptz
+) returns setof fxratepayload as
+$$
+declare
+    payload fxratepayload;
+begin
+    perform validate_currency(p_from_currency);
+    perform validate_currency(p_to_currency);
+
+    if p_at is null then
+        p_at := now();
+    end if;
+
+    select *
+    from find_fx_rate(p_from_currency, p_to_currency, p_at)
+    into payload;
+
+    if payload.rate is null then
+        raise exception 'No exchange rate from % to %', p_from_currency, p_to_currency using errcode = 'QW109';
+    end if;
+    return next payload;
+end;
+$$ language plpgsql stable;
+
+drop function if exists list_fx_rates cascade;
+create or replace function list_fx_rates()
+    returns setof fxratepayload
+as
+$$
+begin
+    -- the latest rate of every pair
+    return query
+        select distinct on (r.basecurrency, r.quotecurrency) r.basecurrency, r.quotecurrency, r.rate, r.source, r.asof
+        from fxratemaster r
+        order by r.basecurrency, r.quotecurrency, r.asof desc;
+end;
+$$ language plpgsql stable;
@@ -1,3 +1,7 @@
+-- columns added after GoalMaster was first created (create table if not exists leaves existing databases as they are)
+alter table GoalMaster
+    add column if not exists Currency varchar(3) not null default 'GHS' check (Currency ~ '^[A-Z]{3}$');
+
 drop table if exists GoalPayload cascade;
 create table if not exists GoalPayload
 (
@@ -7,9 +11,28 @@ create table if not exists GoalPayload
     description text           not null,
     balance     numeric(10, 2) not null,
     user_id     varchar        not null,
-    is_deleted  boolean        not null default false
+    is_deleted  boolean        not null default false,
+    currency    varchar        not null default 'GHS'
 );
 
+drop table if exists GoalContributionPayload cascade;
+create table if not exists GoalContributionPayload
+(
+    id              varchar     not null,
+    name            varchar     not null,
+    target          numeric     not null,
+    description     text        not null,
+    balance         numeric     not null,
+    user_id         varchar     not null,
+    currency        varchar     not null,
+    amount          numeric     not null,
+    amount_currency varchar     not null,
+    account_number  varchar     not null,
+    transaction_id  varchar     not null,
+    contributed_at  timestamptz not null
+);
+comment on column GoalContributionPayload.amount is 'in the currency of the account (amount_currency), not of the goal';
+
 drop function if exists create_goal cascade;
 create or replace function create_goal(
     p_user_id varchar,
@@ -23,21 +46,24 @@ declare
     goal_id varchar;
 begin
     if p_target <= 0 then
-        raise exception 'Target amount must be greater than 0';
+        raise exception 'Target amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     select exists(select 1 from goalmaster g where g.userid = p_user_id and g.name = p_name)
     into exists;
     if exists then
-        raise exception 'Goal % already exists', p_name;
+        raise exception 'Goal % already exists', p_name using errcode = 'QW203';
     end if;
 
     if p_description is null then
         p_description := 'Create a goal to save money for a specific purpose';
     end if;
 
-    insert into goalmaster(userid, name, target, description)
-    values (p_user_id, p_name, p_target, p_description)
+    -- goals are in the base currency of the user
+    insert into goalmaster(userid, name, target, description, currency)
+    select p_user_id, p_name, p_target, p_description, u.basecurrency
+    from usermaster u
+    where u.id = p_user_id
     returning id
         into goal_id;
     return goal_id;
@@ -55,7 +81,7 @@ declare
 begin
     select exists(select 1 from goalmaster g where g.id = p_goal_id and g.userid = p_user_id) into goal_exists;
     if not goal_exists then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
     end if;
 
     delete
@@ -76,7 +102,7 @@ create or replace function update_goal(
 $$
 begin
     if p_target <= 0 then
-        raise exception 'Target amount must be greater than 0';
+        raise exception 'Target amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     update goalmaster
@@ -100,15 +126,15 @@ $$
 declare
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     return query
-        select g.id, g.name, g.target, g.description, g.balance, g.userid, false
+        select g.id, g.name, g.target, g.description, g.balance, g.userid, false, g.currency
         from goalmaster g
         where userid = p_user_id
         order by updatedat desc
@@ -126,12 +152,13 @@ declare
     goal_balance numeric;
 begin
 
-    select g.target, coalesce(sum(t.amount), 0) as total_contributed
+    -- contributions from accounts in other currencies are converted at the rate of the day they were made
+    select g.target, coalesce(sum(convert_amount(t.amount, t.currency, g.currency, t.createdat)), 0) as total_contributed
     into goal_data
     from goalmaster g
              left join transactionmaster t on t.referencenumber = g.id
     where g.id = new.referencenumber
-    group by g.target;
+    group by g.target, g.currency;
 
     if goal_data.target is null then
         raise notice 'Goal % does not exist', new.referencenumber;
@@ -172,12 +199,18 @@ declare
     accounts_array     varchar[];
     category_array     varchar[];
     amount_array       numeric[];
+    payload            goalpayload;
 begin
     if old.status = 'COMPLETED' then
         raise notice 'Goal % has already been completed', old.id;
         return old;
     end if;
 
+    -- a goal deleted before reaching its target was abandoned
+    select old.id, old.name, old.target, old.description, old.balance, old.userid, true, old.currency
+    into payload;
+    perform enqueue_outbox_event('goal_cancellations', old.id, old.userid, 'INSERT', to_jsonb(payload));
+
     select array_agg(distinct t.accountid), array_agg(t.categoryid), array_agg(t.amount)
     into accounts_array, category_array, amount_array
     from transactionmaster t
@@ -224,10 +257,10 @@ begin
         where u.id = old.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', old.userid;
+            raise exception 'User % does not exist', old.userid using errcode = 'QW101';
         end if;
 
-        select old.id, old.name, old.target, old.description, old.balance, old.userid, true
+        select old.id, old.name, old.target, old.description, old.balance, old.userid, true, old.currency
         into payload;
     else
         select u.id
@@ -236,13 +269,14 @@ begin
         where u.id = new.userid;
 
         if user_id is null then
-            raise exception 'User % does not exist', new.userid;
+            raise exception 'User % does not exist', new.userid using errcode = 'QW101';
         end if;
 
-        select new.id, new.name, new.target, new.description, new.balance, new.userid, false
+        select new.id, new.name, new.target, new.description, new.balance, new.userid, false, new.currency
         into payload;
     end if;
 
+    perform enqueue_outbox_event('goals', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('goals', row_to_json(payload)::text);
     return new;
 end;
@@ -0,0 +1,124 @@
+drop table if exists OutboxPayload cascade;
+create table if not exists OutboxPayload
+(
+    id           bigint      not null,
+    aggregate    varchar     not null,
+    aggregate_id varchar     not null,
+    user_id      varchar     not null,
+    operation    varchar     not null,
+    payload      jsonb       not null,
+    attempts     int         not null,
+    created_at   timestamptz not null
+);
+
+drop function if exists enqueue_outbox_event cascade;
+create or replace function enqueue_outbox_event(
+    p_aggregate varchar,
+    p_aggregate_id varchar,
+    p_user_id varchar,
+    p_operation varchar,
+    p_payload jsonb
+) returns void as
+$$
+declare
+    event_id bigint;
+begin
+    if p_aggregate_id is null or p_user_id is null then
+        raise notice 'Skipping outbox event for % without aggregate or user id', p_aggregate;
+        return;
+    end if;
+
+    insert into outboxmaster(aggregate, aggregateid, userid, operation, payload)
+    values (p_aggregate, p_aggregate_id, p_user_id, p_operation, p_payload)
+    returning id into event_id;
+
+    -- wake up the relay (delivered on commit only)
+    perform pg_notify('outbox', event_id::text);
+end;
+$$ language plpgsql;
+
+drop function if exists list_pending_outbox_events cascade;
+create or replace function list_pending_outbox_events(
+    p_batch_size int
+)
+    returns setof outboxpayload
+as
+$$
+begin
+    if p_batch_size < 1 then
+        raise exception 'Batch size must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    return query
+        select o.id, o.aggregate, o.aggregateid, o.userid, o.operation, o.payload, o.attempts, o.createdat
+        from outboxmaster o
+        where o.publishedat is null
+          and o.parkedat is null
+        order by o.id
+        limit p_batch_size;
+end;
+$$ language plpgsql;
+
+drop function if exists mark_outbox_events_published cascade;
+create or replace function mark_outbox_events_published(
+    p_event_ids bigint[]
+) returns void as
+$$
+begin
+    update outboxmaster
+    set publishedat = now(),
+        attempts    = attempts + 1,
+        lasterror   = null
+    where id = any (p_event_ids)
+      and publishedat is null;
+end;
+$$ language plpgsql;
+
+drop function if exists record_outbox_event_failure cascade;
+create or replace function record_outbox_event_failure(
+    p_event_id bigint,
+    p_error varchar,
+    p_park boolean
+) returns void as
+$$
+begin
+    update outboxmaster
+    set attempts  = attempts + 1,
+        lasterror = p_error,
+        parkedat  = case when p_park then now() end
+    where id = p_event_id;
+end;
+$$ language plpgsql;
+
+drop function if exists requeue_parked_outbox_events cascade;
+create or replace function requeue_parked_outbox_events() returns bigint as
+$$
+declare
+    requeued_count bigint;
+begin
+    update outboxmaster
+    set parkedat = null,
+        attempts = 0
+    where parkedat is not null
+      and publishedat is null;
+    get diagnostics requeued_count = row_count;
+    return requeued_count;
+end;
+$$ language plpgsql;
+
+drop function if exists purge_published_outbox_events cascade;
+create or replace function purge_published_outbox_events(
+    p_older_than timestamptz
+) returns bigint as
+$$
+declare
+    deleted_count bigint;
+begin
+    delete
+    from outboxmaster
+    where publishedat is not null
+      and publishedat < p_older_than;
+    get diagnostics deleted_count = row_count;
+    return deleted_count;
+end;
+$$ language plpgsql;
@@ -0,0 +1,58 @@
+drop function if exists claim_processed_message cascade;
+create or replace function claim_processed_message(
+    p_consumer varchar,
+    p_message_key varchar,
+    p_ttl_seconds int
+) returns boolean as
+$$
+declare
+    claimed_count int;
+begin
+    if p_consumer is null or p_message_key is null then
+        raise exception 'Consumer and message key are required' using errcode = 'QW304';
+    end if;
+
+    if p_ttl_seconds < 1 then
+        raise exception 'TTL must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    -- a concurrent claim of the same message waits here until the first one commits or rolls back
+    insert into processedmessagemaster(consumer, messagekey, expiresat)
+    values (p_consumer, p_message_key, now() + make_interval(secs => p_ttl_seconds))
+    on conflict (consumer, messagekey) do update
+        set processedat = now(),
+            expiresat   = excluded.expiresat
+    where processedmessagemaster.expiresat <= now();
+    get diagnostics claimed_count = row_count;
+    return claimed_count > 0;
+end;
+$$ language plpgsql;
+
+drop function if exists is_message_processed cascade;
+create or replace function is_message_processed(
+    p_consumer varchar,
+    p_message_key varchar
+) returns boolean as
+$$
+begin
+    return exists(select 1
+                  from processedmessagemaster p
+                  where p.consumer = p_consumer
+                    and p.messagekey = p_message_key
+                    and p.expiresat > now());
+end;
+$$ language plpgsql;
+
+drop function if exists purge_processed_messages cascade;
+create or replace function purge_processed_messages() returns bigint as
+$$
+declare
+    deleted_count bigint;
+begin
+    delete
+    from processedmessagemaster
+    where expiresat <= now();
+    get diagnostics deleted_count = row_count;
+    return deleted_count;
+end;
+$$ language plpgsql;
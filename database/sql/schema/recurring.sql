@@ -0,0 +1,569 @@
+drop table if exists RecurringTransactionPayload cascade;
+create table if not exists RecurringTransactionPayload
+(
+    id             varchar     not null,
+    user_id        varchar     not null,
+    account_number varchar     not null,
+    category_id    varchar     not null,
+    type           varchar     not null,
+    amount         numeric     not null,
+    description    text        not null,
+    frequency      varchar     not null,
+    repeat_every   int         not null,
+    day_of_week    int         not null,
+    day_of_month   int         not null,
+    business_days  boolean     not null,
+    start_date     timestamptz not null,
+    end_date       timestamptz,
+    next_run_at    timestamptz,
+    status         varchar     not null
+);
+
+drop table if exists RecurringOccurrencePayload cascade;
+create table if not exists RecurringOccurrencePayload
+(
+    id              varchar     not null,
+    recurring_id    varchar     not null,
+    user_id         varchar     not null,
+    occurrence_date timestamptz not null,
+    status          varchar     not null,
+    transaction_id  varchar     not null,
+    error           text        not null,
+    created_at      timestamptz not null
+);
+
+drop function if exists recurring_next_date cascade;
+create or replace function recurring_next_date(
+    p_recurring recurringtransactionmaster,
+    p_from date
+) returns date as
+$$
+declare
+    from_date    date := greatest(p_from, p_recurring.startdate);
+    first_date   date;
+    next_date    date;
+    month_index  int;
+    month_start  date;
+    month_end    date;
+    day_of_month int  := coalesce(p_recurring.dayofmonth, extract(day from p_recurring.startdate)::int);
+begin
+    if p_recurring.frequency = 'DAILY' then
+        next_date := p_recurring.startdate +
+                     ceil((from_date - p_recurring.startdate)::numeric / p_recurring.repeatevery)::int * p_recurring.repeatevery;
+    elsif p_recurring.frequency = 'WEEKLY' then
+        -- the first occurrence is the first matching weekday on or after the start date
+        first_date := p_recurring.startdate +
+                      (coalesce(p_recurring.dayofweek, extract(isodow from p_recurring.startdate)::int) -
+                       extract(isodow from p_recurring.startdate)::int + 7) % 7;
+        next_date := first_date +
+                     greatest(ceil((from_date - first_date)::numeric / (7 * p_recurring.repeatevery))::int, 0) * 7 * p_recurring.repeatevery;
+    else
+        -- months are counted from the month of the start date, the day is clamped to the length of the month
+        month_index := ((extract(year from from_date) - extract(year from p_recurring.startdate)) * 12 +
+                        extract(month from from_date) - extract(month from p_recurring.startdate))::int;
+        month_index := month_index - month_index % p_recurring.repeatevery;
+        loop
+            month_start := (date_trunc('month', p_recurring.startdate) + make_interval(months => month_index))::date;
+            month_end := (month_start + interval '1 month' - interval '1 day')::date;
+            if day_of_month = -1 then
+                next_date := month_end;
+            else
+                next_date := least(month_start + day_of_month - 1, month_end);
+            end if;
+            exit when next_date >= from_date;
+            month_index := month_index + p_recurring.repeatevery;
+        end loop;
+    end if;
+
+    if p_recurring.enddate is not null and next_date > p_recurring.enddate then
+        return null;
+    end if;
+    return next_date;
+end;
+$$ language plpgsql stable;
+
+drop function if exists recurring_due_date cascade;
+create or replace function recurring_due_date(
+    p_recurring recurringtransactionmaster,
+    p_date date
+) returns date as
+$$
+begin
+    -- business-day schedules move weekend occurrences to the friday before
+    if p_date is null or not p_recurring.businessdays then
+        return p_date;
+    end if;
+
+    return case extract(isodow from p_date)
+               when 6 then p_date - 1
+               when 7 then p_date - 2
+               else p_date
+        end;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists validate_recurring_schedule cascade;
+create or replace function validate_recurring_schedule(
+    p_type varchar,
+    p_amount numeric,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_start_date date,
+    p_end_date date
+) returns void as
+$$
+begin
+    if p_amount <= 0 then
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
+    end if;
+
+    if p_type not in ('CREDIT', 'DEBIT') then
+        raise exception 'Transaction type % is not supported', p_type using errcode = 'QW304';
+    end if;
+
+    if p_frequency not in ('DAILY', 'WEEKLY', 'MONTHLY') then
+        raise exception 'Frequency % is not supported', p_frequency using errcode = 'QW304';
+    end if;
+
+    if p_repeat_every < 1 then
+        raise exception 'Repeat interval must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    if p_day_of_week is not null and p_day_of_week not between 1 and 7 then
+        raise exception 'Day of week must be between 1 (monday) and 7 (sunday)' using errcode = 'QW304';
+    end if;
+
+    if p_day_of_month is not null and p_day_of_month <> -1 and p_day_of_month not between 1 and 31 then
+        raise exception 'Day of month must be between 1 and 31, or -1 for the last day' using errcode = 'QW304';
+    end if;
+
+    if p_end_date is not null and p_end_date < p_start_date then
+        raise exception 'End date must not be before the start date' using errcode = 'QW304';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists create_recurring_transaction cascade;
+create or replace function create_recurring_transaction(
+    p_user_id varchar,
+    p_account_number varchar,
+    p_category_id varchar,
+    p_type varchar,
+    p_amount numeric,
+    p_description varchar,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_business_days boolean,
+    p_start_date timestamp,
+    p_end_date timestamp
+) returns varchar as
+$$
+declare
+    account_id varchar;
+    recurring  recurringtransactionmaster;
+begin
+    perform validate_recurring_schedule(p_type, p_amount, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+                                        nullif(p_day_of_month, 0), coalesce(p_start_date::date, current_date), p_end_date::date);
+
+    select a.id
+    into account_id
+    from accountmaster a
+    where a.accountnumber = p_account_number
+      and a.userid = p_user_id;
+
+    if account_id is null then
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    insert into recurringtransactionmaster(userid, accountid, categoryid, type, amount, description, frequency, repeatevery, dayofweek,
+                                           dayofmonth, businessdays, startdate, enddate)
+    values (p_user_id, account_id, p_category_id, p_type, p_amount, p_description, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+            nullif(p_day_of_month, 0), coalesce(p_business_days, false), coalesce(p_start_date::date, current_date), p_end_date::date)
+    returning *
+        into recurring;
+
+    update recurringtransactionmaster
+    set nextrunat = recurring_next_date(recurring, recurring.startdate)
+    where id = recurring.id;
+    return recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists update_recurring_transaction cascade;
+create or replace function update_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_account_number varchar,
+    p_category_id varchar,
+    p_type varchar,
+    p_amount numeric,
+    p_description varchar,
+    p_frequency varchar,
+    p_repeat_every int,
+    p_day_of_week int,
+    p_day_of_month int,
+    p_business_days boolean,
+    p_start_date timestamp,
+    p_end_date timestamp
+) returns void as
+$$
+declare
+    account_id varchar;
+    recurring  recurringtransactionmaster;
+    next_date  date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id
+        for update;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    perform validate_recurring_schedule(p_type, p_amount, p_frequency, p_repeat_every, nullif(p_day_of_week, 0),
+                                        nullif(p_day_of_month, 0), coalesce(p_start_date::date, recurring.startdate),
+                                        p_end_date::date);
+
+    select a.id
+    into account_id
+    from accountmaster a
+    where a.accountnumber = p_account_number
+      and a.userid = p_user_id;
+
+    if account_id is null then
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    if not exists(select 1 from transactioncategorymaster c where c.id = p_category_id and c.userid = p_user_id) then
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
+    end if;
+
+    recurring.accountid := account_id;
+    recurring.categoryid := p_category_id;
+    recurring.type := p_type;
+    recurring.amount := p_amount;
+    recurring.description := p_description;
+    recurring.frequency := p_frequency;
+    recurring.repeatevery := p_repeat_every;
+    recurring.dayofweek := nullif(p_day_of_week, 0);
+    recurring.dayofmonth := nullif(p_day_of_month, 0);
+    recurring.businessdays := coalesce(p_business_days, recurring.businessdays);
+    recurring.startdate := coalesce(p_start_date::date, recurring.startdate);
+    recurring.enddate := p_end_date::date;
+
+    -- edits only apply to future occurrences, the ones already materialized keep their transactions
+    next_date := recurring_next_date(recurring, current_date);
+
+    update recurringtransactionmaster
+    set accountid    = recurring.accountid,
+        categoryid   = recurring.categoryid,
+        type         = recurring.type,
+        amount       = recurring.amount,
+        description  = recurring.description,
+        frequency    = recurring.frequency,
+        repeatevery  = recurring.repeatevery,
+        dayofweek    = recurring.dayofweek,
+        dayofmonth   = recurring.dayofmonth,
+        businessdays = recurring.businessdays,
+        startdate    = recurring.startdate,
+        enddate      = recurring.enddate,
+        nextrunat    = next_date,
+        status       = case
+                           when recurring.status = 'PAUSED' then 'PAUSED'
+                           when next_date is null then 'ENDED'
+                           else 'ACTIVE'
+            end
+    where id = recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_recurring_transaction cascade;
+create or replace function delete_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from recurringtransactionmaster r where r.id = p_recurring_id and r.userid = p_user_id) then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    delete
+    from recurringtransactionmaster
+    where id = p_recurring_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists pause_recurring_transaction cascade;
+create or replace function pause_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+begin
+    if not exists(select 1 from recurringtransactionmaster r where r.id = p_recurring_id and r.userid = p_user_id) then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    update recurringtransactionmaster
+    set status = 'PAUSED'
+    where id = p_recurring_id
+      and userid = p_user_id
+      and status = 'ACTIVE';
+end;
+$$ language plpgsql;
+
+drop function if exists resume_recurring_transaction cascade;
+create or replace function resume_recurring_transaction(
+    p_recurring_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    recurring recurringtransactionmaster;
+    next_date date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id
+        for update;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    if recurring.status <> 'PAUSED' then
+        return;
+    end if;
+
+    -- occurrences missed while paused are not caught up on
+    next_date := recurring_next_date(recurring, current_date);
+
+    update recurringtransactionmaster
+    set nextrunat = next_date,
+        status    = case when next_date is null then 'ENDED' else 'ACTIVE' end
+    where id = recurring.id;
+end;
+$$ language plpgsql;
+
+drop function if exists skip_recurring_occurrence cascade;
+create or replace function skip_recurring_occurrence(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_occurrence_date timestamp
+) returns void as
+$$
+declare
+    recurring       recurringtransactionmaster;
+    occurrence_date date;
+begin
+    select *
+    into recurring
+    from recurringtransactionmaster r
+    where r.id = p_recurring_id
+      and r.userid = p_user_id;
+
+    if recurring.id is null then
+        raise exception 'Recurring transaction % does not exist', p_recurring_id using errcode = 'QW108';
+    end if;
+
+    -- without a date, the next occurrence is skipped
+    occurrence_date := coalesce(p_occurrence_date::date, recurring_due_date(recurring, recurring.nextrunat));
+    if occurrence_date is null or occurrence_date < current_date then
+        raise exception 'Only future occurrences can be skipped' using errcode = 'QW304';
+    end if;
+
+    if exists(select 1
+              from recurringoccurrencemaster o
+              where o.recurringid = recurring.id
+                and o.occurrencedate = occurrence_date
+                and o.status <> 'SKIPPED') then
+        raise exception 'The occurrence on % was already processed', occurrence_date using errcode = 'QW304';
+    end if;
+
+    insert into recurringoccurrencemaster(recurringid, userid, occurrencedate, status)
+    values (recurring.id, recurring.userid, occurrence_date, 'SKIPPED')
+    on conflict (recurringid, occurrencedate) do nothing;
+end;
+$$ language plpgsql;
+
+drop function if exists list_recurring_transactions_for_user cascade;
+create or replace function list_recurring_transactions_for_user(
+    p_user_id varchar
+)
+    returns setof recurringtransactionpayload
+as
+$$
+begin
+    return query
+        select r.id,
+               r.userid,
+               a.accountnumber,
+               r.categoryid,
+               r.type,
+               r.amount,
+               r.description,
+               r.frequency,
+               r.repeatevery,
+               coalesce(r.dayofweek, 0),
+               coalesce(r.dayofmonth, 0),
+               r.businessdays,
+               r.startdate::timestamptz,
+               r.enddate::timestamptz,
+               recurring_due_date(r, r.nextrunat)::timestamptz,
+               r.status
+        from recurringtransactionmaster r
+                 join accountmaster a on a.id = r.accountid
+        where r.userid = p_user_id
+        order by r.createdat;
+end;
+$$ language plpgsql;
+
+drop function if exists list_recurring_occurrences cascade;
+create or replace function list_recurring_occurrences(
+    p_recurring_id varchar,
+    p_user_id varchar,
+    p_page_number int,
+    p_page_size int
+)
+    returns setof recurringoccurrencepayload
+as
+$$
+begin
+    if p_page_number < 1 then
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
+    end if;
+
+    if p_page_size < 1 then
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
+    end if;
+
+    return query
+        select o.id,
+               o.recurringid,
+               o.userid,
+               o.occurrencedate::timestamptz,
+               o.status,
+               coalesce(o.transactionid, ''),
+               coalesce(o.error, ''),
+               o.createdat
+        from recurringoccurrencemaster o
+        where o.recurringid = p_recurring_id
+          and o.userid = p_user_id
+        order by o.occurrencedate desc
+        limit p_page_size offset (p_page_number - 1) * p_page_size;
+end;
+$$ language plpgsql;
+
+drop function if exists materialize_recurring_transactions cascade;
+create or replace function materialize_recurring_transactions(
+    p_now timestamptz,
+    p_batch_size int,
+    p_max_catch_up int
+)
+    returns setof recurringoccurrencepayload
+as
+$$
+declare
+    today           date := p_now::date;
+    recurring       recurringtransactionmaster;
+    account_number  varchar;
+    occurrence_date date;
+    occurrence      recurringoccurrencemaster;
+    caught_up       int;
+begin
+    if p_batch_size < 1 then
+        raise exception 'Batch size must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    if p_max_catch_up < 1 then
+        raise exception 'Catch-up limit must be greater than 0' using errcode = 'QW304';
+    end if;
+
+    -- skip locked lets several schedulers share the due templates, each one is materialized by a single transaction
+    for recurring in
+        select *
+        from recurringtransactionmaster r
+        where r.status = 'ACTIVE'
+          and r.nextrunat is not null
+          and recurring_due_date(r, r.nextrunat) <= today
+        order by r.nextrunat
+        limit p_batch_size for update skip locked
+        loop
+            select a.accountnumber into account_number from accountmaster a where a.id = recurring.accountid;
+            caught_up := 0;
+
+            -- catch up on the occurrences missed while no scheduler was running, at most p_max_catch_up per call:
+            -- a template that is still due afterwards continues from nextrunat on a later call
+            while recurring.nextrunat is not null
+                and recurring_due_date(recurring, recurring.nextrunat) <= today
+                and caught_up < p_max_catch_up
+                loop
+                    caught_up := caught_up + 1;
+                    occurrence_date := recurring_due_date(recurring, recurring.nextrunat);
+                    occurrence := null;
+
+                    -- skipped occurrences (and business days moved onto the same date) already have a row
+                    insert into recurringoccurrencemaster(recurringid, userid, occurrencedate, status)
+                    values (recurring.id, recurring.userid, occurrence_date, 'PENDING')
+                    on conflict (recurringid, occurrencedate) do nothing
+                    returning * into occurrence;
+
+                    if occurrence.id is not null then
+                        begin
+                            occurrence.transactionid := create_transaction(recurring.userid, account_number, recurring.categoryid,
+                                                                           recurring.type, recurring.amount, recurring.description,
+                                                                           case
+                                                                               when occurrence_date < today then occurrence_date::timestamptz
+                                                                               else p_now end);
+                            occurrence.status := 'COMPLETED';
+                        exception
+                            -- QW000 matches every QWxxx code. Business errors (e.g. insufficient funds) are recorded as failed and
+                            -- not retried; anything else (serialization failures, deadlocks, timeouts) aborts the whole run so it is retried
+                            when sqlstate 'QW000' or check_violation then
+                                occurrence.status := 'FAILED';
+                                occurrence.error := sqlerrm;
+                        end;
+
+                        update recurringoccurrencemaster
+                        set status        = occurrence.status,
+                            transactionid = occurrence.transactionid,
+                            error         = occurrence.error
+                        where id = occurrence.id;
+
+                        return next (occurrence.id, occurrence.recurringid, occurrence.userid, occurrence.occurrencedate::timestamptz,
+                                     occurrence.status, coalesce(occurrence.transactionid, ''), coalesce(occurrence.error, ''),
+                                     occurrence.createdat)::recurringoccurrencepayload;
+                    end if;
+
+                    recurring.nextrunat := recurring_next_date(recurring, recurring.nextrunat + 1);
+                end loop;
+
+            update recurringtransactionmaster
+            set nextrunat = recurring.nextrunat,
+                status    = case when recurring.nextrunat is null then 'ENDED' else status end
+            where id = recurring.id;
+        end loop;
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on recurringtransactionmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on recurringtransactionmaster
+    for each row
+execute function update_updated_at_column();
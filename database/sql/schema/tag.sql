@@ -0,0 +1,203 @@
+drop table if exists TagPayload cascade;
+create table if not exists TagPayload
+(
+    id                varchar     not null,
+    user_id           varchar     not null,
+    name              varchar     not null,
+    transaction_count bigint      not null,
+    updated_at        timestamptz not null
+);
+
+drop function if exists validate_tag cascade;
+create or replace function validate_tag(
+    p_name varchar
+) returns void as
+$$
+begin
+    if p_name is null or btrim(p_name) = '' or length(btrim(p_name)) > 50 then
+        raise exception 'Tag % must be 1 to 50 characters', p_name using errcode = 'QW307';
+    end if;
+end;
+$$ language plpgsql immutable;
+
+drop function if exists transaction_tags cascade;
+create or replace function transaction_tags(
+    p_transaction_id varchar
+) returns varchar[] as
+$$
+begin
+    return array(select g.name
+                 from transactiontagmaster tt
+                          join tagmaster g on g.id = tt.tagid
+                 where tt.transactionid = p_transaction_id
+                 order by lower(g.name));
+end;
+$$ language plpgsql stable;
+
+drop function if exists tagged_transactions cascade;
+create or replace function tagged_transactions(
+    p_user_id varchar,
+    p_tags varchar[],
+    p_match_all boolean
+) returns setof varchar as
+$$
+-- resolves the tags once through idx_tag_user_name and walks idx_transaction_tag_tag_id,
+-- so the lists can filter with a semi join instead of a lookup per transaction
+select tt.transactionid
+from tagmaster g
+         join transactiontagmaster tt on tt.tagid = g.id
+where g.userid = p_user_id
+  and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name))
+group by tt.transactionid
+having not p_match_all
+    or count(*) = (select count(distinct lower(btrim(w.name))) from unnest(p_tags) as w(name));
+$$ language sql stable;
+
+drop function if exists tag_transaction cascade;
+create or replace function tag_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_tags varchar[]
+) returns void as
+$$
+declare
+    transaction_exists bool = false;
+    tag_name           varchar;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    if p_tags is null or cardinality(p_tags) = 0 then
+        raise exception 'At least one tag is required' using errcode = 'QW304';
+    end if;
+
+    foreach tag_name in array p_tags
+        loop
+            perform validate_tag(tag_name);
+        end loop;
+
+    -- tags are created on first use, an existing tag keeps the case it was created with
+    insert into tagmaster(userid, name)
+    select distinct on (lower(btrim(w.name))) p_user_id, btrim(w.name)
+    from unnest(p_tags) as w(name)
+    on conflict (userid, lower(name)) do nothing;
+
+    insert into transactiontagmaster(transactionid, tagid, userid)
+    select p_transaction_id, g.id, p_user_id
+    from tagmaster g
+    where g.userid = p_user_id
+      and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name))
+    on conflict (transactionid, tagid) do nothing;
+end;
+$$ language plpgsql;
+
+drop function if exists untag_transaction cascade;
+create or replace function untag_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_tags varchar[]
+) returns void as
+$$
+declare
+    transaction_exists bool = false;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    -- the tags themselves are kept, even when no transaction uses them anymore
+    delete
+    from transactiontagmaster tt
+        using tagmaster g
+    where tt.tagid = g.id
+      and tt.transactionid = p_transaction_id
+      and g.userid = p_user_id
+      and lower(g.name) in (select lower(btrim(w.name)) from unnest(p_tags) as w(name));
+end;
+$$ language plpgsql;
+
+drop function if exists rename_tag cascade;
+create or replace function rename_tag(
+    p_tag_id varchar,
+    p_user_id varchar,
+    p_name varchar
+) returns setof tagpayload as
+$$
+declare
+    tag_exists bool = false;
+begin
+    select exists(select 1 from tagmaster where id = p_tag_id and userid = p_user_id) into tag_exists;
+    if not tag_exists then
+        raise exception 'Tag % does not exist', p_tag_id using errcode = 'QW110';
+    end if;
+
+    perform validate_tag(p_name);
+
+    if exists(select 1
+              from tagmaster g
+              where g.userid = p_user_id
+                and g.id <> p_tag_id
+                and lower(g.name) = lower(btrim(p_name))) then
+        raise exception 'A tag named % already exists', btrim(p_name) using errcode = 'QW206';
+    end if;
+
+    update tagmaster
+    set name = btrim(p_name)
+    where id = p_tag_id;
+
+    return query
+        select *
+        from list_tags_for_user(p_user_id) t
+        where t.id = p_tag_id;
+end;
+$$ language plpgsql;
+
+drop function if exists delete_tag cascade;
+create or replace function delete_tag(
+    p_tag_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    tag_exists bool = false;
+begin
+    select exists(select 1 from tagmaster where id = p_tag_id and userid = p_user_id) into tag_exists;
+    if not tag_exists then
+        raise exception 'Tag % does not exist', p_tag_id using errcode = 'QW110';
+    end if;
+
+    -- removes the tag from every transaction
+    delete
+    from tagmaster
+    where id = p_tag_id
+      and userid = p_user_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_tags_for_user cascade;
+create or replace function list_tags_for_user(
+    p_user_id varchar
+)
+    returns setof tagpayload
+as
+$$
+begin
+    return query
+        select g.id, g.userid, g.name, count(tt.transactionid), g.updatedat
+        from tagmaster g
+                 left join transactiontagmaster tt on tt.tagid = g.id
+        where g.userid = p_user_id
+        group by g.id
+        order by lower(g.name);
+end;
+$$ language plpgsql;
+
+drop trigger if exists trigger_update_updated_at_column on tagmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on tagmaster
+    for each row
+execute function update_updated_at_column();
@@ -1,3 +1,8 @@
+-- columns added after TransactionMaster was first created (create table if not exists leaves existing databases as they are)
+alter table TransactionMaster
+    add column if not exists Currency varchar(3)     not null default 'GHS',
+    add column if not exists FxRate   numeric(18, 8) not null default 1 check (FxRate > 0);
+
 drop table if exists TransactionPayload cascade;
 create table TransactionPayload
 (
@@ -12,7 +17,55 @@ create table TransactionPayload
     reference_number varchar             not null,
     status           varchar             not null,
     updated_at       timestamptz                  default now(),
-    is_deleted       boolean             not null default false
+    is_deleted       boolean             not null default false,
+    currency         varchar             not null default 'GHS',
+    fx_rate          numeric             not null default 1,
+    base_currency    varchar,
+    base_amount      numeric,
+    tags             varchar[]
+);
+comment on column TransactionPayload.base_amount is 'amount in the base currency of the user as of the transaction, null when there is no exchange rate';
+
+drop table if exists TransferPayload cascade;
+create table TransferPayload
+(
+    debit_transaction_id  varchar not null,
+    credit_transaction_id varchar not null,
+    from_currency         varchar not null,
+    to_currency           varchar not null,
+    fx_rate               numeric not null,
+    debited_amount        numeric not null,
+    credited_amount       numeric not null
+);
+
+drop table if exists TransferEventPayload cascade;
+create table TransferEventPayload
+(
+    user_id               varchar     not null,
+    from_account_number   varchar     not null,
+    to_account_number     varchar     not null,
+    description           text        not null,
+    debit_transaction_id  varchar     not null,
+    credit_transaction_id varchar     not null,
+    from_currency         varchar     not null,
+    to_currency           varchar     not null,
+    fx_rate               numeric     not null,
+    debited_amount        numeric     not null,
+    credited_amount       numeric     not null,
+    completed_at          timestamptz not null
+);
+
+drop table if exists TransactionSplitPayload cascade;
+create table TransactionSplitPayload
+(
+    id             varchar     not null,
+    transaction_id varchar     not null,
+    user_id        varchar     not null,
+    category_id    varchar     not null,
+    amount         numeric     not null,
+    memo           varchar     not null,
+    position       int         not null,
+    updated_at     timestamptz not null
 );
 
 drop function if exists create_transaction cascade;
@@ -22,12 +75,14 @@ create or replace function create_transaction(
     p_category_id varchar,
     p_type varchar,
     p_amount numeric,
-    p_description varchar
-) returns void as
+    p_description varchar,
+    p_created_at timestamptz
+) returns varchar as
 $$
 declare
-    account_id  varchar;
-    category_id varchar;
+    account_id     varchar;
+    category_id    varchar;
+    transaction_id varchar;
 begin
     select a.id
     from accountmaster a
@@ -36,7 +91,7 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     select c.id
@@ -46,11 +101,18 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, account_id, category_id, p_type, p_amount, p_description, p_user_id, gen_random_transaction_ref_number());
+    perform ensure_sufficient_funds(account_id, case when p_type = 'DEBIT' then p_amount else 0 end);
+
+    -- backdated transactions (e.g. recurring occurrences caught up on) count in the period they belong to
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber, createdat)
+    values (p_user_id, account_id, category_id, p_type, p_amount, p_description, p_user_id, gen_random_transaction_ref_number(),
+            coalesce(p_created_at, now()))
+    returning id
+        into transaction_id;
+    return transaction_id;
 end;
 $$ language plpgsql;
 
@@ -61,42 +123,60 @@ create or replace function account_to_account_transfer(
     p_to_account_number varchar,
     p_amount numeric,
     p_description varchar
-) returns void as
+) returns setof transferpayload as
 $$
 declare
-    from_account_id  varchar;
-    to_account_id    varchar;
-    category_id      varchar;
-    from_description varchar;
-    to_description   varchar;
-    user_exists      bool = false;
+    from_account_id       varchar;
+    to_account_id         varchar;
+    from_currency         varchar;
+    to_currency           varchar;
+    transfer_rate         numeric;
+    credited_amount       numeric;
+    category_id           varchar;
+    from_description      varchar;
+    to_description        varchar;
+    debit_transaction_id  varchar;
+    credit_transaction_id varchar;
+    user_exists           bool = false;
+    payload               transfereventpayload;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     if p_amount <= 0 then
-        raise exception 'Amount must be greater than 0';
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     if p_to_account_number = p_from_account_number then
-        raise exception 'Cannot transfer to the same account';
+        raise exception 'Cannot transfer to the same account' using errcode = 'QW303';
     end if;
 
-    select a.id
+    select a.id, a.currency
     from accountmaster a
     where a.accountnumber = p_from_account_number
     limit 1
-    into from_account_id;
-    select a.id
+    into from_account_id, from_currency;
+    select a.id, a.currency
     from accountmaster a
     where a.accountnumber = p_to_account_number
     limit 1
-    into to_account_id;
+    into to_account_id, to_currency;
 
     if from_account_id is null or to_account_id is null then
-        raise exception 'One or both of the accounts do not exist';
+        raise exception 'One or both of the accounts do not exist' using errcode = 'QW102';
+    end if;
+
+    -- the rate used is recorded on both legs of the transfer
+    transfer_rate := fx_rate(from_currency, to_currency, now());
+    if transfer_rate is null then
+        raise exception 'No exchange rate from % to %', from_currency, to_currency using errcode = 'QW109';
+    end if;
+
+    credited_amount := round(p_amount * transfer_rate, currency_scale(to_currency));
+    if credited_amount <= 0 then
+        raise exception 'Amount is too small to be converted to %', to_currency using errcode = 'QW301';
     end if;
 
     select c.id
@@ -107,17 +187,22 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', 'General';
+        raise exception 'Category % does not exist', 'General' using errcode = 'QW103';
     end if;
 
+    perform lock_accounts(array [from_account_id, to_account_id]);
+    perform ensure_sufficient_funds(from_account_id, p_amount);
+
     if p_description is null or p_description = '' then
         to_description := 'Transfer to ' || p_to_account_number;
     else
         to_description := p_description;
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, from_account_id, category_id, 'DEBIT', p_amount, to_description, p_user_id, gen_random_transaction_ref_number());
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, fxrate, description, lasteditby, referencenumber)
+    values (p_user_id, from_account_id, category_id, 'DEBIT', p_amount, transfer_rate, to_description, p_user_id, gen_random_transaction_ref_number())
+    returning id
+        into debit_transaction_id;
 
     if p_description is null or p_description = '' then
         from_description := 'Transfer from ' || p_from_account_number;
@@ -125,8 +210,18 @@ begin
         from_description := p_description;
     end if;
 
-    insert into transactionmaster(userid, accountid, categoryid, type, amount, description, lasteditby, referencenumber)
-    values (p_user_id, to_account_id, category_id, 'CREDIT', p_amount, from_description, p_user_id, gen_random_transaction_ref_number());
+    insert into transactionmaster(userid, accountid, categoryid, type, amount, fxrate, description, lasteditby, referencenumber)
+    values (p_user_id, to_account_id, category_id, 'CREDIT', credited_amount, transfer_rate, from_description, p_user_id, gen_random_transaction_ref_number())
+    returning id
+        into credit_transaction_id;
+
+    select p_user_id, p_from_account_number, p_to_account_number, coalesce(p_description, ''), debit_transaction_id,
+           credit_transaction_id, from_currency, to_currency, transfer_rate, p_amount, credited_amount, now()
+    into payload;
+    perform enqueue_outbox_event('transfers', debit_transaction_id, p_user_id, 'INSERT', to_jsonb(payload));
+
+    return query
+        select debit_transaction_id, credit_transaction_id, from_currency, to_currency, transfer_rate, p_amount, credited_amount;
 end;
 
 $$
@@ -139,11 +234,24 @@ create or replace function delete_transaction(
 ) returns void as
 $$
 declare
+    account_id  varchar;
+    effect      numeric;
     user_exists bool = false;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    -- deleting a credit takes its amount away from the account
+    select t.accountid, balance_effect(t.type, t.amount)
+    into account_id, effect
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id;
+
+    if account_id is not null then
+        perform ensure_sufficient_funds(account_id, effect);
     end if;
 
     delete
@@ -161,16 +269,21 @@ create or replace function update_transaction(
     p_category_id varchar,
     p_type varchar,
     p_amount numeric,
-    p_description varchar
+    p_description varchar,
+    p_split_category_ids varchar[],
+    p_split_amounts numeric[],
+    p_split_memos text[]
 ) returns void as
 $$
 declare
-    account_id  varchar;
-    user_exists bool = false;
+    account_id     varchar;
+    old_account_id varchar;
+    old_effect     numeric;
+    user_exists    bool = false;
 begin
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     select a.id
@@ -180,7 +293,24 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
+    end if;
+
+    select t.accountid, balance_effect(t.type, t.amount)
+    into old_account_id, old_effect
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id;
+
+    -- each account must afford what the edit takes away from it
+    if old_account_id is not null then
+        perform lock_accounts(array [old_account_id, account_id]);
+        if old_account_id = account_id then
+            perform ensure_sufficient_funds(account_id, old_effect - balance_effect(p_type, p_amount));
+        else
+            perform ensure_sufficient_funds(old_account_id, old_effect);
+            perform ensure_sufficient_funds(account_id, -balance_effect(p_type, p_amount));
+        end if;
     end if;
 
     update transactionmaster
@@ -191,6 +321,169 @@ begin
         description = p_description
     where id = p_transaction_id
       and userid = p_user_id;
+
+    if not found then
+        return;
+    end if;
+
+    -- null keeps the current lines, an empty array removes the split
+    if p_split_category_ids is not null then
+        perform replace_transaction_splits(p_transaction_id, p_user_id, p_split_category_ids, p_split_amounts, p_split_memos);
+    end if;
+    perform check_transaction_splits(p_transaction_id);
+end;
+$$ language plpgsql;
+
+drop function if exists replace_transaction_splits cascade;
+create or replace function replace_transaction_splits(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_category_ids varchar[],
+    p_amounts numeric[],
+    p_memos text[]
+) returns void as
+$$
+declare
+    line_count int;
+begin
+    line_count := coalesce(array_length(p_category_ids, 1), 0);
+    if coalesce(array_length(p_amounts, 1), 0) <> line_count or coalesce(array_length(p_memos, 1), 0) <> line_count then
+        raise exception 'Every split line needs a category, an amount and a memo' using errcode = 'QW304';
+    end if;
+
+    for idx in 1..line_count
+        loop
+            if p_amounts[idx] is null or p_amounts[idx] <= 0 then
+                raise exception 'Split line % must have an amount greater than 0', idx using errcode = 'QW301';
+            end if;
+
+            if not exists(select 1 from transactioncategorymaster c where c.id = p_category_ids[idx] and c.userid = p_user_id) then
+                raise exception 'Category % does not exist', p_category_ids[idx] using errcode = 'QW103';
+            end if;
+        end loop;
+
+    delete
+    from transactionsplitmaster
+    where transactionid = p_transaction_id;
+
+    insert into transactionsplitmaster(transactionid, userid, categoryid, amount, memo, position)
+    select p_transaction_id, p_user_id, l.category_id, l.amount, coalesce(l.memo, ''), l.position
+    from unnest(p_category_ids, p_amounts, p_memos) with ordinality as l(category_id, amount, memo, position);
+end;
+$$ language plpgsql;
+
+drop function if exists check_transaction_splits cascade;
+create or replace function check_transaction_splits(
+    p_transaction_id varchar
+) returns void as
+$$
+declare
+    transaction_amount numeric;
+    split_total        numeric;
+begin
+    select t.amount, (select sum(s.amount) from transactionsplitmaster s where s.transactionid = t.id)
+    from transactionmaster t
+    where t.id = p_transaction_id
+    into transaction_amount, split_total;
+
+    -- a transaction without lines is not split
+    if split_total is not null and split_total <> transaction_amount then
+        raise exception 'Split lines add up to % but the transaction amount is %', split_total, transaction_amount using errcode = 'QW306';
+    end if;
+end;
+$$ language plpgsql;
+
+drop function if exists split_transaction cascade;
+create or replace function split_transaction(
+    p_transaction_id varchar,
+    p_user_id varchar,
+    p_category_ids varchar[],
+    p_amounts numeric[],
+    p_memos text[]
+) returns setof transactionsplitpayload as
+$$
+declare
+    user_exists bool = false;
+begin
+    select exists(select 1 from usermaster where id = p_user_id) into user_exists;
+    if not user_exists then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    -- the lock makes concurrent splits of the same transaction replace each other instead of interleaving
+    perform 1
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id
+        for update;
+    if not found then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    if coalesce(array_length(p_category_ids, 1), 0) = 0 then
+        raise exception 'A split needs at least one line' using errcode = 'QW304';
+    end if;
+
+    perform replace_transaction_splits(p_transaction_id, p_user_id, p_category_ids, p_amounts, p_memos);
+    perform check_transaction_splits(p_transaction_id);
+
+    return query
+        select *
+        from list_transaction_splits(p_transaction_id, p_user_id);
+end;
+$$ language plpgsql;
+
+drop function if exists delete_transaction_split cascade;
+create or replace function delete_transaction_split(
+    p_transaction_id varchar,
+    p_user_id varchar
+) returns void as
+$$
+declare
+    user_exists bool = false;
+begin
+    select exists(select 1 from usermaster where id = p_user_id) into user_exists;
+    if not user_exists then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+
+    perform 1
+    from transactionmaster t
+    where t.id = p_transaction_id
+      and t.userid = p_user_id
+        for update;
+    if not found then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    -- the transaction is reported under its own category again
+    delete
+    from transactionsplitmaster
+    where transactionid = p_transaction_id;
+end;
+$$ language plpgsql;
+
+drop function if exists list_transaction_splits cascade;
+create or replace function list_transaction_splits(
+    p_transaction_id varchar,
+    p_user_id varchar
+)
+    returns setof transactionsplitpayload
+as
+$$
+declare
+    transaction_exists bool = false;
+begin
+    select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
+    if not transaction_exists then
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
+    end if;
+
+    return query
+        select s.id, s.transactionid, s.userid, s.categoryid, s.amount, s.memo, s.position, s.updatedat
+        from transactionsplitmaster s
+        where s.transactionid = p_transaction_id
+        order by s.position;
 end;
 $$ language plpgsql;
 
@@ -206,12 +499,12 @@ declare
 begin
     select exists(select 1 from transactionmaster where id = p_transaction_id and userid = p_user_id) into transaction_exists;
     if not transaction_exists then
-        raise exception 'Transaction % does not exist', p_transaction_id;
+        raise exception 'Transaction % does not exist', p_transaction_id using errcode = 'QW106';
     end if;
 
     select exists(select 1 from usermaster where id = p_user_id) into user_exists;
     if not user_exists then
-        raise exception 'User % does not exist', p_user_id;
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
     return query
@@ -226,9 +519,15 @@ begin
                t.referencenumber,
                t.status,
                t.updatedat,
-               false
+               false,
+               t.currency,
+               t.fxrate,
+               u.basecurrency,
+               convert_amount(t.amount, t.currency, u.basecurrency, t.createdat),
+               transaction_tags(t.id)
         from transactionmaster t
                  left join accountmaster a on t.accountid = a.id
+                 join usermaster u on u.id = t.userid
         where t.id = p_transaction_id
           and t.userid = p_user_id;
 end;
@@ -240,18 +539,20 @@ create or replace function list_transactions_for_user(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
 $$
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -263,24 +564,31 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -291,18 +599,20 @@ create or replace function list_transactions_for_user_by_type(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
 $$
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -314,25 +624,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and t.type = p_type
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and t.type = p_type
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -343,7 +660,9 @@ create or replace function list_transactions_for_user_by_category(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 )
     returns setof transactionpayload
 as
@@ -352,11 +671,11 @@ declare
     category_exists bool = false;
 begin
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -369,29 +688,38 @@ begin
 
     select exists(select 1 from transactioncategorymaster where id = p_category_id and userid = p_user_id) into category_exists;
     if not category_exists then
-        raise exception 'Category % does not exist', p_category_id;
+        raise exception 'Category % does not exist', p_category_id using errcode = 'QW103';
     end if;
 
+    -- a split transaction is listed once per line of the category, with the amount and memo of the line
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and t.categoryid = p_category_id
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     coalesce(s.categoryid, t.categoryid),
+                     t.type,
+                     coalesce(s.amount, t.amount),
+                     coalesce(nullif(s.memo, ''), t.description),
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(coalesce(s.amount, t.amount), t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+                       left join transactionsplitmaster s on s.transactionid = t.id
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and coalesce(s.categoryid, t.categoryid) = p_category_id
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by t.updatedat desc, s.position
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -402,7 +730,9 @@ create or replace function list_transactions_for_user_by_account(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 ) returns setof transactionpayload
 as
 $$
@@ -416,15 +746,15 @@ begin
     into account_id;
 
     if account_id is null then
-        raise exception 'Account % does not exist', p_account_number;
+        raise exception 'Account % does not exist', p_account_number using errcode = 'QW102';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -436,25 +766,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.updatedat between p_start_date and p_end_date
-          and a.accountnumber = p_account_number
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.updatedat between p_start_date and p_end_date
+                and a.accountnumber = p_account_number
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -465,7 +802,9 @@ create or replace function list_transactions_for_user_by_goal(
     p_start_date timestamp,
     p_end_date timestamp,
     p_page_number int,
-    p_page_size int
+    p_page_size int,
+    p_tags varchar[],
+    p_match_all_tags boolean
 ) returns setof transactionpayload
 as
 $$
@@ -479,15 +818,15 @@ begin
     into goal_id;
 
     if goal_id is null then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
     end if;
 
     if p_page_number < 1 then
-        raise exception 'Page number must be greater than 0';
+        raise exception 'Page number must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_page_size < 1 then
-        raise exception 'Page size must be greater than 0';
+        raise exception 'Page size must be greater than 0' using errcode = 'QW302';
     end if;
 
     if p_start_date is null then
@@ -499,25 +838,32 @@ begin
     end if;
 
     return query
-        select t.id,
-               t.userid,
-               a.accountnumber,
-               a.name,
-               t.categoryid,
-               t.type,
-               t.amount,
-               t.description,
-               t.referencenumber,
-               t.status,
-               t.updatedat,
-               false
-        from transactionmaster t
-                 left join accountmaster a on t.accountid = a.id
-        where t.userid = p_user_id
-          and t.referencenumber = p_goal_id
-          and t.updatedat between p_start_date and p_end_date
-        order by updatedat desc
-        limit p_page_size offset (p_page_number - 1) * p_page_size;
+        select p.*, transaction_tags(p.id)
+        from (select t.id,
+                     t.userid,
+                     a.accountnumber,
+                     a.name,
+                     t.categoryid,
+                     t.type,
+                     t.amount,
+                     t.description,
+                     t.referencenumber,
+                     t.status,
+                     t.updatedat,
+                     false,
+                     t.currency,
+                     t.fxrate,
+                     u.basecurrency,
+                     convert_amount(t.amount, t.currency, u.basecurrency, t.createdat)
+              from transactionmaster t
+                       left join accountmaster a on t.accountid = a.id
+                       join usermaster u on u.id = t.userid
+              where t.userid = p_user_id
+                and t.referencenumber = p_goal_id
+                and t.updatedat between p_start_date and p_end_date
+                and (coalesce(cardinality(p_tags), 0) = 0 or t.id in (select tagged_transactions(p_user_id, p_tags, p_match_all_tags)))
+              order by updatedat desc
+              limit p_page_size offset (p_page_number - 1) * p_page_size) p;
 end;
 $$ language plpgsql;
 
@@ -531,13 +877,18 @@ create or replace function contribute_to_goal(
 ) returns varchar as
 $$
 declare
-    category_id    varchar;
-    account_id     varchar;
-    goal_exists    boolean = false;
-    transaction_id varchar;
+    category_id      varchar;
+    account_id       varchar;
+    account_currency varchar;
+    goal_currency    varchar;
+    goal_status      varchar;
+    goal_exists      boolean = false;
+    transaction_id   varchar;
+    goal             goalpayload;
+    contribution     goalcontributionpayload;
 begin
     if p_amount <= 0 then
-        raise exception 'Amount must be greater than 0';
+        raise exception 'Amount must be greater than 0' using errcode = 'QW301';
     end if;
 
     select c.id
@@ -548,26 +899,41 @@ begin
     into category_id;
 
     if category_id is null then
-        raise exception 'Category % does not exist', 'Goals';
+        raise exception 'Category % does not exist', 'Goals' using errcode = 'QW103';
     end if;
 
-    select a.id
+    select a.id, a.currency
     from accountmaster a
     where a.userid = p_user_id
       and a.accountnumber = p_account_number
     limit 1
-    into account_id;
+    into account_id, account_currency;
 
     if account_id is null then
-        raise exception 'Account does not exist';
+        raise exception 'Account does not exist' using errcode = 'QW102';
     end if;
 
     select exists(select 1 from goalmaster g where g.id = p_goal_id and g.userid = p_user_id) into goal_exists;
 
     if goal_exists = false then
-        raise exception 'Goal % does not exist', p_goal_id;
+        raise exception 'Goal % does not exist', p_goal_id using errcode = 'QW104';
     end if;
 
+    -- locked so that only one of concurrent contributions completes the goal
+    select g.currency, g.status
+    into goal_currency, goal_status
+    from goalmaster g
+    where g.id = p_goal_id
+        for update;
+
+    -- the goal balance converts the contribution into the currency of the goal
+    if fx_rate(account_currency, goal_currency, now()) is null then
+        raise exception 'No exchange rate from % to %', account_currency, goal_currency using errcode = 'QW109';
+    end if;
+
+    -- both legs are on the account, the debit still needs the funds
+    perform ensure_sufficient_funds(account_id, p_amount);
+
     insert into transactionmaster(userid, categoryid, type, amount, description, lasteditby, accountid, referencenumber)
     values (p_user_id, category_id, 'DEBIT', p_amount, p_description, p_user_id, account_id, gen_random_transaction_ref_number());
 
@@ -575,10 +941,49 @@ begin
     values (p_user_id, category_id, 'CREDIT', p_amount, p_description, p_user_id, account_id, p_goal_id)
     returning id into transaction_id;
 
+    -- the credit updated the goal (see update_goal_balance)
+    select g.id, g.name, g.target, g.description, g.balance, g.userid, false, g.currency
+    into goal
+    from goalmaster g
+    where g.id = p_goal_id;
+
+    select goal.id, goal.name, goal.target, goal.description, goal.balance, goal.user_id, goal.currency, p_amount,
+           account_currency, p_account_number, transaction_id, now()
+    into contribution;
+    perform enqueue_outbox_event('goal_contributions', transaction_id, p_user_id, 'INSERT', to_jsonb(contribution));
+
+    if goal_status <> 'COMPLETED' and (select g.status from goalmaster g where g.id = p_goal_id) = 'COMPLETED' then
+        perform enqueue_outbox_event('goal_completions', goal.id, p_user_id, 'INSERT', to_jsonb(goal));
+    end if;
+
     return transaction_id;
 end;
 $$ language plpgsql;
 
+drop function if exists set_transaction_currency cascade;
+create or replace function set_transaction_currency()
+    returns trigger as
+$$
+declare
+    account_currency varchar;
+begin
+    -- a transaction is always in the currency of its account
+    select a.currency
+    from accountmaster a
+    where a.id = new.accountid
+    into account_currency;
+
+    -- the amount is not converted, so it can only move between accounts of the same currency
+    if tg_op = 'UPDATE' and account_currency is distinct from old.currency then
+        raise exception 'Cannot move transaction % from % to an account in %', old.id, old.currency, account_currency
+            using errcode = 'QW305';
+    end if;
+
+    new.currency := account_currency;
+    return new;
+end;
+$$ language plpgsql;
+
 drop function if exists notify_transactions cascade;
 create or replace function notify_transactions() returns trigger as
 $$
@@ -607,7 +1012,12 @@ begin
                    old.referencenumber,
                    old.status,
                    old.updatedat,
-                   true
+                   true,
+                   old.currency,
+                   old.fxrate,
+                   null,
+                   null,
+                   null
             into payload;
         end if;
     else
@@ -629,11 +1039,17 @@ begin
                    new.referencenumber,
                    new.status,
                    new.updatedat,
-                   false
+                   false,
+                   new.currency,
+                   new.fxrate,
+                   null,
+                   null,
+                   null
             into payload;
         end if;
     end if;
 
+    perform enqueue_outbox_event('transactions', payload.id, payload.user_id, tg_op, to_jsonb(payload));
     perform pg_notify('transactions', row_to_json(payload)::text);
     return new;
 end;
@@ -646,9 +1062,23 @@ create or replace trigger trigger_update_updated_at_column
     for each row
 execute function update_updated_at_column();
 
+drop trigger if exists trigger_update_updated_at_column on transactionsplitmaster cascade;
+create or replace trigger trigger_update_updated_at_column
+    before update
+    on transactionsplitmaster
+    for each row
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_set_transaction_currency on transactionmaster cascade;
+create or replace trigger trigger_set_transaction_currency
+    before insert or update of accountid
+    on transactionmaster
+    for each row
+execute function set_transaction_currency();
+
 drop trigger if exists trigger_recompute_account_balance on transactionmaster cascade;
 create or replace trigger trigger_recompute_account_balance
-    after insert
+    after insert or update of accountid, type, amount or delete
     on transactionmaster
     for each row
 execute function recompute_account_balance();
@@ -1,3 +1,7 @@
+-- columns added after UserMaster was first created (create table if not exists leaves existing databases as they are)
+alter table UserMaster
+    add column if not exists BaseCurrency varchar(3) not null default 'GHS' check (BaseCurrency ~ '^[A-Z]{3}$');
+
 drop table if exists UserStats cascade;
 create table if not exists UserStats
 (
@@ -8,8 +12,10 @@ create table if not exists UserStats
     account_balance    numeric not null,
     account_number     varchar not null,
     total_income       numeric not null,
-    total_expense      numeric not null
+    total_expense      numeric not null,
+    base_currency      varchar not null
 );
+comment on column UserStats.account_balance is 'balances, income and expenses are converted into the base currency of the user';
 
 drop table if exists UserPayload cascade;
 create table if not exists UserPayload
@@ -65,7 +71,7 @@ begin
     select exists(select 1 from usermaster u where u.email = p_email)
     into exists;
     if exists then
-        raise exception 'User with email % already exists', p_email;
+        raise exception 'User with email % already exists', p_email using errcode = 'QW204';
     else
         if p_password is null or length(p_password) = 0 then
             raise notice 'Password is null, setting default password as empty string';
@@ -117,10 +123,10 @@ begin
                 where u.id = p_user_id
                 limit 1;
         else
-            raise exception 'Invalid password';
+            raise exception 'Invalid password' using errcode = 'QW501';
         end if;
     else
-        raise exception 'User with id % does not exist', p_user_id;
+        raise exception 'User with id % does not exist', p_user_id using errcode = 'QW101';
     end if;
 
 end;
@@ -143,7 +149,7 @@ declare
 begin
     -- check if user authentication token is passed
     if p_auth_id is null or length(p_auth_id) = 0 then
-        raise exception 'Login failed. Auth token is required';
+        raise exception 'Login failed. Auth token is required' using errcode = 'QW304';
     end if;
 
     -- check if user already exists
@@ -151,7 +157,7 @@ begin
 
     if not user_exists then
         if p_name is null or length(p_name) = 0 then
-            raise exception 'Login failed. User display name is required';
+            raise exception 'Login failed. User display name is required' using errcode = 'QW304';
         end if;
 
         insert into usermaster(name, email, authid, phonenumber)
@@ -223,7 +229,7 @@ declare
     password_hash varchar;
 begin
     if p_password is null or length(p_password) = 0 then
-        raise exception 'Password cannot be null or empty';
+        raise exception 'Password cannot be null or empty' using errcode = 'QW304';
     else
         raise notice 'Hashing password';
         password_hash := hash_password(p_password);
@@ -289,7 +295,26 @@ begin
         return query
             select p_user_id, p_email, p_name, p_phone_number, p_avatar_url, false;
     else
-        raise exception 'User with id % does not exist', p_user_id;
+        raise exception 'User with id % does not exist', p_user_id using errcode = 'QW101';
+    end if;
+end;
+$$ language plpgsql;
+
+drop function if exists update_user_base_currency cascade;
+create or replace function update_user_base_currency(
+    p_user_id varchar,
+    p_currency varchar
+) returns void as
+$$
+begin
+    perform validate_currency(p_currency);
+
+    update usermaster
+    set basecurrency = p_currency
+    where id = p_user_id;
+
+    if not found then
+        raise exception 'User % does not exist', p_user_id using errcode = 'QW101';
     end if;
 end;
 $$ language plpgsql;
@@ -322,31 +347,68 @@ create or replace function get_user_stats(
 as
 $$
 declare
-    user_id varchar;
+    user_id       varchar;
+    base_currency varchar;
+    missing_rate  varchar;
 begin
-    select u.id
+    select u.id, u.basecurrency
     from usermaster u
     where u.email = p_user_email
     limit 1
-    into user_id;
+    into user_id, base_currency;
 
     if user_id is null then
-        raise exception 'User % does not exist', p_user_email;
+        raise exception 'User % does not exist', p_user_email using errcode = 'QW101';
+    end if;
+
+    -- totals are reported in the base currency, so every other currency of the user needs a rate
+    select c.currency
+    from (select a.currency from accountmaster a where a.userid = user_id
+          union
+          select t.currency from transactionmaster t where t.userid = user_id) c
+    where fx_rate(c.currency, base_currency, now()) is null
+    limit 1
+    into missing_rate;
+
+    if missing_rate is not null then
+        raise exception 'No exchange rate from % to %', missing_rate, base_currency using errcode = 'QW109';
     end if;
 
     return query
-        select (select count(*) from accountmaster a where a.userid = user_id)                                             as total_accounts,
-               (select count(*) from transactionmaster t where t.userid = user_id)                                         as total_transactions,
-               (select count(*) from transactioncategorymaster c where c.userid = user_id)                                 as total_categories,
-               (select count(*) from goalmaster g where g.userid = user_id)                                                as total_goals,
-               (select coalesce(sum(a.balance), 0) from accountmaster a where a.userid = user_id)                          as total_account_balance,
-               (select a.accountnumber from accountmaster a where a.userid = user_id limit 1)                              as account_number,
-               (select coalesce(sum(t.amount), 0) from transactionmaster t where t.userid = user_id and t.type = 'CREDIT') as total_income,
-               (select coalesce(sum(t.amount), 0) from transactionmaster t where t.userid = user_id and t.type = 'DEBIT')  as total_expenses;
+        select (select count(*) from accountmaster a where a.userid = user_id)                                                                                                 as total_accounts,
+               (select count(*) from transactionmaster t where t.userid = user_id)                                                                                             as total_transactions,
+               (select count(*) from transactioncategorymaster c where c.userid = user_id)                                                                                     as total_categories,
+               (select count(*) from goalmaster g where g.userid = user_id)                                                                                                    as total_goals,
+               (select coalesce(sum(convert_amount(a.balance, a.currency, base_currency, now())), 0) from accountmaster a where a.userid = user_id)                              as total_account_balance,
+               (select a.accountnumber from accountmaster a where a.userid = user_id limit 1)                                                                                  as account_number,
+               (select coalesce(sum(convert_amount(t.amount, t.currency, base_currency, t.createdat)), 0) from transactionmaster t where t.userid = user_id and t.type = 'CREDIT') as total_income,
+               (select coalesce(sum(convert_amount(t.amount, t.currency, base_currency, t.createdat)), 0) from transactionmaster t where t.userid = user_id and t.type = 'DEBIT')  as total_expenses,
+               base_currency;
 end;
 
 $$ language plpgsql;
 
+drop function if exists notify_users cascade;
+create or replace function notify_users()
+    returns trigger as
+$$
+declare
+    payload userpayload;
+begin
+    if tg_op = 'DELETE' then
+        select old.id, old.email, old.name, coalesce(old.phonenumber, ''), coalesce(old.avatarurl, ''), true
+        into payload;
+    else
+        select new.id, new.email, new.name, coalesce(new.phonenumber, ''), coalesce(new.avatarurl, ''), false
+        into payload;
+    end if;
+
+    -- the events of a user are keyed by the user itself
+    perform enqueue_outbox_event('users', payload.id, payload.id, tg_op, to_jsonb(payload));
+    return new;
+end;
+$$ language plpgsql;
+
 drop trigger if exists trigger_create_account_for_new_user on usermaster cascade;
 create or replace trigger trigger_create_account_for_new_user
     after insert
@@ -373,4 +435,11 @@ create or replace trigger trigger_update_updated_at_column
     before update
     on usermaster
     for each row
-execute function update_updated_at_column();
\ No newline at end of file
+execute function update_updated_at_column();
+
+drop trigger if exists trigger_notify_users on usermaster cascade;
+create or replace trigger trigger_notify_users
+    after insert or update of name, email, phonenumber, avatarurl or delete
+    on usermaster
+    for each row
+execute function notify_users();
\ No newline at end of file
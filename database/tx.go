@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"math/rand"
+	"time"
+)
+
+// SQLSTATE codes of transaction failures that succeed when the transaction is retried
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// Executor runs queries on a pooled connection or inside a transaction.
+// Repositories are built on an `Executor`, so the same repository works in and out of `InTx`.
+type Executor interface {
+	Execute(ctx context.Context, fn func(q gen.Querier) error) error
+}
+
+// ensure the `DB` can back a repository directly
+var _ Executor = (*DB)(nil)
+
+// Execute acquires a connection for the duration of fn
+func (db *DB) Execute(ctx context.Context, fn func(q gen.Querier) error) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return fn(gen.New(conn))
+}
+
+// boundExecutor runs every call on the same querier (e.g. the one of a transaction)
+type boundExecutor struct {
+	q gen.Querier
+}
+
+// Bind returns an `Executor` that runs every call on the given querier.
+// Use it to build repositories that take part in an `InTx` transaction.
+func Bind(q gen.Querier) Executor {
+	return &boundExecutor{q: q}
+}
+
+func (e *boundExecutor) Execute(_ context.Context, fn func(q gen.Querier) error) error {
+	return fn(e.q)
+}
+
+// TxQuerier is the querier passed to the function of `InTx`.
+// Side effects that must only happen once the transaction committed (e.g. cache invalidation) are deferred with `AfterCommit`.
+type TxQuerier interface {
+	gen.Querier
+
+	// AfterCommit runs fn once the transaction committed, fn is dropped if it rolls back
+	AfterCommit(fn func())
+}
+
+// txQuerier runs queries in a transaction and collects its after-commit functions
+type txQuerier struct {
+	gen.Querier
+	afterCommit []func()
+}
+
+func (q *txQuerier) AfterCommit(fn func()) {
+	q.afterCommit = append(q.afterCommit, fn)
+}
+
+// TxOptions configures a transaction started with `InTx`
+type TxOptions struct {
+	// IsoLevel is the isolation level (defaults to the server default, usually read committed)
+	IsoLevel pgx.TxIsoLevel
+
+	// AccessMode makes the transaction read-only when set to `pgx.ReadOnly`
+	AccessMode pgx.TxAccessMode
+
+	// MaxAttempts is how often the transaction is run when it fails with a serialization
+	// failure or a deadlock (defaults to 3)
+	MaxAttempts int
+
+	// Backoff is the base wait between attempts, doubled after each one (defaults to 20ms)
+	Backoff time.Duration
+}
+
+// InTx runs fn in a transaction. The transaction is committed when fn returns nil and rolled back otherwise.
+// Serialization failures and deadlocks are retried, so fn must not have side effects outside the database;
+// defer them with `TxQuerier.AfterCommit` instead (the querier passed to fn is always a `TxQuerier`).
+func (db *DB) InTx(ctx context.Context, opts TxOptions, fn func(q gen.Querier) error) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 20 * time.Millisecond
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = db.runTx(ctx, opts, fn); err == nil || !IsRetryable(err) || attempt >= opts.MaxAttempts {
+			return err
+		}
+
+		backoff := opts.Backoff << (attempt - 1)
+		backoff += time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runTx runs a single attempt of a transaction
+func (db *DB) runTx(ctx context.Context, opts TxOptions, fn func(q gen.Querier) error) (err error) {
+	tx, err := db.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: opts.IsoLevel, AccessMode: opts.AccessMode})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+		if err != nil {
+			// the rollback must happen even if the caller's context was cancelled
+			if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				err = errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+			}
+		}
+	}()
+
+	q := &txQuerier{Querier: gen.New(tx)}
+	if err = fn(q); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	for _, f := range q.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// IsRetryable reports whether the error is a serialization failure or a deadlock
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
@@ -0,0 +1,10 @@
+// Package errors defines the domain errors shared by the qwallet services.
+//
+// The stored functions raise their failures with custom SQLSTATE codes (class "QW"),
+// which `FromDB` translates into the sentinels below. Every sentinel also belongs to
+// a broader kind (`ErrNotFound`, `ErrAlreadyExists`, ...), so callers can branch on
+// either with the standard library's `errors.Is`:
+//
+//	if errors.Is(err, qerrors.ErrInsufficientFunds) { ... }
+//	if errors.Is(err, qerrors.ErrNotFound) { ... }
+package errors
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"errors"
+)
+
+// Kinds group the domain errors by what went wrong, independent of the entity involved
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrPermissionDenied   = errors.New("permission denied")
+)
+
+// Domain errors, each matching a custom SQLSTATE code raised by the stored functions
+var (
+	ErrUserNotFound        = newError("user not found", ErrNotFound)
+	ErrAccountNotFound     = newError("account not found", ErrNotFound)
+	ErrCategoryNotFound    = newError("category not found", ErrNotFound)
+	ErrGoalNotFound        = newError("goal not found", ErrNotFound)
+	ErrBeneficiaryNotFound = newError("beneficiary not found", ErrNotFound)
+	ErrTransactionNotFound = newError("transaction not found", ErrNotFound)
+	ErrBudgetNotFound      = newError("budget not found", ErrNotFound)
+	ErrRecurringNotFound   = newError("recurring transaction not found", ErrNotFound)
+	ErrFxRateNotFound      = newError("exchange rate not found", ErrNotFound)
+	ErrTagNotFound         = newError("tag not found", ErrNotFound)
+
+	ErrDuplicateAccountName = newError("an account with this name already exists", ErrAlreadyExists)
+	ErrDuplicateBeneficiary = newError("a beneficiary for this account already exists", ErrAlreadyExists)
+	ErrDuplicateGoal        = newError("a goal with this name already exists", ErrAlreadyExists)
+	ErrDuplicateUser        = newError("a user with this email already exists", ErrAlreadyExists)
+	ErrDuplicateBudget      = newError("this category already has a budget", ErrAlreadyExists)
+	ErrDuplicateTag         = newError("a tag with this name already exists", ErrAlreadyExists)
+
+	ErrInvalidAmount     = newError("amount must be greater than 0", ErrInvalidArgument)
+	ErrInvalidPage       = newError("page number and size must be greater than 0", ErrInvalidArgument)
+	ErrSameAccount       = newError("cannot transfer to the same account", ErrInvalidArgument)
+	ErrMissingField      = newError("a required field is missing", ErrInvalidArgument)
+	ErrInvalidCurrency   = newError("currency must be a 3-letter ISO 4217 code", ErrInvalidArgument)
+	ErrSplitMismatch     = newError("split lines must add up to the transaction amount", ErrInvalidArgument)
+	ErrInvalidTag        = newError("tag must be 1 to 50 characters", ErrInvalidArgument)
+	ErrInsufficientFunds = newError("insufficient funds", ErrFailedPrecondition)
+
+	ErrInvalidPassword = newError("invalid password", ErrUnauthenticated)
+)
+
+// domainError is a sentinel that also matches its kind
+type domainError struct {
+	msg  string
+	kind error
+}
+
+func newError(msg string, kind error) error {
+	return &domainError{msg: msg, kind: kind}
+}
+
+func (e *domainError) Error() string {
+	return e.msg
+}
+
+func (e *domainError) Unwrap() error {
+	return e.kind
+}
@@ -0,0 +1,121 @@
+package errors
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes raised by the stored functions in `init.sql` (`raise exception ... using errcode = '...'`).
+// Every code is in the QW class and its hundreds give the kind of error, so each kind has room for 99 codes:
+//
+//	QW1xx not found
+//	QW2xx already exists
+//	QW3xx invalid argument
+//	QW4xx failed precondition
+//	QW5xx unauthenticated
+//
+// New codes take the next free number of their block. `when sqlstate 'QW000'` catches the whole class in PL/pgSQL.
+const (
+	CodeUserNotFound        = "QW101"
+	CodeAccountNotFound     = "QW102"
+	CodeCategoryNotFound    = "QW103"
+	CodeGoalNotFound        = "QW104"
+	CodeBeneficiaryNotFound = "QW105"
+	CodeTransactionNotFound = "QW106"
+	CodeBudgetNotFound      = "QW107"
+	CodeRecurringNotFound   = "QW108"
+	CodeFxRateNotFound      = "QW109"
+	CodeTagNotFound         = "QW110"
+
+	CodeDuplicateAccountName = "QW201"
+	CodeDuplicateBeneficiary = "QW202"
+	CodeDuplicateGoal        = "QW203"
+	CodeDuplicateUser        = "QW204"
+	CodeDuplicateBudget      = "QW205"
+	CodeDuplicateTag         = "QW206"
+
+	CodeInvalidAmount   = "QW301"
+	CodeInvalidPage     = "QW302"
+	CodeSameAccount     = "QW303"
+	CodeMissingField    = "QW304"
+	CodeInvalidCurrency = "QW305"
+	CodeSplitMismatch   = "QW306"
+	CodeInvalidTag      = "QW307"
+
+	CodeInsufficientFunds = "QW401"
+
+	CodeInvalidPassword = "QW501"
+)
+
+// Standard SQLSTATE codes that are translated as well
+const (
+	codeUniqueViolation = "23505"
+	codeCheckViolation  = "23514"
+)
+
+var byCode = map[string]error{
+	CodeUserNotFound:         ErrUserNotFound,
+	CodeAccountNotFound:      ErrAccountNotFound,
+	CodeCategoryNotFound:     ErrCategoryNotFound,
+	CodeGoalNotFound:         ErrGoalNotFound,
+	CodeBeneficiaryNotFound:  ErrBeneficiaryNotFound,
+	CodeTransactionNotFound:  ErrTransactionNotFound,
+	CodeBudgetNotFound:       ErrBudgetNotFound,
+	CodeRecurringNotFound:    ErrRecurringNotFound,
+	CodeFxRateNotFound:       ErrFxRateNotFound,
+	CodeTagNotFound:          ErrTagNotFound,
+	CodeDuplicateAccountName: ErrDuplicateAccountName,
+	CodeDuplicateBeneficiary: ErrDuplicateBeneficiary,
+	CodeDuplicateGoal:        ErrDuplicateGoal,
+	CodeDuplicateUser:        ErrDuplicateUser,
+	CodeDuplicateBudget:      ErrDuplicateBudget,
+	CodeDuplicateTag:         ErrDuplicateTag,
+	CodeInvalidAmount:        ErrInvalidAmount,
+	CodeInvalidPage:          ErrInvalidPage,
+	CodeSameAccount:          ErrSameAccount,
+	CodeInsufficientFunds:    ErrInsufficientFunds,
+	CodeMissingField:         ErrMissingField,
+	CodeInvalidCurrency:      ErrInvalidCurrency,
+	CodeSplitMismatch:        ErrSplitMismatch,
+	CodeInvalidTag:           ErrInvalidTag,
+	CodeInvalidPassword:      ErrInvalidPassword,
+	codeUniqueViolation:      ErrAlreadyExists,
+	codeCheckViolation:       ErrInvalidArgument,
+}
+
+// dbError keeps the database message while matching both the domain error and the original error
+type dbError struct {
+	target error
+	cause  error
+	msg    string
+}
+
+func (e *dbError) Error() string {
+	return e.msg
+}
+
+func (e *dbError) Unwrap() []error {
+	return []error{e.target, e.cause}
+}
+
+// FromDB translates a database error into a domain error.
+// `pgx.ErrNoRows` becomes `ErrNotFound`; errors it does not know are returned unchanged.
+func FromDB(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &dbError{target: ErrNotFound, cause: err, msg: ErrNotFound.Error()}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	target, ok := byCode[pgErr.Code]
+	if !ok {
+		return err
+	}
+	return &dbError{target: target, cause: err, msg: pgErr.Message}
+}
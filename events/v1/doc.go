@@ -0,0 +1,7 @@
+// Package eventsv1 holds the protobuf payloads of the events in the broker topic catalog
+// (see `broker.Catalog`). The message name of a payload is the `event-type` header of its events.
+//
+// Regenerate the code from the repository root after changing a .proto file:
+//
+//	protoc -I . --go_out=. --go_opt=paths=source_relative events/v1/*.proto
+package eventsv1
@@ -0,0 +1,7 @@
+// Package fx keeps the exchange rates used to convert amounts between account currencies.
+//
+// Rates are stored in `FxRateMaster` with the time they were quoted, so amounts can be converted
+// as of a date (`fx_rate` in the database, `IFxRateRepository.GetRate` in Go). A `Provider` quotes
+// the rates: `StaticProvider` and `FileProvider` stand in until a market data provider is plugged in.
+// `Refresher` copies the rates of a provider into the store on an interval.
+package fx
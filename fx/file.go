@@ -0,0 +1,62 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"os"
+	"time"
+)
+
+// FileProvider quotes the rates of a JSON file, which is read again on every call so it can be edited in place:
+//
+//	{
+//	  "as_of": "2024-05-01T00:00:00Z",
+//	  "rates": {"USD/GHS": "14.85", "GHS/KES": 9.12}
+//	}
+//
+// Without "as_of" the rates are quoted as of the modification time of the file.
+type FileProvider struct {
+	path string
+}
+
+// ensure `FileProvider` implements the `Provider` interface
+var _ Provider = (*FileProvider)(nil)
+
+// rateFile is the content of a rate file
+type rateFile struct {
+	AsOf  time.Time             `json:"as_of"`
+	Rates map[string]money.Rate `json:"rates"`
+}
+
+// NewFileProvider creates a provider quoting the rates of the file at `path`
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name implements the `Provider` interface
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Rates implements the `Provider` interface
+func (p *FileProvider) Rates(context.Context) ([]Quote, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("fx: failed to read rate file: %w", err)
+	}
+
+	var file rateFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fx: failed to parse rate file %s: %w", p.path, err)
+	}
+	if file.AsOf.IsZero() {
+		info, err := os.Stat(p.path)
+		if err != nil {
+			return nil, fmt.Errorf("fx: failed to read rate file: %w", err)
+		}
+		file.AsOf = info.ModTime()
+	}
+	return quotes(file.Rates, file.AsOf)
+}
@@ -0,0 +1,73 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Quote is an exchange rate quoted by a provider, one unit of `Base` buys `Rate` of `Quote`
+type Quote struct {
+	Base  string
+	Quote string
+	Rate  money.Rate
+
+	// AsOf is when the rate was quoted (the zero time is when it is stored)
+	AsOf time.Time
+}
+
+// Provider quotes exchange rates, e.g. from a market data API
+type Provider interface {
+	// Name identifies the provider, it is stored as the source of its rates
+	Name() string
+
+	// Rates returns the current rates
+	Rates(ctx context.Context) ([]Quote, error)
+}
+
+// ParsePair parses a currency pair written as "USD/GHS" into its base and quote currencies
+func ParsePair(pair string) (base, quote string, err error) {
+	base, quote, ok := strings.Cut(strings.TrimSpace(pair), "/")
+	if !ok || !IsCurrency(base) || !IsCurrency(quote) || base == quote {
+		return "", "", fmt.Errorf("fx: invalid currency pair %q", pair)
+	}
+	return base, quote, nil
+}
+
+// IsCurrency reports whether the code looks like an ISO 4217 currency code (three upper-case letters)
+func IsCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// quotes converts rates keyed by currency pair into quotes, sorted by pair
+func quotes(rates map[string]money.Rate, asOf time.Time) ([]Quote, error) {
+	result := make([]Quote, 0, len(rates))
+	for pair, rate := range rates {
+		base, quote, err := ParsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("fx: rate of %s must be greater than 0", pair)
+		}
+		result = append(result, Quote{Base: base, Quote: quote, Rate: rate, AsOf: asOf})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Base != result[j].Base {
+			return result[i].Base < result[j].Base
+		}
+		return result[i].Quote < result[j].Quote
+	})
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"log/slog"
+	"time"
+)
+
+// Config configures the refresher
+type Config struct {
+	// Interval is how often the rates of the provider are fetched (defaults to 1h)
+	Interval time.Duration
+
+	// Logger receives the refresher records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// DefaultConfig returns the default refresher configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval: time.Hour,
+	}
+}
+
+// Refresher copies the rates of a provider into the rate store
+type Refresher struct {
+	provider Provider
+	rates    interfaces.IFxRateRepository
+	cfg      Config
+}
+
+// NewRefresher creates a new refresher
+func NewRefresher(provider Provider, rates interfaces.IFxRateRepository, cfg Config) *Refresher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultConfig().Interval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Refresher{provider: provider, rates: rates, cfg: cfg}
+}
+
+// Run refreshes the rates every interval until the context is cancelled
+func (r *Refresher) Run(ctx context.Context) error {
+	r.cfg.Logger.InfoContext(ctx, "fx refresher started",
+		slog.String("provider", r.provider.Name()),
+		slog.Duration("interval", r.cfg.Interval),
+	)
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RefreshOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				r.cfg.Logger.InfoContext(ctx, "fx refresher stopped")
+				return nil
+			}
+			// the stored rates keep being used until the next tick succeeds
+			r.cfg.Logger.ErrorContext(ctx, "fx refresher: failed to refresh rates",
+				slog.String("provider", r.provider.Name()),
+				slog.Any("error", err),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.cfg.Logger.InfoContext(ctx, "fx refresher stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshOnce stores the current rates of the provider and returns the number of rates stored.
+// Rates without a quote time that did not change since the last refresh are not stored again.
+func (r *Refresher) RefreshOnce(ctx context.Context) (int, error) {
+	quotes, err := r.provider.Rates(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch rates from %s: %w", r.provider.Name(), err)
+	}
+
+	stored, err := r.rates.GetRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	latest := make(map[string]Quote, len(stored))
+	for _, s := range stored {
+		latest[s.BaseCurrency+"/"+s.QuoteCurrency] = Quote{Base: s.BaseCurrency, Quote: s.QuoteCurrency, Rate: s.Rate, AsOf: s.AsOf}
+	}
+
+	saved := 0
+	for _, q := range quotes {
+		if last, ok := latest[q.Base+"/"+q.Quote]; ok && last.Rate == q.Rate && (q.AsOf.IsZero() || !q.AsOf.After(last.AsOf)) {
+			continue
+		}
+		if _, err = r.rates.SaveRate(ctx, interfaces.SaveFxRateParams{
+			BaseCurrency:  q.Base,
+			QuoteCurrency: q.Quote,
+			Rate:          q.Rate,
+			Source:        r.provider.Name(),
+			AsOf:          q.AsOf,
+		}); err != nil {
+			return saved, fmt.Errorf("failed to store rate %s/%s: %w", q.Base, q.Quote, err)
+		}
+		saved++
+	}
+	return saved, nil
+}
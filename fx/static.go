@@ -0,0 +1,34 @@
+package fx
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"time"
+)
+
+// StaticProvider quotes a fixed set of rates, e.g. in development or until a market data provider is set up
+type StaticProvider struct {
+	quotes []Quote
+}
+
+// ensure `StaticProvider` implements the `Provider` interface
+var _ Provider = (*StaticProvider)(nil)
+
+// NewStaticProvider creates a provider quoting the given rates, keyed by currency pair (e.g. "USD/GHS")
+func NewStaticProvider(rates map[string]money.Rate) (*StaticProvider, error) {
+	q, err := quotes(rates, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return &StaticProvider{quotes: q}, nil
+}
+
+// Name implements the `Provider` interface
+func (p *StaticProvider) Name() string {
+	return "static"
+}
+
+// Rates implements the `Provider` interface
+func (p *StaticProvider) Rates(context.Context) ([]Quote, error) {
+	return append([]Quote(nil), p.quotes...), nil
+}
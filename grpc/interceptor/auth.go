@@ -0,0 +1,136 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"github.com/qwallet-expense-tracker/shared/auth"
+	"github.com/qwallet-expense-tracker/shared/database/interfaces"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+)
+
+// AuthOptions configures the auth interceptors
+type AuthOptions struct {
+	// Verifier validates the bearer token of each call
+	Verifier auth.Verifier
+
+	// Users resolves the token's subject (or verified email) into a user
+	Users interfaces.IUserRepository
+
+	// PublicMethods are full method names (or prefixes) that can be called without a token
+	PublicMethods []string
+
+	// Logger receives the failures to resolve a user (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// AuthUnaryInterceptor authenticates unary calls and puts the caller's `auth.Principal` in the context
+func AuthUnaryInterceptor(opts AuthOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic(info.FullMethod, opts.PublicMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor authenticates streams and puts the caller's `auth.Principal` in the stream context
+func AuthStreamInterceptor(opts AuthOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod, opts.PublicMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), opts)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate verifies the bearer token of the call and resolves its user
+func authenticate(ctx context.Context, opts AuthOptions) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := opts.Verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	user, err := resolveUser(ctx, opts.Users, claims)
+	if err != nil {
+		if errors.Is(err, qerrors.ErrNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "unknown user")
+		}
+		logger := opts.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.ErrorContext(ctx, "auth: failed to resolve user",
+			slog.String("subject", claims.Subject),
+			slog.Any("error", err),
+		)
+		return nil, status.Error(codes.Internal, "failed to resolve user")
+	}
+	if user.IsDeleted {
+		return nil, status.Error(codes.Unauthenticated, "unknown user")
+	}
+
+	return auth.WithPrincipal(ctx, &auth.Principal{User: user, Claims: claims}), nil
+}
+
+// resolveUser looks the user up by the token subject, then by its verified email for identity provider subjects
+func resolveUser(ctx context.Context, users interfaces.IUserRepository, claims *auth.Claims) (*gen.Userpayload, error) {
+	user, err := users.GetUserByID(ctx, claims.Subject)
+	if err == nil || !claims.EmailVerified || claims.Email == "" || !errors.Is(err, qerrors.ErrNotFound) {
+		return user, err
+	}
+	return users.GetUserByEmail(ctx, claims.Email)
+}
+
+// bearerToken returns the token of the `authorization: Bearer <token>` metadata
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization metadata must be a bearer token")
+	}
+	return token, nil
+}
+
+// isPublic reports whether the method can be called without a token
+func isPublic(method string, public []string) bool {
+	for _, m := range public {
+		if strings.HasPrefix(method, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatedStream carries the principal in the stream context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,67 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// DeadlineOptions configures the deadline interceptors
+type DeadlineOptions struct {
+	// Default is the deadline applied to calls that arrive without one
+	Default time.Duration
+
+	// Max caps the deadline of every call, including the ones set by clients (0 means no cap)
+	Max time.Duration
+
+	// Methods overrides the maximum deadline per full method name.
+	// It also applies to calls without a deadline, in place of `Default` when it is shorter.
+	Methods map[string]time.Duration
+}
+
+// DeadlineUnaryInterceptor bounds how long a unary handler may run
+func DeadlineUnaryInterceptor(opts DeadlineOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := opts.bound(ctx, info.FullMethod)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// DeadlineStreamInterceptor bounds how long a stream handler may run
+func DeadlineStreamInterceptor(opts DeadlineOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := opts.bound(ss.Context(), info.FullMethod)
+		defer cancel()
+		return handler(srv, &deadlineStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// bound returns a context whose deadline is within the limits of the method
+func (o DeadlineOptions) bound(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	limit := o.Max
+	if m, ok := o.Methods[method]; ok {
+		limit = m
+	}
+
+	timeout := limit
+	if _, ok := ctx.Deadline(); !ok && o.Default > 0 && (limit <= 0 || o.Default < limit) {
+		timeout = o.Default
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	// a shorter deadline set by the client is kept, `WithTimeout` never extends it
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineStream carries the bounded context of a stream
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *deadlineStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,153 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	qerrors "github.com/qwallet-expense-tracker/shared/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"log"
+)
+
+// ErrorOptions configures the error interceptors
+type ErrorOptions struct {
+	// Production hides internal error messages and database text from clients
+	Production bool
+
+	// Domain is the `ErrorInfo` domain attached to every error (defaults to "qwallet")
+	Domain string
+}
+
+// domainErrors maps the domain errors to the reason reported in `ErrorInfo`
+var domainErrors = []struct {
+	err    error
+	reason string
+	field  string
+}{
+	{qerrors.ErrUserNotFound, "USER_NOT_FOUND", ""},
+	{qerrors.ErrAccountNotFound, "ACCOUNT_NOT_FOUND", ""},
+	{qerrors.ErrCategoryNotFound, "CATEGORY_NOT_FOUND", ""},
+	{qerrors.ErrGoalNotFound, "GOAL_NOT_FOUND", ""},
+	{qerrors.ErrBeneficiaryNotFound, "BENEFICIARY_NOT_FOUND", ""},
+	{qerrors.ErrTransactionNotFound, "TRANSACTION_NOT_FOUND", ""},
+	{qerrors.ErrBudgetNotFound, "BUDGET_NOT_FOUND", ""},
+	{qerrors.ErrRecurringNotFound, "RECURRING_TRANSACTION_NOT_FOUND", ""},
+	{qerrors.ErrFxRateNotFound, "FX_RATE_NOT_FOUND", ""},
+	{qerrors.ErrTagNotFound, "TAG_NOT_FOUND", ""},
+	{qerrors.ErrDuplicateAccountName, "DUPLICATE_ACCOUNT_NAME", "name"},
+	{qerrors.ErrDuplicateBeneficiary, "DUPLICATE_BENEFICIARY", "account_number"},
+	{qerrors.ErrDuplicateGoal, "DUPLICATE_GOAL", "name"},
+	{qerrors.ErrDuplicateUser, "DUPLICATE_USER", "email"},
+	{qerrors.ErrDuplicateBudget, "DUPLICATE_BUDGET", "category_id"},
+	{qerrors.ErrDuplicateTag, "DUPLICATE_TAG", "name"},
+	{qerrors.ErrInvalidAmount, "INVALID_AMOUNT", "amount"},
+	{qerrors.ErrInvalidPage, "INVALID_PAGE", "page"},
+	{qerrors.ErrSameAccount, "SAME_ACCOUNT", "to_account_number"},
+	{qerrors.ErrMissingField, "MISSING_FIELD", ""},
+	{qerrors.ErrInvalidCurrency, "INVALID_CURRENCY", "currency"},
+	{qerrors.ErrSplitMismatch, "SPLIT_MISMATCH", "splits"},
+	{qerrors.ErrInvalidTag, "INVALID_TAG", "tags"},
+	{qerrors.ErrInsufficientFunds, "INSUFFICIENT_FUNDS", ""},
+	{qerrors.ErrInvalidPassword, "INVALID_PASSWORD", ""},
+}
+
+// kinds maps the error kinds to gRPC status codes
+var kinds = []struct {
+	err  error
+	code codes.Code
+}{
+	{qerrors.ErrNotFound, codes.NotFound},
+	{qerrors.ErrAlreadyExists, codes.AlreadyExists},
+	{qerrors.ErrInvalidArgument, codes.InvalidArgument},
+	{qerrors.ErrFailedPrecondition, codes.FailedPrecondition},
+	{qerrors.ErrUnauthenticated, codes.Unauthenticated},
+	{qerrors.ErrPermissionDenied, codes.PermissionDenied},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+// ErrorUnaryInterceptor converts the errors returned by unary handlers into gRPC statuses
+func ErrorUnaryInterceptor(opts ErrorOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, toStatus(info.FullMethod, err, opts).Err()
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamInterceptor converts the errors returned by stream handlers into gRPC statuses
+func ErrorStreamInterceptor(opts ErrorOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatus(info.FullMethod, err, opts).Err()
+		}
+		return nil
+	}
+}
+
+// toStatus maps an error to a gRPC status with `errdetails` attached.
+// Errors that already carry a status are returned as they are.
+func toStatus(method string, err error, opts ErrorOptions) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	if opts.Domain == "" {
+		opts.Domain = "qwallet"
+	}
+
+	code := codes.Internal
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			code = k.code
+			break
+		}
+	}
+
+	if code == codes.Internal {
+		log.Printf("⛔️internal error in %s: %v\n", method, err)
+		if opts.Production {
+			return status.New(codes.Internal, "internal error")
+		}
+		return withDetails(status.New(codes.Internal, err.Error()), &errdetails.DebugInfo{Detail: err.Error()})
+	}
+
+	msg := err.Error()
+	info := &errdetails.ErrorInfo{Reason: code.String(), Domain: opts.Domain}
+	var field string
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			info.Reason = d.reason
+			field = d.field
+			if opts.Production {
+				msg = d.err.Error()
+			}
+			break
+		}
+	}
+	if opts.Production && msg == err.Error() {
+		msg = code.String()
+	}
+
+	details := []protoadapt.MessageV1{info}
+	if code == codes.InvalidArgument && field != "" {
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: msg}},
+		})
+	}
+	return withDetails(status.New(code, msg), details...)
+}
+
+// withDetails attaches the details to the status, falling back to the bare status if they cannot be marshalled
+func withDetails(s *status.Status, details ...protoadapt.MessageV1) *status.Status {
+	ds, err := s.WithDetails(details...)
+	if err != nil {
+		log.Printf("failed to attach error details: %v\n", err)
+		return s
+	}
+	return ds
+}
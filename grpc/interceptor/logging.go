@@ -2,96 +2,245 @@ package interceptor
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"encoding/hex"
 	"google.golang.org/grpc"
-	"log"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	mrand "math/rand"
+	"os"
 	"strings"
 	"time"
 )
 
-var (
-	ignoredMethods = []string{
-		"/grpc.health.v1.Health/Check",
-		"/grpc.health.v1.Health/Watch",
-		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+// RequestIDHeader is the metadata key carrying the request id, it is echoed back in the response headers
+const RequestIDHeader = "x-request-id"
+
+// DefaultIgnoredMethods are the methods that are not logged unless `LogOptions.IgnoredMethods` is set
+var DefaultIgnoredMethods = []string{
+	"/grpc.health.v1.Health/Check",
+	"/grpc.health.v1.Health/Watch",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+}
+
+// DefaultRedactedFields are the proto field names whose values are never logged unless `LogOptions.RedactedFields` is set
+var DefaultRedactedFields = []string{"password", "new_password", "old_password", "token", "auth_id", "refresh_token"}
+
+// LogOptions configures the logging interceptors
+type LogOptions struct {
+	// Logger receives the log records (defaults to a JSON logger on stdout)
+	Logger *slog.Logger
+
+	// IgnoredMethods are full method names (or prefixes, e.g. "/grpc.health.v1.Health/") that are never logged
+	IgnoredMethods []string
+
+	// RedactedFields are proto field names whose values are replaced in logged payloads.
+	// Fields marked with the `debug_redact` field option are always redacted.
+	RedactedFields []string
+
+	// LogPayloads adds the request and response messages to unary call records
+	LogPayloads bool
+
+	// MaxPayloadBytes truncates logged payloads (defaults to 2048)
+	MaxPayloadBytes int
+
+	// SampleRate is the fraction of successful calls that are logged (defaults to 1, failed calls are always logged)
+	SampleRate float64
+
+	// MethodSampleRates overrides the sample rate per full method name
+	MethodSampleRates map[string]float64
+}
+
+// logger is the shared state of the logging interceptors
+type logger struct {
+	opts     LogOptions
+	redacted map[string]bool
+}
+
+func newLogger(opts LogOptions) *logger {
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	if opts.IgnoredMethods == nil {
+		opts.IgnoredMethods = DefaultIgnoredMethods
+	}
+	if opts.RedactedFields == nil {
+		opts.RedactedFields = DefaultRedactedFields
+	}
+	if opts.MaxPayloadBytes <= 0 {
+		opts.MaxPayloadBytes = 2048
+	}
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 1
 	}
-)
 
-// LoggingUnaryInterceptor logs the unary request
-func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	for _, m := range ignoredMethods {
-		if strings.Contains(info.FullMethod, m) {
+	redacted := make(map[string]bool, len(opts.RedactedFields))
+	for _, f := range opts.RedactedFields {
+		redacted[f] = true
+	}
+	return &logger{opts: opts, redacted: redacted}
+}
+
+// SlogUnaryInterceptor logs every unary call as one structured record
+func SlogUnaryInterceptor(opts LogOptions) grpc.UnaryServerInterceptor {
+	l := newLogger(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if l.ignored(info.FullMethod) {
 			return handler(ctx, req)
 		}
+
+		ctx, requestID := withRequestID(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if !l.sampled(info.FullMethod, err) {
+			return resp, err
+		}
+
+		attrs := l.attrs(ctx, info.FullMethod, requestID, start, err)
+		if l.opts.LogPayloads {
+			attrs = append(attrs, slog.String("request", l.payload(req)))
+			if err == nil {
+				attrs = append(attrs, slog.String("response", l.payload(resp)))
+			}
+		}
+		l.opts.Logger.LogAttrs(ctx, level(err), "gRPC unary call", attrs...)
+		return resp, err
 	}
-	
-	start := time.Now()
-	
-	h, err := handler(ctx, req)
-	
-	// logging
-	log.Printf(`
-================== ⚡️ gRPC Unary Call ⚡️ ===================
-⚙️Method: %v
-
-☘️↙️Request: %+v
-❄️↗️Response: %+v
-
-⏰Duration: %v
-======================================================
-`, info.FullMethod, req, h, time.Since(start))
-	//Request: %v
-	return h, err
-}
-
-// LoggingStreamInterceptor logs the stream request
-func LoggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	for _, m := range ignoredMethods {
-		if strings.Contains(info.FullMethod, m) {
+}
+
+// SlogStreamInterceptor logs every stream as one structured record when it ends.
+// Stream messages are counted rather than logged.
+func SlogStreamInterceptor(opts LogOptions) grpc.StreamServerInterceptor {
+	l := newLogger(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if l.ignored(info.FullMethod) {
 			return handler(srv, ss)
 		}
+
+		ctx, requestID := withRequestID(ss.Context())
+		start := time.Now()
+		stream := &countingStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, stream)
+		if !l.sampled(info.FullMethod, err) {
+			return err
+		}
+
+		attrs := l.attrs(ctx, info.FullMethod, requestID, start, err)
+		attrs = append(attrs,
+			slog.Int("messages_received", stream.received),
+			slog.Int("messages_sent", stream.sent),
+		)
+		l.opts.Logger.LogAttrs(ctx, level(err), "gRPC streaming call", attrs...)
+		return err
 	}
-	
-	start := time.Now()
-	
-	// Create a wrapper around the ServerStream to log incoming messages
-	wrappedStream := newWrappedStream(ss)
-	err := handler(srv, wrappedStream)
-	
-	// logging
-	log.Printf(`
-================== ⚡️ gRPC Streaming Call ⚡️ ===================
-⚙️Method: %v
-
-%v
-
-⏰Duration: %v
-⛔️Error: %v
-======================================================
-`, info.FullMethod, wrappedStream.logMsg, time.Since(start), err)
-	
-	return err
 }
 
-// Ensure the wrappedStream struct implements the grpc.ServerStream interface
-type wrappedStream struct {
+// LoggingUnaryInterceptor logs the unary request with the default options
+var LoggingUnaryInterceptor = SlogUnaryInterceptor(LogOptions{})
+
+// LoggingStreamInterceptor logs the stream request with the default options
+var LoggingStreamInterceptor = SlogStreamInterceptor(LogOptions{})
+
+// ignored reports whether the method matches the ignore list
+func (l *logger) ignored(method string) bool {
+	for _, m := range l.opts.IgnoredMethods {
+		if strings.HasPrefix(method, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled reports whether the call should be logged
+func (l *logger) sampled(method string, err error) bool {
+	if err != nil {
+		return true
+	}
+	rate, ok := l.opts.MethodSampleRates[method]
+	if !ok {
+		rate = l.opts.SampleRate
+	}
+	return rate >= 1 || mrand.Float64() < rate
+}
+
+// attrs returns the attributes shared by unary and stream records
+func (l *logger) attrs(ctx context.Context, method, requestID string, start time.Time, err error) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("request_id", requestID),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	return attrs
+}
+
+// level logs failed calls as errors
+func level(err error) slog.Level {
+	if err != nil {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the id of the request being handled, or "" outside of the logging interceptors
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID takes the request id from the incoming metadata, or generates one,
+// and sends it back to the client in the response headers
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDHeader); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	return context.WithValue(ctx, requestIDKey{}, id), id
+}
+
+// countingStream counts the messages of a stream and carries the request id in its context
+type countingStream struct {
 	grpc.ServerStream
-	logMsg string
+	ctx      context.Context
+	received int
+	sent     int
 }
 
-// newWrappedStream creates a new wrappedStream instance
-func newWrappedStream(stream grpc.ServerStream) *wrappedStream {
-	return &wrappedStream{ServerStream: stream, logMsg: ""}
+func (s *countingStream) Context() context.Context {
+	return s.ctx
 }
 
-func (w *wrappedStream) RecvMsg(m any) error {
-	err := w.ServerStream.RecvMsg(m)
-	w.logMsg += fmt.Sprintf("☘️↙️Received message: %v\n", m)
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
 	return err
 }
 
-func (w *wrappedStream) SendMsg(m any) error {
-	err := w.ServerStream.SendMsg(m)
-	w.logMsg += fmt.Sprintf("\n❄️↗️Sent message: %v", m)
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
 	return err
 }
@@ -0,0 +1,29 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// MetricsUnaryInterceptor records the latency and status code of every unary call
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return resp, err
+	}
+}
+
+// MetricsStreamInterceptor records the duration and status code of every stream
+func MetricsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return err
+	}
+}
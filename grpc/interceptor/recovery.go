@@ -0,0 +1,75 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// RecoveryOptions configures the recovery interceptors
+type RecoveryOptions struct {
+	// Logger receives the panic records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+
+	// OnPanic is called after a panic was recovered, e.g. to report it
+	OnPanic func(ctx context.Context, method string, p any)
+}
+
+// panics counts the panics recovered by every recovery interceptor of the process
+var panics atomic.Uint64
+
+// Panics returns the number of handler panics recovered since the process started
+func Panics() uint64 {
+	return panics.Load()
+}
+
+// RecoveryUnaryInterceptor turns a panic in a unary handler into a `codes.Internal` error
+func RecoveryUnaryInterceptor(opts RecoveryOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, info.FullMethod, p, opts)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor turns a panic in a stream handler into a `codes.Internal` error
+func RecoveryStreamInterceptor(opts RecoveryOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), info.FullMethod, p, opts)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// recovered logs and counts a panic and returns the error sent to the client
+func recovered(ctx context.Context, method string, p any, opts RecoveryOptions) error {
+	panics.Add(1)
+	metrics.RecordPanic(method)
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.ErrorContext(ctx, "gRPC handler panicked",
+		slog.String("method", method),
+		slog.String("request_id", RequestID(ctx)),
+		slog.Any("panic", p),
+		slog.String("stack", string(debug.Stack())),
+	)
+
+	if opts.OnPanic != nil {
+		opts.OnPanic(ctx, method, p)
+	}
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// redactedValue replaces the value of redacted string fields
+const redactedValue = "[REDACTED]"
+
+// payload renders a message as JSON with the sensitive fields redacted, truncated to `MaxPayloadBytes`.
+// Anything that is not a proto message is only logged by type so that it cannot leak.
+func (l *logger) payload(v interface{}) string {
+	var msg proto.Message
+	switch m := v.(type) {
+	case nil:
+		return ""
+	case proto.Message:
+		msg = m
+	case protoadapt.MessageV1:
+		msg = protoadapt.MessageV2Of(m)
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+
+	msg = proto.Clone(msg)
+	l.redact(msg.ProtoReflect())
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	if len(b) > l.opts.MaxPayloadBytes {
+		return string(b[:l.opts.MaxPayloadBytes]) + "...(truncated)"
+	}
+	return string(b)
+}
+
+// redact clears the sensitive fields of a message and its nested messages in place
+func (l *logger) redact(m protoreflect.Message) {
+	// fields are only replaced after ranging, a message must not be modified while it is ranged over
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if l.sensitive(fd) {
+			sensitive = append(sensitive, fd)
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				l.redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				l.redact(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			l.redact(v.Message())
+		}
+		return true
+	})
+
+	for _, fd := range sensitive {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(redactedValue))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+// sensitive reports whether the field is on the denylist or marked with `[debug_redact = true]`
+func (l *logger) sensitive(fd protoreflect.FieldDescriptor) bool {
+	if l.redacted[string(fd.Name())] {
+		return true
+	}
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
@@ -0,0 +1,105 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/qwallet-expense-tracker/shared/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// TracingUnaryInterceptor starts a server span for every unary call, continuing the caller's trace
+func TracingUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startServerSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		endServerSpan(span, err)
+		return resp, err
+	}
+}
+
+// TracingStreamInterceptor starts a server span for every stream, continuing the caller's trace
+func TracingStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+		endServerSpan(span, err)
+		return err
+	}
+}
+
+// startServerSpan extracts the trace context of the incoming metadata and starts the call's span
+func startServerSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	return telemetry.Tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+			attribute.String("request_id", RequestID(ctx)),
+		),
+	)
+}
+
+// endServerSpan records the status of the call on its span
+func endServerSpan(span trace.Span, err error) {
+	s := status.Convert(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(s.Code())))
+	switch s.Code() {
+	case codes.OK:
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, s.Message())
+	default:
+		// client errors are not server failures, but they are still visible on the span
+		span.SetAttributes(attribute.String("rpc.grpc.status_message", s.Message()))
+	}
+}
+
+// metadataCarrier adapts gRPC metadata to a `propagation.TextMapCarrier`
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// tracedStream carries the span in the stream context
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,8 @@
+// Package idempotency makes Kafka consumers skip the messages they already processed.
+//
+// Kafka delivers at-least-once, so a message is redelivered after a rebalance or a crash that
+// happened before its offset was committed. `Handler` wraps a `broker.Handler` and records the
+// key of every processed message in a `Store` (Postgres or Redis) for a TTL. When the handler
+// writes to Postgres, `TxHandler` records the key in the handler's own transaction instead, so the
+// message's changes and its key are committed together and a duplicate can never be applied twice.
+package idempotency
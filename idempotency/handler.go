@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/qwallet-expense-tracker/shared/broker"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// DefaultTTL is how long processed keys are kept by default, the retention of the catalog topics.
+// A message cannot be redelivered once its topic dropped it.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// Options configures the idempotent handlers
+type Options struct {
+	// Consumer names the consumer the keys are recorded for, usually its group id.
+	// Consumers with different names process the same message independently.
+	Consumer string
+
+	// TTL is how long the key of a processed message is kept (defaults to `DefaultTTL`)
+	TTL time.Duration
+
+	// Key returns the key that identifies a message (defaults to `MessageKey`)
+	Key func(msg *kafka.Message) string
+
+	// Tx configures the transaction of `TxHandler`
+	Tx database.TxOptions
+
+	// Logger receives the skipped duplicates and failures to record a key (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Consumer == "" {
+		panic("idempotency: consumer name is required")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Key == nil {
+		opts.Key = MessageKey
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return opts
+}
+
+// MessageKey identifies a message by the event id of its envelope, so an event that was
+// published twice is still processed once. Messages without an envelope are identified by
+// their topic, partition and offset, which only catches redeliveries.
+func MessageKey(msg *kafka.Message) string {
+	topic := *msg.TopicPartition.Topic
+	if env, err := broker.ParseEnvelope(msg); err == nil {
+		return topic + "/" + env.ID
+	}
+	return topic + "/" + strconv.Itoa(int(msg.TopicPartition.Partition)) + "/" + msg.TopicPartition.Offset.String()
+}
+
+// Handler skips the messages that were already processed and records the key of every message `next` handled.
+//
+// Checking and recording the key are separate from the handler's work: a crash in between
+// processes the message again on redelivery. Use `TxHandler` when the handler writes to Postgres.
+func Handler(store Store, opts Options, next broker.Handler) broker.Handler {
+	opts = opts.withDefaults()
+	return broker.HandlerFunc(func(ctx context.Context, msg *kafka.Message) error {
+		key := opts.Key(msg)
+		processed, err := store.Processed(ctx, opts.Consumer, key)
+		if err != nil {
+			return fmt.Errorf("failed to check message %s: %w", key, err)
+		}
+		if processed {
+			skip(ctx, opts, msg, key)
+			return nil
+		}
+
+		if err = next.Handle(ctx, msg); err != nil {
+			return err
+		}
+		if err = store.MarkProcessed(ctx, opts.Consumer, key, opts.TTL); err != nil {
+			// the message was handled, failing now would only have it handled again
+			opts.Logger.ErrorContext(ctx, "failed to mark message as processed",
+				slog.String("key", key),
+				slog.String("consumer", opts.Consumer),
+				slog.Any("error", err),
+			)
+		}
+		return nil
+	})
+}
+
+// TxHandler runs fn in a transaction that first claims the message's key.
+// The key is committed together with the changes of fn, so a message is applied exactly once:
+// a duplicate finds the key and is skipped, and a concurrent duplicate waits for the first claim to commit or roll back.
+func TxHandler(db *database.DB, opts Options, fn func(ctx context.Context, q gen.Querier, msg *kafka.Message) error) broker.Handler {
+	opts = opts.withDefaults()
+	return broker.HandlerFunc(func(ctx context.Context, msg *kafka.Message) error {
+		key := opts.Key(msg)
+		duplicate := false
+		err := db.InTx(ctx, opts.Tx, func(q gen.Querier) error {
+			claimed, err := q.ClaimProcessedMessage(ctx, opts.Consumer, key, ttlSeconds(opts.TTL))
+			if err != nil {
+				return fmt.Errorf("failed to claim message %s: %w", key, err)
+			}
+			if duplicate = !claimed; duplicate {
+				return nil
+			}
+			return fn(ctx, q, msg)
+		})
+		if err == nil && duplicate {
+			skip(ctx, opts, msg, key)
+		}
+		return err
+	})
+}
+
+// skip records a skipped duplicate
+func skip(ctx context.Context, opts Options, msg *kafka.Message, key string) {
+	opts.Logger.InfoContext(ctx, "skipping message already processed",
+		slog.String("key", key),
+		slog.String("consumer", opts.Consumer),
+		slog.String("topic", *msg.TopicPartition.Topic),
+		slog.Int("partition", int(msg.TopicPartition.Partition)),
+		slog.Int64("offset", int64(msg.TopicPartition.Offset)),
+	)
+	metrics.RecordDuplicate(*msg.TopicPartition.Topic, opts.Consumer)
+}
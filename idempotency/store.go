@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"github.com/qwallet-expense-tracker/shared/cache"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Store records the keys of processed messages
+type Store interface {
+	// Processed reports whether the consumer already processed the message with the given key
+	Processed(ctx context.Context, consumer, key string) (bool, error)
+
+	// MarkProcessed records that the consumer processed the message, the key is forgotten after the ttl
+	MarkProcessed(ctx context.Context, consumer, key string, ttl time.Duration) error
+}
+
+// PostgresStore keeps the keys in the `ProcessedMessageMaster` table
+type PostgresStore struct {
+	db database.Executor
+}
+
+// ensure every method of the `Store` interface is implemented
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a store on the given executor
+func NewPostgresStore(db database.Executor) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Processed reports whether the consumer already processed the message with the given key
+func (s *PostgresStore) Processed(ctx context.Context, consumer, key string) (processed bool, err error) {
+	err = s.db.Execute(ctx, func(q gen.Querier) error {
+		processed, err = q.IsMessageProcessed(ctx, consumer, key)
+		return err
+	})
+	return processed, err
+}
+
+// MarkProcessed records that the consumer processed the message
+func (s *PostgresStore) MarkProcessed(ctx context.Context, consumer, key string, ttl time.Duration) error {
+	return s.db.Execute(ctx, func(q gen.Querier) error {
+		_, err := q.ClaimProcessedMessage(ctx, consumer, key, ttlSeconds(ttl))
+		return err
+	})
+}
+
+// Purge deletes the expired keys and returns how many were deleted
+func (s *PostgresStore) Purge(ctx context.Context) (deleted int64, err error) {
+	err = s.db.Execute(ctx, func(q gen.Querier) error {
+		deleted, err = q.PurgeProcessedMessages(ctx)
+		return err
+	})
+	return deleted, err
+}
+
+// ttlSeconds rounds the ttl up to whole seconds
+func ttlSeconds(ttl time.Duration) int32 {
+	seconds := int32((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// RedisStore keeps the keys in Redis, where they expire on their own
+type RedisStore struct {
+	prefix string
+}
+
+// ensure every method of the `Store` interface is implemented
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a store on the cache client (see `cache.Connect`).
+// Keys are stored as "<prefix>:<consumer>:<key>", the prefix defaults to "qwallet:processed".
+func NewRedisStore(prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "qwallet:processed"
+	}
+	return &RedisStore{prefix: prefix}
+}
+
+// Processed reports whether the consumer already processed the message with the given key
+func (s *RedisStore) Processed(ctx context.Context, consumer, key string) (bool, error) {
+	client, err := cache.CacheClient()
+	if err != nil {
+		return false, err
+	}
+	err = client.Get(ctx, s.key(consumer, key)).Err()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// MarkProcessed records that the consumer processed the message
+func (s *RedisStore) MarkProcessed(ctx context.Context, consumer, key string, ttl time.Duration) error {
+	client, err := cache.CacheClient()
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, s.key(consumer, key), time.Now().UTC().Format(time.RFC3339), ttl).Err()
+}
+
+func (s *RedisStore) key(consumer, key string) string {
+	return s.prefix + ":" + consumer + ":" + key
+}
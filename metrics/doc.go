@@ -0,0 +1,8 @@
+// Package metrics exposes Prometheus metrics for the qwallet services.
+//
+// The constructors of the other packages register their collectors here automatically:
+// `database.Connect` for the pool, `cache.Connect` for Redis commands, `cached.New` for hit
+// ratios, `broker.NewProducer`/`broker.NewConsumer` and the consumer runner for Kafka, the
+// `scheduler` for recurring transactions, and the metrics interceptors of `grpc/interceptor`
+// for RPCs. Serve them with `Handler()`.
+package metrics
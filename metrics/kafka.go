@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// committedTimeout bounds how long a scrape waits for the committed offsets of a consumer
+const committedTimeout = time.Second
+
+var (
+	produceDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "produce_seconds",
+		Help:      "Time from producing a message to its delivery report, by topic.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"topic"}))
+
+	produceErrors = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "produce_errors_total",
+		Help:      "Messages that could not be produced or delivered, by topic.",
+	}, []string{"topic"}))
+
+	consumerHandled = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumed_messages_total",
+		Help:      "Messages handled by the consumer runner, by topic and result (ok, error, dead_lettered).",
+	}, []string{"topic", "result"}))
+
+	consumerDuplicates = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "duplicate_messages_total",
+		Help:      "Redelivered messages skipped by idempotent consumers, by topic and consumer.",
+	}, []string{"topic", "consumer"}))
+)
+
+// ObserveProduce records the delivery of a message
+func ObserveProduce(topic string, d time.Duration, err error) {
+	if err != nil {
+		produceErrors.WithLabelValues(topic).Inc()
+		return
+	}
+	produceDuration.WithLabelValues(topic).Observe(d.Seconds())
+}
+
+// RecordConsumed counts a message handled by the consumer runner
+func RecordConsumed(topic, result string) {
+	consumerHandled.WithLabelValues(topic, result).Inc()
+}
+
+// RecordDuplicate counts a redelivered message that an idempotent consumer skipped
+func RecordDuplicate(topic, consumer string) {
+	consumerDuplicates.WithLabelValues(topic, consumer).Inc()
+}
+
+// producerCollector reports the queue length of a producer
+type producerCollector struct {
+	key      string
+	producer *kafka.Producer
+	queue    *prometheus.Desc
+}
+
+// RegisterProducer registers the queue length of a producer.
+// The returned func unregisters it, a producer closed without calling it is unregistered on the next scrape.
+func RegisterProducer(p *kafka.Producer) (unregister func()) {
+	key := "producer/" + p.String()
+	return registerInstance(key, &producerCollector{
+		key:      key,
+		producer: p,
+		queue: prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka", "producer_queue_length"),
+			"Messages and requests waiting to be transmitted or acknowledged.", nil, prometheus.Labels{"client": p.String()}),
+	})
+}
+
+func (c *producerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queue
+}
+
+func (c *producerCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.producer.IsClosed() {
+		// the registry is locked while it collects, unregister once it is done
+		go unregisterInstance(c.key, c)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.queue, prometheus.GaugeValue, float64(c.producer.Len()))
+}
+
+// consumerCollector reports the committed offset, high watermark and lag of the assigned partitions
+type consumerCollector struct {
+	key       string
+	consumer  *kafka.Consumer
+	committed *prometheus.Desc
+	high      *prometheus.Desc
+	lag       *prometheus.Desc
+}
+
+// RegisterConsumer registers the offsets of a consumer's assigned partitions under the given group.
+// The returned func unregisters it, a consumer closed without calling it is unregistered on the next scrape.
+func RegisterConsumer(group string, c *kafka.Consumer) (unregister func()) {
+	labels := prometheus.Labels{"group": group, "client": c.String()}
+	variable := []string{"topic", "partition"}
+	key := "consumer/" + group + "/" + c.String()
+	return registerInstance(key, &consumerCollector{
+		key:       key,
+		consumer:  c,
+		committed: prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka", "consumer_committed_offset"), "Committed offset of the partition.", variable, labels),
+		high:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka", "consumer_high_watermark"), "High watermark of the partition, as last seen by the consumer.", variable, labels),
+		lag:       prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka", "consumer_lag"), "High watermark minus committed offset of the partition.", variable, labels),
+	})
+}
+
+func (c *consumerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.committed
+	ch <- c.high
+	ch <- c.lag
+}
+
+func (c *consumerCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.consumer.IsClosed() {
+		// the registry is locked while it collects, unregister once it is done
+		go unregisterInstance(c.key, c)
+		return
+	}
+	assigned, err := c.consumer.Assignment()
+	if err != nil || len(assigned) == 0 {
+		return
+	}
+	committed, err := c.consumer.Committed(assigned, int(committedTimeout.Milliseconds()))
+	if err != nil {
+		slog.Warn("metrics: failed to get committed offsets", slog.String("client", c.consumer.String()), slog.Any("error", err))
+		return
+	}
+
+	for _, tp := range committed {
+		topic, partition := *tp.Topic, strconv.Itoa(int(tp.Partition))
+		_, high, err := c.consumer.GetWatermarkOffsets(*tp.Topic, tp.Partition)
+		if err != nil || high < 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.high, prometheus.GaugeValue, float64(high), topic, partition)
+		if tp.Offset < 0 {
+			// nothing committed yet, the whole partition is lag
+			ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(high), topic, partition)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.committed, prometheus.GaugeValue, float64(tp.Offset), topic, partition)
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(high-int64(tp.Offset)), topic, partition)
+	}
+}
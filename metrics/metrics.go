@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// namespace prefixes every metric name
+const namespace = "qwallet"
+
+// Registerer is where every collector of the module is registered
+var Registerer = prometheus.DefaultRegisterer
+
+// Gatherer is what `Handler` serves
+var Gatherer = prometheus.DefaultGatherer
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Gatherer, promhttp.HandlerOpts{Registry: Registerer})
+}
+
+// register registers the collector, returning the one already registered under the same descriptors.
+// Constructors may run more than once (e.g. in tests), which must not fail.
+func register[C prometheus.Collector](c C) C {
+	if err := Registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+			return c
+		}
+		slog.Error("metrics: failed to register collector", slog.Any("error", err))
+	}
+	return c
+}
+
+// instances holds the collectors of pools, producers and consumers by key, guarded by instancesMu
+var (
+	instancesMu sync.Mutex
+	instances   = make(map[string]prometheus.Collector)
+)
+
+// registerInstance registers the collector of one pool, producer or consumer under the key.
+// A collector of a previous instance with the same key (e.g. before a reconnect) is replaced,
+// so the metrics never report a closed object. The returned func unregisters the collector.
+func registerInstance(key string, c prometheus.Collector) (unregister func()) {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	if old, ok := instances[key]; ok {
+		Registerer.Unregister(old)
+		delete(instances, key)
+	}
+	if err := Registerer.Register(c); err != nil {
+		slog.Error("metrics: failed to register collector", slog.String("collector", key), slog.Any("error", err))
+		return func() {}
+	}
+	instances[key] = c
+	return func() { unregisterInstance(key, c) }
+}
+
+// unregisterInstance unregisters the collector, unless another instance replaced it under the key meanwhile
+func unregisterInstance(key string, c prometheus.Collector) {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	if instances[key] != c {
+		return
+	}
+	Registerer.Unregister(c)
+	delete(instances, key)
+}
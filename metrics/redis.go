@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"time"
+)
+
+var (
+	redisDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_seconds",
+		Help:      "Latency of Redis commands, by command. Pipelines are reported as \"pipeline\".",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"}))
+
+	redisErrors = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "errors_total",
+		Help:      "Failed Redis commands, by command. Cache misses are not errors.",
+	}, []string{"command"}))
+
+	cacheRequests = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Read-through cache lookups, by entity and result (hit, miss).",
+	}, []string{"entity", "result"}))
+
+	cacheInvalidations = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "invalidations_total",
+		Help:      "Read-through cache invalidations, by entity.",
+	}, []string{"entity"}))
+)
+
+// RedisHook is a go-redis hook that measures command latency and errors
+type RedisHook struct{}
+
+var _ redis.Hook = RedisHook{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeRedis(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observeRedis("pipeline", start, err)
+		return err
+	}
+}
+
+func observeRedis(command string, start time.Time, err error) {
+	redisDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		redisErrors.WithLabelValues(command).Inc()
+	}
+}
+
+// RecordCacheHit counts a read-through cache hit
+func RecordCacheHit(entity string) {
+	cacheRequests.WithLabelValues(entity, "hit").Inc()
+}
+
+// RecordCacheMiss counts a read-through cache miss
+func RecordCacheMiss(entity string) {
+	cacheRequests.WithLabelValues(entity, "miss").Inc()
+}
+
+// RecordCacheInvalidation counts a read-through cache invalidation
+func RecordCacheInvalidation(entity string) {
+	cacheInvalidations.WithLabelValues(entity).Inc()
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+var (
+	rpcDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "handling_seconds",
+		Help:      "Time taken to handle gRPC calls, by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"}))
+
+	rpcPanics = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "panics_total",
+		Help:      "Handler panics recovered by the recovery interceptors, by method.",
+	}, []string{"method"}))
+)
+
+// ObserveRPC records the duration and status code of a gRPC call
+func ObserveRPC(method, code string, d time.Duration) {
+	rpcDuration.WithLabelValues(method, code).Observe(d.Seconds())
+}
+
+// RecordPanic counts a handler panic
+func RecordPanic(method string) {
+	rpcPanics.WithLabelValues(method).Inc()
+}
@@ -0,0 +1,17 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var recurringOccurrences = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "scheduler",
+	Name:      "recurring_occurrences_total",
+	Help:      "Occurrences of recurring transactions materialized by the scheduler, by status (COMPLETED, FAILED).",
+}, []string{"status"}))
+
+// RecordRecurringOccurrence counts an occurrence materialized by the scheduler
+func RecordRecurringOccurrence(status string) {
+	recurringOccurrences.WithLabelValues(status).Inc()
+}
@@ -0,0 +1,13 @@
+// Package money provides an exact decimal type for monetary amounts.
+//
+// Amounts are stored as integer minor units (pesewas, cents) so that balances
+// never drift the way `float32` values do. The type implements the pgx numeric
+// scanner/valuer interfaces and JSON (un)marshalling, so it can be used directly
+// in the generated models and query parameters.
+//
+// Exchange rates are kept the same way as a `Rate` with `RateScale` decimal places.
+// `Amount.Convert` applies a rate and rounds the result half away from zero.
+//
+// Every currency is kept with `Scale` decimal places. The database rounds converted
+// amounts of zero-decimal currencies (e.g. JPY) to whole units, see `currency_scale`.
+package money
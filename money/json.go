@@ -0,0 +1,128 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	_ json.Marshaler   = Amount(0)
+	_ json.Unmarshaler = (*Amount)(nil)
+)
+
+// MarshalJSON encodes the amount as a JSON number with exactly `Scale` decimal places (e.g. 10.50)
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number (10.5) or string ("10.50") into the amount.
+// The literal is parsed as a decimal so no precision is lost through float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	literal := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &literal); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+		}
+	}
+
+	v, err := Parse(literal)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// MarshalText implements the `encoding.TextMarshaler` interface
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements the `encoding.TextUnmarshaler` interface
+func (a *Amount) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+var (
+	_ json.Marshaler   = NullAmount{}
+	_ json.Unmarshaler = (*NullAmount)(nil)
+)
+
+// MarshalJSON encodes the amount like `Amount`, or as null when it is not valid
+func (a NullAmount) MarshalJSON() ([]byte, error) {
+	if !a.Valid {
+		return []byte("null"), nil
+	}
+	return a.Amount.MarshalJSON()
+}
+
+// UnmarshalJSON decodes null into an invalid NullAmount and anything else like `Amount`
+func (a *NullAmount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*a = NullAmount{}
+		return nil
+	}
+	if err := a.Amount.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	a.Valid = true
+	return nil
+}
+
+var (
+	_ json.Marshaler   = Rate(0)
+	_ json.Unmarshaler = (*Rate)(nil)
+)
+
+// MarshalJSON encodes the rate as a JSON number with exactly `RateScale` decimal places
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number (15.5) or string ("15.5") into the rate
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	literal := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &literal); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRate, data)
+		}
+	}
+
+	v, err := ParseRate(literal)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// MarshalText implements the `encoding.TextMarshaler` interface
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements the `encoding.TextUnmarshaler` interface
+func (r *Rate) UnmarshalText(text []byte) error {
+	v, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
@@ -0,0 +1,199 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept by an Amount (numeric(10, 2))
+const Scale = 2
+
+// minorPerMajor is the number of minor units (pesewas, cents) in one major unit
+const minorPerMajor = 100
+
+// Zero is the zero amount
+const Zero Amount = 0
+
+var (
+	// ErrInvalidAmount is returned when a value cannot be represented exactly as an Amount
+	ErrInvalidAmount = errors.New("money: invalid amount")
+
+	// ErrOverflow is returned when a value does not fit into an Amount
+	ErrOverflow = errors.New("money: amount overflows")
+)
+
+// Amount is an exact monetary value stored as an integer number of minor units.
+// It maps to the `numeric(10, 2)` columns in the database without going through floating point.
+type Amount int64
+
+// FromMinor creates an Amount from minor units (e.g. 1050 => 10.50)
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// FromMajor creates an Amount from whole major units (e.g. 10 => 10.00)
+func FromMajor(major int64) Amount {
+	return Amount(major * minorPerMajor)
+}
+
+// Parse parses a decimal string such as "10", "10.5" or "-0.05" into an Amount.
+// Values with more than `Scale` decimal places are rejected instead of being rounded.
+func Parse(s string) (Amount, error) {
+	v, err := parseDecimal(s, Scale, ErrInvalidAmount)
+	return Amount(v), err
+}
+
+// MustParse is like Parse but panics if the value is invalid. Intended for constants and tests.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Minor returns the amount in minor units
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float64 returns an approximate floating point value. Use only for display, never for arithmetic.
+func (a Amount) Float64() float64 {
+	return float64(a) / minorPerMajor
+}
+
+// Add returns a + b
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+// Sub returns a - b
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// Cmp compares a and b and returns -1, 0 or +1
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String formats the amount with exactly `Scale` decimal places (e.g. "-10.05")
+func (a Amount) String() string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+
+	// use uint64 so that math.MinInt64 does not overflow when negated
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-(v + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/minorPerMajor, u%minorPerMajor)
+}
+
+// parseDecimal parses a decimal string into an integer scaled by 10^scale.
+// Errors wrap `invalid`, or `ErrOverflow` when the value does not fit into an int64.
+func parseDecimal(s string, scale int, invalid error) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty string", invalid)
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" && (!hasPoint || frac == "") {
+		return 0, fmt.Errorf("%w: %q", invalid, s)
+	}
+	if len(frac) > scale {
+		// allow trailing zeros beyond the scale (e.g. "10.500")
+		if strings.TrimRight(frac[scale:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q has more than %d decimal places", invalid, s, scale)
+		}
+		frac = frac[:scale]
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", invalid, s)
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	unit := int64(math.Pow10(scale))
+	var major, minor int64
+	var err error
+	if whole != "" {
+		if major, err = strconv.ParseInt(whole, 10, 64); err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+		}
+	}
+	if frac != "" {
+		if minor, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return 0, fmt.Errorf("%w: %q", invalid, s)
+		}
+	}
+	if major > (math.MaxInt64-minor)/unit {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	v := major*unit + minor
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fromBig converts a scaled integer to an Amount, checking for overflow
+func fromBig(v *big.Int) (Amount, error) {
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, v.String())
+	}
+	return Amount(v.Int64()), nil
+}
@@ -0,0 +1,87 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{in: "10", want: 1000},
+		{in: "10.5", want: 1050},
+		{in: "10.05", want: 1005},
+		{in: "-0.05", want: -5},
+		{in: "+3.10", want: 310},
+		{in: ".5", want: 50},
+		{in: "7.", want: 700},
+		{in: " 12.34 ", want: 1234},
+		{in: "10.500", want: 1050},
+		{in: "0", want: Zero},
+		{in: "92233720368547758.07", want: math.MaxInt64},
+		{in: "-92233720368547758.07", want: -math.MaxInt64},
+		{in: "", err: ErrInvalidAmount},
+		{in: "-", err: ErrInvalidAmount},
+		{in: ".", err: ErrInvalidAmount},
+		{in: "10.005", err: ErrInvalidAmount},
+		{in: "1e3", err: ErrInvalidAmount},
+		{in: "1,000.00", err: ErrInvalidAmount},
+		{in: "--1", err: ErrInvalidAmount},
+		{in: "12.3.4", err: ErrInvalidAmount},
+		{in: "92233720368547758.08", err: ErrOverflow},
+		{in: "100000000000000000000", err: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{in: Zero, want: "0.00"},
+		{in: 5, want: "0.05"},
+		{in: -5, want: "-0.05"},
+		{in: FromMajor(10), want: "10.00"},
+		{in: -1005, want: "-10.05"},
+		{in: math.MaxInt64, want: "92233720368547758.07"},
+		{in: math.MinInt64, want: "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	for _, s := range []string{"0.00", "0.01", "-0.01", "10.50", "-1234567.89"} {
+		a, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", s, err)
+		}
+		if got := a.String(); got != s {
+			t.Errorf("Parse(%q).String() = %q", s, got)
+		}
+	}
+}
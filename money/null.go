@@ -0,0 +1,12 @@
+package money
+
+// NullAmount is an Amount that may be NULL, e.g. a balance that cannot be converted for lack of an exchange rate
+type NullAmount struct {
+	Amount Amount
+	Valid  bool
+}
+
+// NewNullAmount creates a valid NullAmount
+func NewNullAmount(a Amount) NullAmount {
+	return NullAmount{Amount: a, Valid: true}
+}
@@ -0,0 +1,132 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	_ pgtype.NumericScanner = (*Amount)(nil)
+	_ pgtype.NumericValuer  = Amount(0)
+)
+
+// ScanNumeric implements the `pgtype.NumericScanner` interface.
+// Values with more than `Scale` decimal places are rounded half away from zero.
+func (a *Amount) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return fmt.Errorf("%w: cannot scan NULL into money.Amount", ErrInvalidAmount)
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: cannot scan NaN or infinity into money.Amount", ErrInvalidAmount)
+	}
+
+	v, err := scaleNumeric(n, Scale)
+	if err != nil {
+		return err
+	}
+	*a = Amount(v)
+	return nil
+}
+
+// NumericValue implements the `pgtype.NumericValuer` interface
+func (a Amount) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{
+		Int:   big.NewInt(int64(a)),
+		Exp:   -Scale,
+		Valid: true,
+	}, nil
+}
+
+var (
+	_ pgtype.NumericScanner = (*NullAmount)(nil)
+	_ pgtype.NumericValuer  = NullAmount{}
+)
+
+// ScanNumeric implements the `pgtype.NumericScanner` interface, NULL scans into an invalid NullAmount
+func (a *NullAmount) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		*a = NullAmount{}
+		return nil
+	}
+	if err := a.Amount.ScanNumeric(n); err != nil {
+		return err
+	}
+	a.Valid = true
+	return nil
+}
+
+// NumericValue implements the `pgtype.NumericValuer` interface
+func (a NullAmount) NumericValue() (pgtype.Numeric, error) {
+	if !a.Valid {
+		return pgtype.Numeric{}, nil
+	}
+	return a.Amount.NumericValue()
+}
+
+var (
+	_ pgtype.NumericScanner = (*Rate)(nil)
+	_ pgtype.NumericValuer  = Rate(0)
+)
+
+// ScanNumeric implements the `pgtype.NumericScanner` interface.
+// Values with more than `RateScale` decimal places are rounded half away from zero.
+func (r *Rate) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return fmt.Errorf("%w: cannot scan NULL into money.Rate", ErrInvalidRate)
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: cannot scan NaN or infinity into money.Rate", ErrInvalidRate)
+	}
+
+	v, err := scaleNumeric(n, RateScale)
+	if err != nil {
+		return err
+	}
+	*r = Rate(v)
+	return nil
+}
+
+// NumericValue implements the `pgtype.NumericValuer` interface
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{
+		Int:   big.NewInt(int64(r)),
+		Exp:   -RateScale,
+		Valid: true,
+	}, nil
+}
+
+// scaleNumeric converts a finite numeric to an integer scaled by 10^scale, rounding half away from zero
+func scaleNumeric(n pgtype.Numeric, scale int) (int64, error) {
+	v := new(big.Int)
+	if n.Int != nil {
+		v.Set(n.Int)
+	}
+
+	shift := int64(n.Exp) + int64(scale)
+	switch {
+	case shift > 0:
+		v.Mul(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(shift), nil))
+	case shift < 0:
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(-shift), nil)
+		remainder := new(big.Int)
+		v.QuoRem(v, divisor, remainder)
+
+		// round half away from zero
+		remainder.Abs(remainder).Mul(remainder, big.NewInt(2))
+		if remainder.Cmp(divisor) >= 0 {
+			if n.Int.Sign() < 0 {
+				v.Sub(v, big.NewInt(1))
+			} else {
+				v.Add(v, big.NewInt(1))
+			}
+		}
+	}
+
+	amount, err := fromBig(v)
+	if err != nil {
+		return 0, err
+	}
+	return int64(amount), nil
+}
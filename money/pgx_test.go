@@ -0,0 +1,92 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// scanNumeric scans the text representation of a numeric the way pgx scans a query result
+func scanNumeric(t *testing.T, src string, dst any) error {
+	t.Helper()
+	m := pgtype.NewMap()
+	var data []byte
+	if src != "NULL" {
+		data = []byte(src)
+	}
+	return m.Scan(pgtype.NumericOID, pgtype.TextFormatCode, data, dst)
+}
+
+func TestAmountScanNumeric(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{in: "10.50", want: 1050},
+		{in: "-0.05", want: -5},
+		{in: "12", want: 1200},
+		{in: "1.005", want: 101},
+		{in: "-1.005", want: -101},
+		{in: "1.0049", want: 100},
+		{in: "NULL", err: ErrInvalidAmount},
+		{in: "NaN", err: ErrInvalidAmount},
+		{in: "100000000000000000000", err: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Amount
+			err := scanNumeric(t, tt.in, &got)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("scan %s error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scan %s error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("scan %s = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullAmountScanNumeric(t *testing.T) {
+	var got NullAmount
+	if err := scanNumeric(t, "NULL", &got); err != nil {
+		t.Fatalf("scan NULL error = %v", err)
+	}
+	if got.Valid {
+		t.Errorf("scan NULL = %+v, want an invalid NullAmount", got)
+	}
+
+	if err := scanNumeric(t, "-3.20", &got); err != nil {
+		t.Fatalf("scan -3.20 error = %v", err)
+	}
+	if want := NewNullAmount(-320); got != want {
+		t.Errorf("scan -3.20 = %+v, want %+v", got, want)
+	}
+}
+
+func TestAmountNumericRoundTrip(t *testing.T) {
+	m := pgtype.NewMap()
+	for _, format := range []int16{pgtype.TextFormatCode, pgtype.BinaryFormatCode} {
+		for _, want := range []Amount{Zero, 1, -1, 1050, -123_456_789} {
+			buf, err := m.Encode(pgtype.NumericOID, format, want, nil)
+			if err != nil {
+				t.Fatalf("encode %s error = %v", want, err)
+			}
+
+			var got Amount
+			if err = m.Scan(pgtype.NumericOID, format, buf, &got); err != nil {
+				t.Fatalf("scan %s error = %v", want, err)
+			}
+			if got != want {
+				t.Errorf("round trip (format %d) = %s, want %s", format, got, want)
+			}
+		}
+	}
+}
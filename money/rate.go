@@ -0,0 +1,97 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// RateScale is the number of decimal places kept by a Rate (numeric(18, 8))
+const RateScale = 8
+
+// unitRate is the Rate of 1
+const unitRate = 100_000_000
+
+// ErrInvalidRate is returned when a value cannot be represented exactly as a Rate
+var ErrInvalidRate = errors.New("money: invalid exchange rate")
+
+// Rate is an exact exchange rate stored as an integer scaled by 10^RateScale.
+// A rate converts amounts of its base currency into its quote currency (USD/GHS 15.5 => 1 USD = 15.50 GHS).
+type Rate int64
+
+// OneRate is the rate between a currency and itself
+const OneRate Rate = unitRate
+
+// ParseRate parses a decimal string such as "15.5" or "0.06451613" into a Rate.
+// Values with more than `RateScale` decimal places are rejected instead of being rounded.
+func ParseRate(s string) (Rate, error) {
+	v, err := parseDecimal(s, RateScale, ErrInvalidRate)
+	return Rate(v), err
+}
+
+// MustParseRate is like ParseRate but panics if the value is invalid. Intended for constants and tests.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// IsPositive reports whether the rate is greater than zero
+func (r Rate) IsPositive() bool {
+	return r > 0
+}
+
+// Inverse returns the rate of the opposite direction (USD/GHS => GHS/USD), rounded half away from zero
+func (r Rate) Inverse() (Rate, error) {
+	if !r.IsPositive() {
+		return 0, fmt.Errorf("%w: cannot invert %s", ErrInvalidRate, r)
+	}
+	v := divRound(big.NewInt(unitRate), big.NewInt(unitRate), big.NewInt(int64(r)))
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, v.String())
+	}
+	return Rate(v.Int64()), nil
+}
+
+// String formats the rate with exactly `RateScale` decimal places (e.g. "15.50000000")
+func (r Rate) String() string {
+	v := int64(r)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+
+	// use uint64 so that math.MinInt64 does not overflow when negated
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-(v + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, u/unitRate, u%unitRate)
+}
+
+// Convert converts the amount with the rate, rounding half away from zero to `Scale` decimal places
+func (a Amount) Convert(r Rate) (Amount, error) {
+	if !r.IsPositive() {
+		return 0, fmt.Errorf("%w: cannot convert with %s", ErrInvalidRate, r)
+	}
+	return fromBig(divRound(big.NewInt(int64(a)), big.NewInt(int64(r)), big.NewInt(unitRate)))
+}
+
+// divRound returns a * b / c rounded half away from zero, c must be positive
+func divRound(a, b, c *big.Int) *big.Int {
+	v := new(big.Int).Mul(a, b)
+	remainder := new(big.Int)
+	v.QuoRem(v, c, remainder)
+
+	remainder.Abs(remainder).Mul(remainder, big.NewInt(2))
+	if remainder.Cmp(c) >= 0 {
+		if a.Sign()*b.Sign() < 0 {
+			v.Sub(v, big.NewInt(1))
+		} else {
+			v.Add(v, big.NewInt(1))
+		}
+	}
+	return v
+}
@@ -0,0 +1,142 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+		err  error
+	}{
+		{in: "1", want: OneRate},
+		{in: "15.5", want: 1_550_000_000},
+		{in: "0.06451613", want: 6_451_613},
+		{in: "1.123456780", want: 112_345_678},
+		{in: "1.123456789", err: ErrInvalidRate},
+		{in: "abc", err: ErrInvalidRate},
+		{in: "", err: ErrInvalidRate},
+		{in: "100000000000", err: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ParseRate(%q) error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRate(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateInverse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{in: "1", want: "1.00000000"},
+		{in: "15.5", want: "0.06451613"},
+		{in: "3", want: "0.33333333"},
+		{in: "0.00000003", want: "33333333.33333333"},
+		{in: "0", err: ErrInvalidRate},
+		{in: "-2", err: ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := MustParseRate(tt.in).Inverse()
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Inverse(%s) error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inverse(%s) error = %v", tt.in, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Inverse(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		rate   string
+		want   Amount
+		err    error
+	}{
+		{name: "whole rate", amount: MustParse("10.00"), rate: "15.5", want: MustParse("155.00")},
+		{name: "rounds down", amount: MustParse("1.00"), rate: "0.06451613", want: MustParse("0.06")},
+		{name: "half rounds away from zero", amount: MustParse("0.01"), rate: "0.5", want: MustParse("0.01")},
+		{name: "negative half rounds away from zero", amount: MustParse("-0.01"), rate: "0.5", want: MustParse("-0.01")},
+		{name: "below half rounds to zero", amount: MustParse("0.01"), rate: "0.49999999", want: Zero},
+		{name: "one", amount: MustParse("-42.42"), rate: "1", want: MustParse("-42.42")},
+		{name: "zero rate", amount: MustParse("1.00"), rate: "0", err: ErrInvalidRate},
+		{name: "negative rate", amount: MustParse("1.00"), rate: "-1", err: ErrInvalidRate},
+		{name: "overflow", amount: math.MaxInt64, rate: "2", err: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.Convert(MustParseRate(tt.rate))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("%s.Convert(%s) error = %v, want %v", tt.amount, tt.rate, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s.Convert(%s) error = %v", tt.amount, tt.rate, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s.Convert(%s) = %s, want %s", tt.amount, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateScanNumeric(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+		err  error
+	}{
+		{in: "15.50000000", want: 1_550_000_000},
+		{in: "15.123456785", want: 1_512_345_679},
+		{in: "15.123456784", want: 1_512_345_678},
+		{in: "NULL", err: ErrInvalidRate},
+		{in: "NaN", err: ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Rate
+			err := scanNumeric(t, tt.in, &got)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("scan %s error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scan %s error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("scan %s = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Package outbox relays the change events recorded in the `OutboxMaster` table to Kafka.
+//
+// The `notify_*` triggers call `enqueue_outbox_event` in the same transaction as the change,
+// so an event exists if and only if the change was committed. The `Relay` reads pending events
+// (woken up by `LISTEN outbox`, with polling as a fallback), publishes them to the matching
+// `broker` topic keyed by user id, and marks them as published once Kafka acknowledges them.
+// Events that keep failing are parked (`OutboxMaster.ParkedAt`) until `Relay.RequeueParked` is called.
+// A deleted user is also removed from the compacted `qwallet.user.updated` topic with a tombstone.
+package outbox
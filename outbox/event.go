@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/qwallet-expense-tracker/shared/broker"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+)
+
+// Aggregates written to the outbox by the `notify_*` triggers
+const (
+	AggregateAccounts      = "accounts"
+	AggregateBeneficiaries = "beneficiaries"
+	AggregateCategories    = "categories"
+	AggregateGoals         = "goals"
+	AggregateTransactions  = "transactions"
+	AggregateBudgetAlerts  = "budget_alerts"
+	AggregateUsers         = "users"
+)
+
+// Aggregates written to the outbox by the functions that record what happened,
+// their events are always inserts (e.g. `contribute_to_goal`)
+const (
+	AggregateGoalContributions = "goal_contributions"
+	AggregateGoalCompletions   = "goal_completions"
+	AggregateGoalCancellations = "goal_cancellations"
+	AggregateTransfers         = "transfers"
+)
+
+// Operations recorded by the triggers (`tg_op`)
+const (
+	OperationInsert = "INSERT"
+	OperationUpdate = "UPDATE"
+	OperationDelete = "DELETE"
+)
+
+// Event is a change captured in the outbox table in the same transaction as the change itself
+type Event struct {
+	ID          int64
+	Aggregate   string
+	AggregateID string
+	UserID      string
+	Operation   string
+	Payload     []byte
+	Attempts    int32
+	CreatedAt   time.Time
+}
+
+// topics maps an aggregate and operation to the broker topic the event is published to
+var topics = map[string]map[string]string{
+	AggregateAccounts: {
+		OperationInsert: string(broker.AccountCreated),
+		OperationUpdate: string(broker.AccountUpdated),
+		OperationDelete: string(broker.AccountDeleted),
+	},
+	AggregateBeneficiaries: {
+		OperationInsert: string(broker.BeneficiaryCreated),
+		OperationUpdate: string(broker.BeneficiaryUpdated),
+		OperationDelete: string(broker.BeneficiaryDeleted),
+	},
+	AggregateCategories: {
+		OperationInsert: string(broker.CategoryCreated),
+		OperationUpdate: string(broker.CategoryUpdated),
+		OperationDelete: string(broker.CategoryDeleted),
+	},
+	AggregateGoals: {
+		OperationInsert: string(broker.GoalCreated),
+		OperationUpdate: string(broker.GoalUpdated),
+		OperationDelete: string(broker.GoalDeleted),
+	},
+	AggregateTransactions: {
+		OperationInsert: string(broker.TransactionCreated),
+		OperationUpdate: string(broker.TransactionUpdated),
+		OperationDelete: string(broker.TransactionDeleted),
+	},
+	AggregateBudgetAlerts: {
+		OperationInsert: string(broker.BudgetThresholdReached),
+	},
+	AggregateUsers: {
+		OperationInsert: string(broker.UserCreated),
+		OperationUpdate: string(broker.UserUpdated),
+		OperationDelete: string(broker.UserDeleted),
+	},
+	AggregateGoalContributions: {
+		OperationInsert: string(broker.GoalContributed),
+	},
+	AggregateGoalCompletions: {
+		OperationInsert: string(broker.GoalCompleted),
+	},
+	AggregateGoalCancellations: {
+		OperationInsert: string(broker.GoalCancelled),
+	},
+	AggregateTransfers: {
+		OperationInsert: string(broker.TransferCompleted),
+	},
+}
+
+// tombstones maps an aggregate and operation to the compacted topics the user is removed from,
+// a tombstone is published there once the event itself was delivered
+var tombstones = map[string]map[string][]string{
+	AggregateUsers: {
+		OperationDelete: {string(broker.UserUpdated)},
+	},
+}
+
+// newEvent converts a generated outbox row into an `Event`
+func newEvent(p *gen.Outboxpayload) *Event {
+	return &Event{
+		ID:          p.ID,
+		Aggregate:   p.Aggregate,
+		AggregateID: p.AggregateID,
+		UserID:      p.UserID,
+		Operation:   p.Operation,
+		Payload:     p.Payload,
+		Attempts:    p.Attempts,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+// Topic returns the broker topic for the event
+func (e *Event) Topic() (string, error) {
+	if topic, ok := topics[e.Aggregate][e.Operation]; ok {
+		return topic, nil
+	}
+	return "", fmt.Errorf("no topic for %s %s event", e.Aggregate, e.Operation)
+}
+
+// Tombstones returns the compacted topics whose message keyed by the user of the event is removed
+func (e *Event) Tombstones() []string {
+	return tombstones[e.Aggregate][e.Operation]
+}
+
+// Decode decodes the event payload into one of the generated payload types
+// (e.g. `gen.Accountpayload` for the accounts aggregate)
+func Decode[T gen.Accountpayload | gen.Beneficiarypayload | gen.Categorypayload | gen.Goalpayload | gen.Transactionpayload |
+	gen.Budgetalertpayload | gen.Userpayload | gen.Goalcontributionpayload | gen.Transfereventpayload](payload []byte) (*T, error) {
+	payload, err := normalizeTimestamps(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode outbox payload: %w", err)
+	}
+
+	var out T
+	if err = json.Unmarshal(payload, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox payload: %w", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,258 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	eventsv1 "github.com/qwallet-expense-tracker/shared/events/v1"
+	"github.com/qwallet-expense-tracker/shared/money"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"regexp"
+	"time"
+)
+
+// Message converts the event payload into the catalog payload of its topic (e.g. `eventsv1.AccountCreated`)
+func (e *Event) Message() (proto.Message, error) {
+	switch e.Aggregate {
+	case AggregateAccounts:
+		p, err := Decode[gen.Accountpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		account := &eventsv1.Account{
+			AccountNumber: p.AccountNumber,
+			Name:          p.Name,
+			Balance:       toMoney(p.Balance, p.Currency),
+			UserId:        p.UserID,
+			UpdatedAt:     toTimestamp(p.UpdatedAt),
+		}
+		return pick(e.Operation,
+			&eventsv1.AccountCreated{Account: account},
+			&eventsv1.AccountUpdated{Account: account},
+			&eventsv1.AccountDeleted{AccountNumber: p.AccountNumber, UserId: p.UserID},
+		)
+	case AggregateBeneficiaries:
+		p, err := Decode[gen.Beneficiarypayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		beneficiary := &eventsv1.Beneficiary{
+			Id:            p.ID,
+			AccountNumber: p.AccountNumber,
+			Name:          p.Name,
+			Description:   p.Description,
+			UserId:        p.UserID,
+		}
+		return pick(e.Operation,
+			&eventsv1.BeneficiaryCreated{Beneficiary: beneficiary},
+			&eventsv1.BeneficiaryUpdated{Beneficiary: beneficiary},
+			&eventsv1.BeneficiaryDeleted{BeneficiaryId: p.ID, UserId: p.UserID},
+		)
+	case AggregateCategories:
+		p, err := Decode[gen.Categorypayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		category := &eventsv1.Category{Id: p.ID, Name: p.Name, Description: p.Description, UserId: p.UserID}
+		return pick(e.Operation,
+			&eventsv1.CategoryCreated{Category: category},
+			&eventsv1.CategoryUpdated{Category: category},
+			&eventsv1.CategoryDeleted{CategoryId: p.ID, UserId: p.UserID},
+		)
+	case AggregateGoals:
+		p, err := Decode[gen.Goalpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		goal := toGoal(p)
+		return pick(e.Operation,
+			&eventsv1.GoalCreated{Goal: goal},
+			&eventsv1.GoalUpdated{Goal: goal},
+			&eventsv1.GoalDeleted{GoalId: p.ID, UserId: p.UserID},
+		)
+	case AggregateTransactions:
+		p, err := Decode[gen.Transactionpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		transaction := &eventsv1.Transaction{
+			Id:              p.ID,
+			UserId:          p.UserID,
+			AccountNumber:   p.AccountNumber,
+			AccountName:     p.AccountName,
+			CategoryId:      p.CategoryID,
+			Type:            toTransactionType(p.Type),
+			Amount:          toMoney(p.Amount, p.Currency),
+			Description:     p.Description,
+			ReferenceNumber: p.ReferenceNumber,
+			Status:          p.Status,
+			UpdatedAt:       toTimestamp(p.UpdatedAt),
+			FxRate:          toRate(p.FxRate),
+		}
+		return pick(e.Operation,
+			&eventsv1.TransactionCreated{Transaction: transaction},
+			&eventsv1.TransactionUpdated{Transaction: transaction},
+			&eventsv1.TransactionDeleted{TransactionId: p.ID, UserId: p.UserID, AccountNumber: p.AccountNumber},
+		)
+	case AggregateBudgetAlerts:
+		p, err := Decode[gen.Budgetalertpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &eventsv1.BudgetThresholdReached{
+			AlertId:          p.ID,
+			BudgetId:         p.BudgetID,
+			UserId:           p.UserID,
+			CategoryId:       p.CategoryID,
+			ThresholdPercent: p.Threshold,
+			Limit:            toMoney(p.LimitAmount, p.Currency),
+			Spent:            toMoney(p.Spent, p.Currency),
+			PeriodStart:      toTimestamp(p.PeriodStart),
+			PeriodEnd:        toTimestamp(p.PeriodEnd),
+		}, nil
+	case AggregateUsers:
+		p, err := Decode[gen.Userpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		user := &eventsv1.User{
+			Id:          p.ID,
+			Email:       p.Email,
+			Name:        p.Name,
+			PhoneNumber: p.PhoneNumber,
+			AvatarUrl:   p.AvatarUrl,
+		}
+		at := toTimestamp(e.CreatedAt)
+		return pick(e.Operation,
+			&eventsv1.UserCreated{User: user, CreatedAt: at},
+			&eventsv1.UserUpdated{User: user, UpdatedAt: at},
+			&eventsv1.UserDeleted{UserId: p.ID, DeletedAt: at},
+		)
+	case AggregateGoalContributions:
+		p, err := Decode[gen.Goalcontributionpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &eventsv1.GoalContributed{
+			Goal: &eventsv1.Goal{
+				Id:          p.ID,
+				Name:        p.Name,
+				Description: p.Description,
+				Target:      toMoney(p.Target, p.Currency),
+				Balance:     toMoney(p.Balance, p.Currency),
+				UserId:      p.UserID,
+			},
+			Amount:        toMoney(p.Amount, p.AmountCurrency),
+			AccountNumber: p.AccountNumber,
+			TransactionId: p.TransactionID,
+			ContributedAt: toTimestamp(p.ContributedAt),
+		}, nil
+	case AggregateGoalCompletions:
+		p, err := Decode[gen.Goalpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &eventsv1.GoalCompleted{Goal: toGoal(p), CompletedAt: toTimestamp(e.CreatedAt)}, nil
+	case AggregateGoalCancellations:
+		p, err := Decode[gen.Goalpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &eventsv1.GoalCancelled{Goal: toGoal(p), CancelledAt: toTimestamp(e.CreatedAt)}, nil
+	case AggregateTransfers:
+		p, err := Decode[gen.Transfereventpayload](e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &eventsv1.TransferCompleted{
+			UserId:              p.UserID,
+			FromAccountNumber:   p.FromAccountNumber,
+			ToAccountNumber:     p.ToAccountNumber,
+			Amount:              toMoney(p.DebitedAmount, p.FromCurrency),
+			Description:         p.Description,
+			DebitTransactionId:  p.DebitTransactionID,
+			CreditTransactionId: p.CreditTransactionID,
+			CompletedAt:         toTimestamp(p.CompletedAt),
+			CreditedAmount:      toMoney(p.CreditedAmount, p.ToCurrency),
+			FxRate:              p.FxRate.String(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("no payload for %s events", e.Aggregate)
+	}
+}
+
+// pick returns the message of the operation
+func pick(operation string, created, updated, deleted proto.Message) (proto.Message, error) {
+	switch operation {
+	case OperationInsert:
+		return created, nil
+	case OperationUpdate:
+		return updated, nil
+	case OperationDelete:
+		return deleted, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %s", operation)
+	}
+}
+
+// toGoal converts the goal of a payload
+func toGoal(p *gen.Goalpayload) *eventsv1.Goal {
+	return &eventsv1.Goal{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Target:      toMoney(p.Target, p.Currency),
+		Balance:     toMoney(p.Balance, p.Currency),
+		UserId:      p.UserID,
+	}
+}
+
+// toMoney converts an amount, the currency is empty for events written before the payload had one
+func toMoney(a money.Amount, currency string) *eventsv1.Money {
+	return &eventsv1.Money{Minor: a.Minor(), Currency: currency}
+}
+
+// toRate formats a rate, events written before rates were recorded have none
+func toRate(r money.Rate) string {
+	if r == 0 {
+		return ""
+	}
+	return r.String()
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func toTransactionType(t string) eventsv1.TransactionType {
+	switch t {
+	case "CREDIT":
+		return eventsv1.TransactionType_TRANSACTION_TYPE_CREDIT
+	case "DEBIT":
+		return eventsv1.TransactionType_TRANSACTION_TYPE_DEBIT
+	default:
+		return eventsv1.TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+	}
+}
+
+// zonelessTimestamp matches the timestamps `to_jsonb` writes for `timestamp` columns (e.g. "2024-03-01T12:00:00.123456").
+// They have no zone, which `time.Time` cannot decode.
+var zonelessTimestamp = regexp.MustCompile(`^"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?"$`)
+
+// normalizeTimestamps marks the zoneless timestamps of a payload as UTC, which is how the database stores them
+func normalizeTimestamps(payload []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if zonelessTimestamp.Match(v) {
+			fields[k] = json.RawMessage(string(v[:len(v)-1]) + `Z"`)
+		}
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,361 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/qwallet-expense-tracker/shared/broker"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// channel is the `pg_notify` channel `enqueue_outbox_event` signals on
+const channel = "outbox"
+
+// Config configures the outbox relay
+type Config struct {
+	// BatchSize is the maximum number of events read from the outbox per round
+	BatchSize int32
+
+	// PollInterval is how long the relay waits for a notification before polling the outbox anyway
+	PollInterval time.Duration
+
+	// DeliveryTimeout bounds how long the relay waits for Kafka delivery reports of a batch
+	DeliveryTimeout time.Duration
+
+	// LockID is the Postgres advisory lock key used to elect a single active relay
+	LockID int64
+
+	// Retention is how long published events are kept before they are purged (0 keeps them forever)
+	Retention time.Duration
+
+	// MaxAttempts is how often an event is tried before it is parked and skipped (see `RequeueParked`)
+	MaxAttempts int32
+
+	// Logger receives the relay records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// DefaultConfig returns the default relay configuration
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:       100,
+		PollInterval:    5 * time.Second,
+		DeliveryTimeout: 30 * time.Second,
+		LockID:          7_283_001,
+		Retention:       7 * 24 * time.Hour,
+		MaxAttempts:     10,
+	}
+}
+
+// Relay publishes outbox events to their Kafka topics with at-least-once delivery.
+//
+// Only one relay holds the advisory lock at a time. Events are read in id order, which is the order
+// they were inserted in, not the order their transactions committed: events of concurrent transactions
+// of the same user can be relayed in either order. Events of sequential transactions keep their order,
+// as each user's events are produced one after the other and keyed by the user id within a partition.
+// The producer should be created with `enable.idempotence=true` so retries cannot reorder messages.
+//
+// An event that fails permanently, or `MaxAttempts` times, is parked so it cannot hold up the outbox;
+// the later events of its user are relayed without it.
+type Relay struct {
+	db       *database.DB
+	producer *kafka.Producer
+	cfg      Config
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(db *database.DB, producer *kafka.Producer, cfg Config) *Relay {
+	def := DefaultConfig()
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.DeliveryTimeout <= 0 {
+		cfg.DeliveryTimeout = def.DeliveryTimeout
+	}
+	if cfg.LockID == 0 {
+		cfg.LockID = def.LockID
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = def.MaxAttempts
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Relay{db: db, producer: producer, cfg: cfg}
+}
+
+// Run relays outbox events until the context is cancelled
+func (r *Relay) Run(ctx context.Context) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if err = r.acquireLock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		// the lock is session scoped, release it before the connection goes back to the pool
+		if _, err := conn.Exec(context.Background(), "select pg_advisory_unlock($1)", r.cfg.LockID); err != nil {
+			r.cfg.Logger.ErrorContext(ctx, "outbox: failed to release advisory lock",
+				slog.Int64("lock_id", r.cfg.LockID),
+				slog.Any("error", err),
+			)
+		}
+	}()
+
+	if _, err = conn.Exec(ctx, "listen "+channel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "unlisten "+channel)
+	}()
+
+	r.cfg.Logger.InfoContext(ctx, "outbox relay started", slog.Int64("lock_id", r.cfg.LockID))
+	q := gen.New(conn)
+	lastPurge := time.Time{}
+	for {
+		// drain the outbox, a full batch means there may be more waiting
+		for {
+			n, err := r.relayBatch(ctx, q)
+			if err != nil {
+				if ctx.Err() != nil {
+					r.cfg.Logger.InfoContext(ctx, "outbox relay stopped")
+					return nil
+				}
+				return err
+			}
+			if n < int(r.cfg.BatchSize) {
+				break
+			}
+		}
+
+		if r.cfg.Retention > 0 && time.Since(lastPurge) > time.Hour {
+			if _, err := q.PurgePublishedOutboxEvents(ctx, time.Now().Add(-r.cfg.Retention)); err != nil {
+				r.cfg.Logger.ErrorContext(ctx, "outbox: failed to purge published events", slog.Any("error", err))
+			}
+			lastPurge = time.Now()
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, r.cfg.PollInterval)
+		_, err = conn.Conn().WaitForNotification(waitCtx)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			r.cfg.Logger.InfoContext(ctx, "outbox relay stopped")
+			return nil
+		case err != nil && !errors.Is(err, context.DeadlineExceeded):
+			return fmt.Errorf("failed to wait for outbox notification: %w", err)
+		}
+	}
+}
+
+// acquireLock blocks until this relay holds the advisory lock or the context is cancelled
+func (r *Relay) acquireLock(ctx context.Context, conn *pgxpool.Conn) error {
+	for {
+		var locked bool
+		if err := conn.QueryRow(ctx, "select pg_try_advisory_lock($1)", r.cfg.LockID).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to acquire outbox lock: %w", err)
+		}
+		if locked {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// relayBatch publishes one batch of pending events and returns the number of events read
+func (r *Relay) relayBatch(ctx context.Context, q *gen.Queries) (int, error) {
+	rows, err := q.ListPendingOutboxEvents(ctx, r.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list outbox events: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	events := make([]*Event, len(rows))
+	for i, row := range rows {
+		events[i] = newEvent(row)
+	}
+
+	published, failures := r.publish(ctx, events)
+	for _, e := range events {
+		err, failed := failures[e.ID]
+		if !failed {
+			continue
+		}
+
+		park := broker.IsPermanent(err) || e.Attempts+1 >= r.cfg.MaxAttempts
+		r.cfg.Logger.WarnContext(ctx, "outbox: failed to publish event",
+			slog.Int64("event_id", e.ID),
+			slog.String("aggregate", e.Aggregate),
+			slog.String("user_id", e.UserID),
+			slog.Int("attempt", int(e.Attempts)+1),
+			slog.Bool("parked", park),
+			slog.Any("error", err),
+		)
+		if err := q.RecordOutboxEventFailure(ctx, e.ID, err.Error(), park); err != nil {
+			return 0, fmt.Errorf("failed to record outbox failure: %w", err)
+		}
+	}
+
+	if len(published) > 0 {
+		if err := q.MarkOutboxEventsPublished(ctx, published); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox events as published: %w", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		// back off instead of hammering the broker with the same failing batch
+		return 0, nil
+	}
+	return len(events), nil
+}
+
+// publish produces the events and returns the ids of the delivered ones and the error of each failed one.
+//
+// The events of a user are produced one at a time, each after the previous one was delivered, and stop at
+// the first failure: the later events of that user are neither produced nor marked, so they are relayed
+// after the failed one on a later round. Different users are produced concurrently.
+func (r *Relay) publish(ctx context.Context, events []*Event) (published []int64, failures map[int64]error) {
+	var users []string
+	byUser := make(map[string][]*Event)
+	for _, e := range events {
+		if _, ok := byUser[e.UserID]; !ok {
+			users = append(users, e.UserID)
+		}
+		byUser[e.UserID] = append(byUser[e.UserID], e)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.DeliveryTimeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	failures = make(map[int64]error)
+	for _, userID := range users {
+		wg.Add(1)
+		go func(userEvents []*Event) {
+			defer wg.Done()
+			deliveryChan := make(chan kafka.Event, 1)
+			for _, e := range userEvents {
+				err := r.produce(ctx, e, deliveryChan)
+
+				mu.Lock()
+				if err != nil {
+					failures[e.ID] = err
+				} else {
+					published = append(published, e.ID)
+				}
+				mu.Unlock()
+
+				if err != nil {
+					return
+				}
+			}
+		}(byUser[userID])
+	}
+	wg.Wait()
+	return published, failures
+}
+
+// produce produces a single event, followed by its tombstones, and waits for their delivery reports.
+// Events that can never be produced (unknown aggregate, undecodable payload) fail with a `broker.Permanent` error.
+func (r *Relay) produce(ctx context.Context, e *Event, deliveryChan chan kafka.Event) error {
+	topic, err := e.Topic()
+	if err != nil {
+		return broker.Permanent(err)
+	}
+
+	msg, err := e.Message()
+	if err != nil {
+		return broker.Permanent(err)
+	}
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return broker.Permanent(fmt.Errorf("failed to marshal %s: %w", msg.ProtoReflect().Descriptor().FullName(), err))
+	}
+
+	if err = r.send(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(e.UserID),
+		Value:          value,
+		Headers: append(broker.Envelope{
+			ID:            strconv.FormatInt(e.ID, 10),
+			Type:          string(msg.ProtoReflect().Descriptor().FullName()),
+			SchemaVersion: 1,
+			OccurredAt:    e.CreatedAt,
+			UserID:        e.UserID,
+			Producer:      "outbox-relay",
+			ContentType:   broker.ContentTypeProtobuf,
+		}.Headers(),
+			kafka.Header{Key: "aggregate-id", Value: []byte(e.AggregateID)},
+			kafka.Header{Key: "operation", Value: []byte(e.Operation)},
+		),
+		Opaque: e.ID,
+	}, deliveryChan); err != nil {
+		return err
+	}
+
+	// a nil value removes the user from the compacted topic
+	for _, tombstone := range e.Tombstones() {
+		if err = r.send(ctx, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &tombstone, Partition: kafka.PartitionAny},
+			Key:            []byte(e.UserID),
+			Opaque:         e.ID,
+		}, deliveryChan); err != nil {
+			return fmt.Errorf("failed to publish tombstone to %s: %w", tombstone, err)
+		}
+	}
+	return nil
+}
+
+// send produces a message and waits for its delivery report
+func (r *Relay) send(ctx context.Context, msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	if err := r.producer.Produce(msg, deliveryChan); err != nil {
+		return err
+	}
+
+	// the report of a message that timed out may still arrive, the channel is buffered so it never blocks the producer
+	for {
+		select {
+		case ev := <-deliveryChan:
+			m, ok := ev.(*kafka.Message)
+			if !ok {
+				continue
+			}
+			return m.TopicPartition.Error
+		case <-ctx.Done():
+			return fmt.Errorf("no delivery report received: %w", ctx.Err())
+		}
+	}
+}
+
+// RequeueParked makes the parked events pending again and returns how many were requeued
+func (r *Relay) RequeueParked(ctx context.Context) (n int64, err error) {
+	err = r.db.Execute(ctx, func(q gen.Querier) error {
+		n, err = q.RequeueParkedOutboxEvents(ctx)
+		return err
+	})
+	return n, err
+}
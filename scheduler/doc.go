@@ -0,0 +1,9 @@
+// Package scheduler materializes the due occurrences of recurring transactions.
+//
+// The schedules live in `RecurringTransactionMaster`. `materialize_recurring_transactions` locks
+// the due templates with `for update skip locked`, records each occurrence in
+// `RecurringOccurrenceMaster` (unique per template and date) and creates its transaction through
+// `create_transaction`, all in one database transaction. Every replica of a service can run a
+// `Scheduler`: they share the due templates and an occurrence is still materialized exactly once.
+// A template that is far behind catches up `Config.MaxCatchUp` occurrences per tick.
+package scheduler
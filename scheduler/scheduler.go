@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/qwallet-expense-tracker/shared/database"
+	"github.com/qwallet-expense-tracker/shared/database/sql/gen"
+	"github.com/qwallet-expense-tracker/shared/metrics"
+	"log/slog"
+	"time"
+)
+
+// Config configures the scheduler
+type Config struct {
+	// Interval is how often due occurrences are materialized (defaults to 1m)
+	Interval time.Duration
+
+	// BatchSize is the maximum number of recurring transactions materialized per database transaction (defaults to 100)
+	BatchSize int32
+
+	// MaxCatchUp is the maximum number of missed occurrences materialized per recurring transaction and tick (defaults to 31).
+	// A recurring transaction that is further behind catches up over the next ticks.
+	MaxCatchUp int32
+
+	// Logger receives the scheduler records (defaults to `slog.Default()`)
+	Logger *slog.Logger
+}
+
+// DefaultConfig returns the default scheduler configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval:   time.Minute,
+		BatchSize:  100,
+		MaxCatchUp: 31,
+	}
+}
+
+// Scheduler materializes the due occurrences of recurring transactions
+type Scheduler struct {
+	db  *database.DB
+	cfg Config
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(db *database.DB, cfg Config) *Scheduler {
+	def := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.MaxCatchUp <= 0 {
+		cfg.MaxCatchUp = def.MaxCatchUp
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Scheduler{db: db, cfg: cfg}
+}
+
+// Run materializes due occurrences every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.cfg.Logger.InfoContext(ctx, "scheduler started", slog.Duration("interval", s.cfg.Interval))
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				s.cfg.Logger.InfoContext(ctx, "scheduler stopped")
+				return nil
+			}
+			// the next tick retries, a failed batch was rolled back as a whole
+			s.cfg.Logger.ErrorContext(ctx, "scheduler: failed to materialize occurrences", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.cfg.Logger.InfoContext(ctx, "scheduler stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce materializes the occurrences that are due now and returns them.
+// Each recurring transaction catches up on at most `MaxCatchUp` occurrences, the rest is left for the next run.
+// Occurrences whose transaction could not be created (e.g. insufficient funds) are returned as FAILED.
+func (s *Scheduler) RunOnce(ctx context.Context) ([]*gen.Recurringoccurrencepayload, error) {
+	var all []*gen.Recurringoccurrencepayload
+	seen := make(map[string]bool)
+	for {
+		var batch []*gen.Recurringoccurrencepayload
+		err := s.db.InTx(ctx, database.TxOptions{}, func(q gen.Querier) (err error) {
+			batch, err = q.MaterializeRecurringTransactions(ctx, time.Now(), s.cfg.BatchSize, s.cfg.MaxCatchUp)
+			return err
+		})
+		if err != nil {
+			return all, fmt.Errorf("failed to materialize recurring transactions: %w", err)
+		}
+
+		for _, o := range batch {
+			metrics.RecordRecurringOccurrence(o.Status)
+			if o.Error != "" {
+				s.cfg.Logger.WarnContext(ctx, "scheduler: recurring transaction failed",
+					slog.String("recurring_id", o.RecurringID),
+					slog.String("user_id", o.UserID),
+					slog.String("occurrence_date", o.OccurrenceDate.Format(time.DateOnly)),
+					slog.String("error", o.Error),
+				)
+			}
+		}
+		all = append(all, batch...)
+
+		// a short batch means little is left, anything still due is picked up on the next tick.
+		// A recurring transaction coming back hit its catch-up limit, it continues on the next tick too.
+		templates := make(map[string]bool)
+		for _, o := range batch {
+			templates[o.RecurringID] = true
+		}
+		repeated := false
+		for id := range templates {
+			repeated = repeated || seen[id]
+			seen[id] = true
+		}
+		if len(templates) < int(s.cfg.BatchSize) || repeated {
+			return all, nil
+		}
+	}
+}
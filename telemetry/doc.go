@@ -0,0 +1,8 @@
+// Package telemetry sets up OpenTelemetry tracing for the qwallet services.
+//
+// `Setup` installs the global tracer provider and the W3C trace-context propagator.
+// The rest of the module instruments itself against those globals: the gRPC tracing
+// interceptors, the pgx query tracer installed by `database.Connect`, the Redis hook
+// installed by `cache.Connect` and the Kafka header propagation of the broker package.
+// Without `Setup` every span is a no-op.
+package telemetry
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier adapts Kafka message headers to a `propagation.TextMapCarrier`
+type headerCarrier struct {
+	msg *kafka.Message
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (c headerCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range c.msg.Headers {
+		if h.Key == key {
+			c.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.Headers))
+	for i, h := range c.msg.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// InjectKafka writes the trace context of ctx into the message headers (`traceparent`, `tracestate`)
+func InjectKafka(ctx context.Context, msg *kafka.Message) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{msg: msg})
+}
+
+// ExtractKafka returns a copy of ctx carrying the trace context of the message headers
+func ExtractKafka(ctx context.Context, msg *kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier{msg: msg})
+}
+
+// StartProduce starts a producer span for the message and injects it into the message headers
+func StartProduce(ctx context.Context, msg *kafka.Message) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, topicOf(msg)+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes(msg)...),
+	)
+	InjectKafka(ctx, msg)
+	return ctx, span
+}
+
+// StartConsume starts a consumer span for the message, continuing the trace of its producer
+func StartConsume(ctx context.Context, msg *kafka.Message) (context.Context, trace.Span) {
+	ctx = ExtractKafka(ctx, msg)
+	attrs := append(messagingAttributes(msg),
+		attribute.Int64("messaging.kafka.destination.partition", int64(msg.TopicPartition.Partition)),
+		attribute.Int64("messaging.kafka.message.offset", int64(msg.TopicPartition.Offset)),
+	)
+	return Tracer().Start(ctx, topicOf(msg)+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func messagingAttributes(msg *kafka.Message) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.destination.name", topicOf(msg)),
+		attribute.String("messaging.kafka.message.key", string(msg.Key)),
+	}
+}
+
+func topicOf(msg *kafka.Message) string {
+	if msg.TopicPartition.Topic == nil {
+		return ""
+	}
+	return *msg.TopicPartition.Topic
+}
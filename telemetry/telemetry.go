@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+)
+
+// instrumentationName names the tracer used by every instrumented package of the module
+const instrumentationName = "github.com/qwallet-expense-tracker/shared"
+
+// Exporters supported by `Setup`
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Config configures tracing
+type Config struct {
+	// ServiceName is reported as the `service.name` resource attribute
+	ServiceName string
+
+	// ServiceVersion is reported as the `service.version` resource attribute
+	ServiceVersion string
+
+	// Exporter is one of "otlp", "stdout" or "none" (defaults to "otlp")
+	Exporter string
+
+	// Endpoint is the OTLP gRPC collector address (defaults to the OTEL_EXPORTER_OTLP_ENDPOINT env variable)
+	Endpoint string
+
+	// Insecure disables TLS to the collector
+	Insecure bool
+
+	// SampleRatio is the fraction of new traces that are sampled (defaults to 1).
+	// Traces started by a caller keep the caller's sampling decision.
+	SampleRatio float64
+
+	// SpanExporter is used instead of `Exporter` when set, e.g. `tracetest.NewInMemoryExporter()` in tests
+	SpanExporter sdktrace.SpanExporter
+}
+
+// Setup installs the global tracer provider and propagator.
+// The returned function flushes pending spans and must be called before the process exits.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	exporter := cfg.SpanExporter
+	if exporter == nil {
+		if exporter, err = newExporter(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry resource: %w", err)
+	}
+
+	if cfg.SampleRatio <= 0 {
+		cfg.SampleRatio = 1
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	log.Printf("🚀tracing started for %s\n", cfg.ServiceName)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the span exporter named by the config
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, errors.New("telemetry: unknown exporter " + cfg.Exporter)
+	}
+}
+
+// Tracer returns the tracer of the module, backed by the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}